@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"mateo/internal/config"
+	"mateo/internal/logging"
+	"mateo/internal/store/pg"
+	"mateo/internal/system"
+)
+
+const usage = `usage: counters <command>
+
+commands:
+  rebuild  recompute capacity counters from InvoiceIn to repair drift
+  prune    delete counter buckets outside every limit window`
+
+func main() {
+	logging.Setup(os.Stderr)
+
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, cfg.DB.DSN())
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create connection pool")
+	}
+	defer pool.Close()
+
+	store := pg.NewStore(
+		pool,
+		system.Clock{},
+		system.UUIDGenerator{},
+		cfg.Business.Location,
+		cfg.Business.MaxRollingWindow,
+	)
+
+	switch os.Args[1] {
+	case "rebuild":
+		if err := store.RebuildCapacityCounters(ctx); err != nil {
+			log.Fatal().Err(err).Msg("Failed to rebuild capacity counters")
+		}
+		log.Info().Msg("Capacity counters rebuilt")
+	case "prune":
+		deleted, err := store.PruneCapacityCounterBuckets(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to prune capacity counter buckets")
+		}
+		log.Info().Int64("deleted", deleted).Msg("Capacity counter buckets pruned")
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
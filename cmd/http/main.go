@@ -2,27 +2,43 @@ package main
 
 import (
 	"context"
+	"errors"
 	"github.com/go-redis/redis/v8"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"mateo/internal/cache"
+	"mateo/internal/callback"
 	"mateo/internal/domain"
+	"mateo/internal/metrics"
+	"mateo/internal/notify"
+	"mateo/internal/ratelimit"
+	"mateo/internal/service/admin"
+	"mateo/internal/service/health"
 	"mateo/internal/service/invoice"
 	"mateo/internal/service/merchant"
+	"mateo/internal/service/payer"
+	"mateo/internal/service/quarantine"
 	"mateo/internal/service/requisite"
+	"mateo/internal/service/sandbox"
+	"mateo/internal/store/memory"
 	"mateo/internal/store/pg"
+	"mateo/internal/store/pg/migrations"
 	"mateo/internal/store/pgcached"
+	"mateo/internal/system"
+	"mateo/internal/tracing"
+	stdhttp "net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"mateo/internal/config"
+	"mateo/internal/logging"
 	"mateo/internal/transport/http"
 )
 
 func main() {
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	logging.Setup(os.Stderr)
 
 	// Load configuration
 	cfg, err := config.Load()
@@ -30,18 +46,28 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to load configuration")
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		File:        cfg.Tracing.File,
+		ServiceName: "mateo-invoice-in",
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up tracing")
+	}
+
 	// Создаем пул подключений вместо одиночного соединения
 	pgConfig, err := pgxpool.ParseConfig(cfg.DB.DSN())
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to parse DB config")
 	}
+	pgConfig.ConnConfig.Tracer = tracing.PgxTracer{}
 
-	// Настройки пула (рекомендуемые значения)
-	pgConfig.MaxConns = 50
-	pgConfig.MinConns = 10
-	pgConfig.MaxConnLifetime = time.Hour
-	pgConfig.MaxConnIdleTime = time.Minute * 30
-	pgConfig.HealthCheckPeriod = time.Minute
+	pgConfig.MaxConns = cfg.DB.Pool.MaxConns
+	pgConfig.MinConns = cfg.DB.Pool.MinConns
+	pgConfig.MaxConnLifetime = cfg.DB.Pool.MaxConnLifetime
+	pgConfig.MaxConnIdleTime = cfg.DB.Pool.MaxConnIdleTime
+	pgConfig.HealthCheckPeriod = cfg.DB.Pool.HealthCheckPeriod
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), pgConfig)
 	if err != nil {
@@ -50,14 +76,29 @@ func main() {
 	defer pool.Close() // Важно: закрываем пул при завершении
 
 	// Проверяем подключение
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.DB.ConnectTimeout)
 	defer cancel()
 	if err := pool.Ping(ctx); err != nil {
 		log.Fatal().Err(err).Msg("Failed to ping database")
 	}
 
+	// Не стартуем на схеме, к которой не применены миграции этой версии сервиса
+	migrator, err := migrations.New(pool)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load migrations")
+	}
+	if err := migrator.CheckVersion(ctx); err != nil {
+		log.Fatal().Err(err).Msg("Database schema is not up to date, run migrations first")
+	}
+
 	// Initialize store с использованием пула
-	store := pg.NewStore(pool)
+	store := pg.NewStore(
+		pool,
+		system.Clock{},
+		system.UUIDGenerator{},
+		cfg.Business.Location,
+		cfg.Business.MaxRollingWindow,
+	)
 
 	// Init Redis client
 	redisClient := redis.NewClient(&redis.Options{
@@ -66,19 +107,170 @@ func main() {
 		DB:       cfg.Redis.DB,
 	})
 	defer redisClient.Close() // Закрываем подключение к Redis
+	redisClient.AddHook(tracing.RedisHook{})
 
-	cachedStore := pgcached.NewCachedStore(store, redisClient)
+	redisRemote := cache.NewRedis(redisClient)
+	redisCache := cache.NewBreaker(redisRemote, system.Clock{}, cache.BreakerOptions{
+		Failures: cfg.Redis.BreakerFailures,
+		Cooldown: cfg.Redis.BreakerCooldown,
+	})
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		if !cfg.Redis.Optional {
+			log.Fatal().Err(err).Msg("Failed to ping redis")
+		}
+		log.Warn().Err(err).Msg("Redis is unavailable, starting with process memory cache")
+		redisCache.Trip()
+	}
+
+	cachedStore := pgcached.NewCachedStore(store, redisCache, system.Clock{}, system.Rand{}, cacheOptions(cfg))
+	// Пока Redis был недоступен, удаления ключей в нем не выполнялись
+	redisCache.OnRecover(cachedStore.Flush)
+
+	// Метрики создаются до сервисов: их передают в сервисы при создании
+	var appMetrics domain.Metrics = domain.NoopMetrics{}
+	var metricsServer *stdhttp.Server
+	if cfg.HTTP.MetricsPort != "" {
+		m := metrics.New()
+		m.RegisterPool(pool)
+		m.RegisterCaches(cachedStore.CacheStats)
+		m.RegisterBreaker(redisCache)
+		appMetrics = m
+
+		metricsServer = m.NewServer(cfg.HTTP.MetricsPort)
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, stdhttp.ErrServerClosed) {
+				log.Error().Err(err).Msg("Failed to start metrics server")
+			}
+		}()
+	}
 
 	// Initialize services
 	merchantService := merchant.NewService(cachedStore)
-	invoiceService := invoice.NewService(cachedStore)
-	requisiteService := requisite.NewService(cachedStore)
+	invoiceService := invoice.NewService(cachedStore, system.Clock{}).
+		WithDefaultTTL(cfg.Invoice.DefaultTTL)
+	requisiteService := requisite.NewService(cachedStore, system.Clock{}, system.Rand{}).
+		WithScoring(scoring(cfg)).
+		WithFlexible(flexible(cfg)).
+		WithMetrics(appMetrics)
+
+	// Закрываем просроченные Invoice и освобождаем hold на кошельках
+	expirationCtx, stopExpiration := context.WithCancel(context.Background())
+	defer stopExpiration()
+
+	// Карантин реквизитов и терминалов, у которых Invoice истекают неоплаченными.
+	// Сервис создается и при выключенном карантине, чтобы его можно было включить на ходу.
+	quarantineService := quarantine.NewService(
+		cachedStore,
+		notify.NewWebhook(cfg.Quarantine.WebhookURL, cfg.Quarantine.WebhookTimeout),
+		system.Clock{},
+		quarantineRules(cfg),
+	)
+	quarantineService.SetEnabled(cfg.Quarantine.Enabled)
+	invoiceService.WithObserver(quarantineService)
+
+	if cfg.Quarantine.WebhookURL != "" {
+		go quarantineService.RunNotifications(expirationCtx, cfg.Quarantine.NotifyInterval)
+	}
+
+	go invoiceService.RunExpiration(expirationCtx, cfg.Invoice.ExpirationInterval)
+
+	// Корзины счетчиков за пределами окон лимитов больше не читаются
+	go store.RunCounterPruning(expirationCtx, cfg.Business.CounterPruneInterval)
+
+	// Изменения мерчантов, команд и настроек сбрасывают кеш по уведомлениям Postgres
+	go cachedStore.RunInvalidation(expirationCtx)
 
 	// Initialize app
-	app := domain.NewApp(merchantService, requisiteService, invoiceService)
+	app := domain.NewApp(merchantService, requisiteService, invoiceService).
+		WithAdmin(admin.NewService(cachedStore, system.Clock{})).
+		WithMetrics(appMetrics)
+
+	healthService := health.NewService(store, migrator, redisRemote, redisCache, system.Clock{}, healthOptions(cfg))
+	app.WithHealth(healthService)
+
+	// Лимиты мерчантов делят с кешем автомат Redis: пока Redis недоступен, запросы пропускаются
+	limiter := ratelimit.New(
+		ratelimit.NewRedisBuckets(redisClient, redisCache, cfg.RateLimit.RedisTimeout),
+		rateLimitPolicy(cfg),
+	)
+	limiter.SetEnabled(cfg.RateLimit.Enabled)
+	app.WithRateLimiter(limiter)
+
+	payerService := payer.NewService(cachedStore, system.Clock{}, payerLimits(cfg))
+	payerService.SetEnabled(cfg.Payer.Enabled)
+	app.WithPayer(payerService)
+
+	// Песочница: Invoice мерчантов с is_sandbox получают реквизиты фиктивного пула
+	// и хранятся в "SandboxInvoice", поэтому имитация оплаты работает на любой реплике
+	var (
+		sandboxRequisiteService *requisite.Service
+		sandboxInvoiceService   *invoice.Service
+	)
+	if cfg.Sandbox.Enabled {
+		sandboxStore := pg.NewSandboxStore(
+			pool,
+			memory.NewSandboxPool(system.Clock{}, system.UUIDGenerator{}),
+			system.Clock{},
+			system.UUIDGenerator{},
+		)
+		sandboxRequisiteService = requisite.NewService(sandboxStore, system.Clock{}, system.Rand{}).
+			WithFlexible(flexible(cfg))
+		sandboxInvoiceService = invoice.NewService(sandboxStore, system.Clock{}).
+			WithDefaultTTL(cfg.Invoice.DefaultTTL)
+		sandboxService := sandbox.NewService(
+			sandboxStore,
+			callback.NewClient(cfg.Sandbox.CallbackTimeout, system.Clock{}),
+			system.Clock{},
+		)
+
+		go sandboxInvoiceService.RunExpiration(expirationCtx, cfg.Invoice.ExpirationInterval)
+		go sandboxService.RunPruning(expirationCtx, time.Hour, cfg.Sandbox.InvoiceRetention)
+
+		app.WithSandbox(
+			sandboxRequisiteService,
+			sandboxInvoiceService,
+			sandboxService,
+		)
+	}
+
+	// Изменяемые настройки перечитываются по SIGHUP и при изменении CONFIG_FILE
+	reloader := newReloader(cfg, func(cfg *config.Config) {
+		cachedStore.SetCacheOptions(cacheOptions(cfg))
+		invoiceService.SetDefaultTTL(cfg.Invoice.DefaultTTL)
+		requisiteService.SetScoring(scoring(cfg))
+		requisiteService.SetFlexible(flexible(cfg))
+		quarantineService.SetRules(quarantineRules(cfg))
+		quarantineService.SetEnabled(cfg.Quarantine.Enabled)
+		payerService.SetLimits(payerLimits(cfg))
+		payerService.SetEnabled(cfg.Payer.Enabled)
+		healthService.SetOptions(healthOptions(cfg))
+		limiter.SetPolicy(rateLimitPolicy(cfg))
+		limiter.SetEnabled(cfg.RateLimit.Enabled)
+		if sandboxInvoiceService != nil {
+			sandboxInvoiceService.SetDefaultTTL(cfg.Invoice.DefaultTTL)
+			sandboxRequisiteService.SetFlexible(flexible(cfg))
+		}
+	})
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			log.Info().Msg("Received SIGHUP, reloading configuration")
+			reloader.Reload()
+		}
+	}()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		go config.Watch(expirationCtx, path, configWatchInterval, reloader.Reload)
+	}
 
 	// Initialize and start HTTP server
-	srv, err := http.NewServer(app)
+	srv, err := http.NewServer(app, cfg.Admin.Tokens, http.ServerOptions{
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
+		BodyLimit:    cfg.HTTP.BodyLimit,
+	})
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to create server")
 	}
@@ -96,9 +288,28 @@ func main() {
 	<-quit
 
 	log.Info().Msg("Shutting down server...")
+	// Балансировщик должен увидеть неготовность до того, как сервер перестанет принимать соединения
+	healthService.Drain()
+	signal.Stop(hup)
+	cfg = reloader.Current()
+	time.Sleep(cfg.HTTP.DrainDelay)
+	stopExpiration()
 	if err := srv.Stop(cfg.HTTP.ShutdownTimeout); err != nil {
 		log.Error().Err(err).Msg("Failed to stop server")
 	}
+	if metricsServer != nil {
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("Failed to stop metrics server")
+		}
+		cancelShutdown()
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancelShutdown()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to flush traces")
+	}
 
 	log.Info().Msg("Server stopped")
 }
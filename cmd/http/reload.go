@@ -0,0 +1,130 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"mateo/internal/cache"
+	"mateo/internal/config"
+	"mateo/internal/ratelimit"
+	"mateo/internal/service/health"
+	"mateo/internal/service/payer"
+	"mateo/internal/service/quarantine"
+	"mateo/internal/service/requisite"
+)
+
+// configWatchInterval как часто проверяется, не изменился ли CONFIG_FILE
+const configWatchInterval = 5 * time.Second
+
+// reloader перечитывает конфигурацию и передает изменяемые настройки работающим сервисам.
+// Новая конфигурация сначала целиком проходит проверку, поэтому сервисы получают либо
+// все новые значения, либо ни одного.
+type reloader struct {
+	mu      sync.Mutex
+	current atomic.Pointer[config.Config]
+	apply   func(cfg *config.Config)
+}
+
+func newReloader(cfg *config.Config, apply func(cfg *config.Config)) *reloader {
+	r := &reloader{apply: apply}
+	r.current.Store(cfg)
+	return r
+}
+
+// Current действующая конфигурация
+func (r *reloader) Current() *config.Config {
+	return r.current.Load()
+}
+
+// Reload применяет изменяемые настройки; ошибки и отклоненные изменения только логируются
+func (r *reloader) Reload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, rejected, err := config.Reload(r.current.Load())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to reload configuration, keeping the current one")
+		return
+	}
+	for _, setting := range rejected {
+		log.Warn().Str("setting", setting).Msg("Setting cannot be changed without a restart, keeping the current value")
+	}
+
+	r.apply(next)
+	r.current.Store(next)
+	log.Info().Msg("Configuration reloaded")
+}
+
+func cacheOptions(cfg *config.Config) cache.Options {
+	return cache.Options{
+		LocalSize:    cfg.Cache.LocalSize,
+		TTL:          cfg.Cache.TTL,
+		StaleTTL:     cfg.Cache.StaleTTL,
+		StaleRetry:   cfg.Cache.StaleRetry,
+		EarlyRefresh: cfg.Cache.EarlyRefresh,
+		LoadTimeout:  cfg.Cache.LoadTimeout,
+	}
+}
+
+func scoring(cfg *config.Config) requisite.Scoring {
+	return requisite.Scoring{
+		PriorSuccessRate: cfg.Scoring.PriorSuccessRate,
+		PriorWeight:      cfg.Scoring.PriorWeight,
+		RateWeight:       cfg.Scoring.RateWeight,
+		RecencyWeight:    cfg.Scoring.RecencyWeight,
+		LoadWeight:       cfg.Scoring.LoadWeight,
+		RecencyHorizon:   cfg.Scoring.RecencyHorizon,
+		Exploration:      cfg.Scoring.Exploration,
+		MinObservations:  cfg.Scoring.MinObservations,
+	}
+}
+
+func flexible(cfg *config.Config) requisite.Flexible {
+	return requisite.Flexible{
+		Step:         cfg.Invoice.FlexibleStep,
+		DefaultRange: cfg.Invoice.FlexibleRange,
+	}
+}
+
+func quarantineRules(cfg *config.Config) quarantine.Rules {
+	return quarantine.Rules{
+		ConsecutiveExpired: cfg.Quarantine.ConsecutiveExpired,
+		MinSuccessRate:     cfg.Quarantine.MinSuccessRate,
+		Window:             cfg.Quarantine.Window,
+		MinInvoices:        cfg.Quarantine.MinInvoices,
+		Duration:           cfg.Quarantine.Duration,
+	}
+}
+
+func payerLimits(cfg *config.Config) payer.Limits {
+	return payer.Limits{
+		MaxInvoices: cfg.Payer.MaxInvoices,
+		Window:      cfg.Payer.Window,
+		MaxActive:   cfg.Payer.MaxActiveInvoices,
+	}
+}
+
+func healthOptions(cfg *config.Config) health.Options {
+	return health.Options{
+		CheckTimeout:       cfg.Health.CheckTimeout,
+		MaxExchangeRateAge: cfg.Health.MaxExchangeRateAge,
+		RedisOptional:      cfg.Redis.Optional,
+	}
+}
+
+func rateLimitPolicy(cfg *config.Config) ratelimit.Policy {
+	limits := func(limit config.MerchantRateLimit) ratelimit.Limits {
+		return ratelimit.Limits{Rate: limit.Rate, Burst: limit.Burst, MaxConcurrent: limit.MaxConcurrent}
+	}
+
+	policy := ratelimit.Policy{
+		Default:   limits(cfg.RateLimit.Default),
+		Merchants: make(map[string]ratelimit.Limits, len(cfg.RateLimit.Merchants)),
+	}
+	for merchantID, limit := range cfg.RateLimit.Merchants {
+		policy.Merchants[merchantID] = limits(limit)
+	}
+	return policy
+}
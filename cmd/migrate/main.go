@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"mateo/internal/config"
+	"mateo/internal/logging"
+	"mateo/internal/store/pg/migrations"
+)
+
+const usage = `usage: migrate [-dir path] <command> [args]
+
+commands:
+  up           apply all pending migrations
+  down [n]     roll back the last n migrations (default 1)
+  status       list migrations and whether they are applied
+  create NAME  create empty up/down files for a new migration in -dir`
+
+func main() {
+	logging.Setup(os.Stderr)
+
+	dir := flag.String("dir", "internal/store/pg/migrations", "migrations source directory for create")
+	flag.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// create работает только с файлами и не требует базы
+	if args[0] == "create" {
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		upPath, downPath, err := migrations.Create(*dir, args[1])
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create migration")
+		}
+		fmt.Println(upPath)
+		fmt.Println(downPath)
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, cfg.DB.DSN())
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create connection pool")
+	}
+	defer pool.Close()
+
+	migrator, err := migrations.New(pool)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load migrations")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			log.Info().Int64("version", m.Version).Str("name", m.Name).Msg("Migration applied")
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to apply migrations")
+		}
+		if len(applied) == 0 {
+			log.Info().Msg("Schema is up to date")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatal().Str("steps", args[1]).Msg("Invalid number of steps")
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		for _, m := range rolledBack {
+			log.Info().Int64("version", m.Version).Str("name", m.Name).Msg("Migration rolled back")
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to roll back migrations")
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to get migration status")
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-40s %s\n", status.Version, status.Name, applied)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"mateo/internal/domain"
+)
+
+// ErrorUnavailable автомат разомкнут, запрос в Redis не отправлялся
+var ErrorUnavailable = errors.New("remote cache unavailable")
+
+type BreakerState string
+
+const (
+	// BreakerClosed запросы идут в Redis
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen запросы не отправляются до конца паузы
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen пауза прошла, один пробный запрос проверяет Redis
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+type BreakerOptions struct {
+	// Failures сколько ошибок подряд размыкают автомат
+	Failures int
+	// Cooldown сколько автомат остается разомкнутым до пробного запроса
+	Cooldown time.Duration
+}
+
+// Breaker автоматический выключатель перед Remote. Пока он разомкнут, кеш работает только
+// на LRU процесса и источнике. Пока Redis недоступен, удаления ключей в нем теряются,
+// поэтому после восстановления вызывается onRecover, который должен сбросить общий кеш.
+type Breaker struct {
+	remote  Remote
+	clock   domain.Clock
+	options BreakerOptions
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	openUntil time.Time
+	probing   bool
+	onRecover func(ctx context.Context)
+}
+
+func NewBreaker(remote Remote, clock domain.Clock, options BreakerOptions) *Breaker {
+	return &Breaker{
+		remote:  remote,
+		clock:   clock,
+		options: options,
+		state:   BreakerClosed,
+	}
+}
+
+// OnRecover задает вызов при замыкании автомата после недоступности Redis
+func (b *Breaker) OnRecover(onRecover func(ctx context.Context)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onRecover = onRecover
+}
+
+// State текущее состояние автомата
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && !b.clock.Now().Before(b.openUntil) {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Trip размыкает автомат, например если Redis недоступен при старте
+func (b *Breaker) Trip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.open()
+}
+
+func (b *Breaker) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	value, err := b.remote.Get(ctx, key)
+	// Отсутствие ключа — ответ Redis, а не ошибка связи
+	b.done(ctx, err != nil && !errors.Is(err, ErrorMiss))
+	return value, err
+}
+
+func (b *Breaker) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := b.remote.Set(ctx, key, value, ttl)
+	b.done(ctx, err != nil)
+	return err
+}
+
+func (b *Breaker) Del(ctx context.Context, keys ...string) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := b.remote.Del(ctx, keys...)
+	b.done(ctx, err != nil)
+	return err
+}
+
+func (b *Breaker) DelPrefix(ctx context.Context, prefix string) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := b.remote.DelPrefix(ctx, prefix)
+	b.done(ctx, err != nil)
+	return err
+}
+
+// Do выполняет через автомат команду, которой нет в Remote; fn получает ошибку связи
+// с Redis, а не отсутствие ключа. Собственный таймаут внутри fn считается ошибкой Redis,
+// отмена ctx — нет.
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := fn(ctx)
+	b.done(ctx, err != nil)
+	return err
+}
+
+// allow пропускает запрос при замкнутом автомате и один пробный запрос после паузы
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerClosed:
+		return nil
+	case BreakerOpen:
+		if b.clock.Now().Before(b.openUntil) {
+			return ErrorUnavailable
+		}
+		b.state = BreakerHalfOpen
+	}
+	if b.probing {
+		return ErrorUnavailable
+	}
+	b.probing = true
+	return nil
+}
+
+func (b *Breaker) done(ctx context.Context, failed bool) {
+	b.mu.Lock()
+
+	// Отмена или дедлайн вызывающего говорят о клиенте, а не о Redis: счетчик ошибок
+	// не меняется, пробный запрос уступает место следующему
+	if failed && ctx.Err() != nil {
+		b.probing = false
+		b.mu.Unlock()
+		return
+	}
+
+	if failed {
+		b.failures++
+		if b.state == BreakerHalfOpen || b.failures >= b.options.Failures {
+			if b.state == BreakerClosed {
+				log.Ctx(ctx).Error().Int("failures", b.failures).Dur("cooldown", b.options.Cooldown).
+					Msg("redis is unavailable, serving cache from process memory")
+			}
+			b.open()
+		}
+		b.mu.Unlock()
+		return
+	}
+
+	b.failures = 0
+	if b.state != BreakerHalfOpen {
+		b.mu.Unlock()
+		return
+	}
+
+	b.state = BreakerClosed
+	b.probing = false
+	onRecover := b.onRecover
+	b.mu.Unlock()
+
+	log.Ctx(ctx).Info().Msg("redis is available again")
+	if onRecover != nil {
+		onRecover(context.WithoutCancel(ctx))
+	}
+}
+
+func (b *Breaker) open() {
+	b.state = BreakerOpen
+	b.probing = false
+	b.openUntil = b.clock.Now().Add(b.options.Cooldown)
+}
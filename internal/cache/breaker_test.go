@@ -0,0 +1,162 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mateo/internal/cache"
+	"mateo/internal/fake"
+)
+
+var errorRedis = errors.New("dial tcp: connection refused")
+
+func breakerOptions() cache.BreakerOptions {
+	return cache.BreakerOptions{Failures: 2, Cooldown: 10 * time.Second}
+}
+
+func fail(context.Context) error { return errorRedis }
+
+func succeed(context.Context) error { return nil }
+
+func TestBreakerOpensAfterFailures(t *testing.T) {
+	clock := fake.NewClock(start)
+	breaker := cache.NewBreaker(newMemoryRemote(), clock, breakerOptions())
+
+	// Отсутствие ключа не считается ошибкой Redis
+	for i := 0; i < 3; i++ {
+		_, err := breaker.Get(context.Background(), "merchant:missing")
+		require.True(t, errors.Is(err, cache.ErrorMiss), "got error %v", err)
+	}
+	assert.Equal(t, cache.BreakerClosed, breaker.State())
+
+	assert.Equal(t, errorRedis, breaker.Do(context.Background(), fail))
+	assert.Equal(t, cache.BreakerClosed, breaker.State())
+	assert.Equal(t, errorRedis, breaker.Do(context.Background(), fail))
+	assert.Equal(t, cache.BreakerOpen, breaker.State())
+
+	called := false
+	err := breaker.Do(context.Background(), func(context.Context) error {
+		called = true
+		return nil
+	})
+	assert.True(t, errors.Is(err, cache.ErrorUnavailable), "got error %v", err)
+	assert.False(t, called)
+
+	clock.Advance(breakerOptions().Cooldown)
+	assert.Equal(t, cache.BreakerHalfOpen, breaker.State())
+}
+
+func TestBreakerHalfOpenSingleProbe(t *testing.T) {
+	clock := fake.NewClock(start)
+	breaker := cache.NewBreaker(newMemoryRemote(), clock, breakerOptions())
+	breaker.Trip()
+	clock.Advance(breakerOptions().Cooldown)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	probe := make(chan error)
+	go func() {
+		probe <- breaker.Do(context.Background(), func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+
+	// Пока пробный запрос не завершился, остальные в Redis не идут
+	<-started
+	_, err := breaker.Get(context.Background(), "merchant:merchant-1")
+	assert.True(t, errors.Is(err, cache.ErrorUnavailable), "got error %v", err)
+
+	close(release)
+	require.NoError(t, <-probe)
+	assert.Equal(t, cache.BreakerClosed, breaker.State())
+
+	_, err = breaker.Get(context.Background(), "merchant:merchant-1")
+	assert.True(t, errors.Is(err, cache.ErrorMiss), "got error %v", err)
+}
+
+func TestBreakerFailedProbeReopens(t *testing.T) {
+	clock := fake.NewClock(start)
+	breaker := cache.NewBreaker(newMemoryRemote(), clock, breakerOptions())
+	breaker.Trip()
+	clock.Advance(breakerOptions().Cooldown)
+
+	// Одной ошибки пробного запроса достаточно, пауза начинается заново
+	assert.Equal(t, errorRedis, breaker.Do(context.Background(), fail))
+	assert.Equal(t, cache.BreakerOpen, breaker.State())
+
+	clock.Advance(breakerOptions().Cooldown - time.Nanosecond)
+	assert.True(t, errors.Is(breaker.Do(context.Background(), succeed), cache.ErrorUnavailable))
+
+	clock.Advance(time.Nanosecond)
+	require.NoError(t, breaker.Do(context.Background(), succeed))
+	assert.Equal(t, cache.BreakerClosed, breaker.State())
+}
+
+func TestBreakerIgnoresCallerCancellation(t *testing.T) {
+	clock := fake.NewClock(start)
+	breaker := cache.NewBreaker(newMemoryRemote(), clock, breakerOptions())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, errorRedis, breaker.Do(ctx, fail))
+	}
+	assert.Equal(t, cache.BreakerClosed, breaker.State())
+
+	// Ошибки до и после отмены не складываются с ней
+	assert.Equal(t, errorRedis, breaker.Do(context.Background(), fail))
+	assert.Equal(t, errorRedis, breaker.Do(ctx, fail))
+	assert.Equal(t, cache.BreakerClosed, breaker.State())
+
+	// Отмененный пробный запрос уступает место следующему, автомат не размыкается снова
+	breaker.Trip()
+	clock.Advance(breakerOptions().Cooldown)
+	assert.Equal(t, errorRedis, breaker.Do(ctx, fail))
+	assert.Equal(t, cache.BreakerHalfOpen, breaker.State())
+	require.NoError(t, breaker.Do(context.Background(), succeed))
+	assert.Equal(t, cache.BreakerClosed, breaker.State())
+}
+
+func TestBreakerOnRecover(t *testing.T) {
+	clock := fake.NewClock(start)
+	remote := newMemoryRemote()
+	breaker := cache.NewBreaker(remote, clock, breakerOptions())
+
+	c := cache.New[string]("merchant", breaker, clock, fake.NewRand(), testOptions())
+	source := &loader{value: "v1"}
+	_, err := c.Get(context.Background(), "merchant-1", source.load)
+	require.NoError(t, err)
+	require.True(t, remote.has("merchant:merchant-1"))
+
+	recovered := 0
+	breaker.OnRecover(func(ctx context.Context) {
+		recovered++
+		c.InvalidateAll(ctx)
+	})
+
+	// Удаление при разомкнутом автомате теряется, после восстановления общий кеш сбрасывается
+	breaker.Trip()
+	c.Invalidate(context.Background(), "merchant-1")
+	assert.True(t, remote.has("merchant:merchant-1"))
+	assert.Zero(t, recovered)
+
+	clock.Advance(breakerOptions().Cooldown)
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, breaker.Do(ctx, func(context.Context) error {
+		// onRecover получает контекст без отмены вызывающего
+		cancel()
+		return nil
+	}))
+	assert.Equal(t, 1, recovered)
+	assert.False(t, remote.has("merchant:merchant-1"))
+
+	// Обычные успешные запросы onRecover не вызывают
+	require.NoError(t, breaker.Do(context.Background(), succeed))
+	assert.Equal(t, 1, recovered)
+}
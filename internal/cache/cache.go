@@ -0,0 +1,334 @@
+// Package cache двухуровневый кеш: LRU процесса перед общим для реплик кешем в Redis.
+//
+// Промахи одного ключа внутри процесса объединяются в одну загрузку. Незадолго до истечения
+// запись обновляется в фоне; момент обновления выбирается случайно, чтобы реплики не шли
+// в источник одновременно. Если источник недоступен, до истечения StaleTTL отдается
+// устаревшее значение; если источник ответил, что значения нет, ключ удаляется.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
+	"mateo/internal/domain"
+)
+
+type Options struct {
+	// LocalSize сколько записей хранит LRU процесса
+	LocalSize int
+	// TTL сколько запись считается свежей
+	TTL time.Duration
+	// StaleTTL сколько после TTL запись может отдаваться при ошибке источника
+	StaleTTL time.Duration
+	// StaleRetry через сколько после отдачи устаревшего значения источник опрашивается снова
+	StaleRetry time.Duration
+	// EarlyRefresh доля TTL в конце срока, в которой запись обновляется в фоне; 0 выключает обновление
+	EarlyRefresh float64
+	// LoadTimeout ограничивает загрузку из источника; загрузка не прерывается отменой запроса,
+	// который ее начал, поскольку результат ждут и другие запросы
+	LoadTimeout time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		LocalSize:    1000,
+		TTL:          5 * time.Minute,
+		StaleTTL:     time.Hour,
+		StaleRetry:   5 * time.Second,
+		EarlyRefresh: 0.2,
+		LoadTimeout:  5 * time.Second,
+	}
+}
+
+// Stats счетчики обращений к кешу с момента запуска
+type Stats struct {
+	// LocalHits запросы, обслуженные LRU процесса
+	LocalHits uint64
+	// RemoteHits загрузки, обслуженные Redis
+	RemoteHits uint64
+	// Loads загрузки из источника
+	Loads uint64
+	// Stale загрузки, на которые при ошибке источника отдано устаревшее значение
+	Stale uint64
+	// Errors загрузки, завершившиеся ошибкой
+	Errors uint64
+}
+
+// entry значение со сроками; в Redis хранится в JSON
+type entry[V any] struct {
+	Value      V         `json:"value"`
+	FreshUntil time.Time `json:"freshUntil"`
+	StaleUntil time.Time `json:"staleUntil"`
+	// refreshAt момент фонового обновления в этом процессе; нулевой, если обновление не нужно
+	refreshAt time.Time
+}
+
+// Loader загружает значение из источника
+type Loader[V any] func(ctx context.Context) (V, error)
+
+// Cache кеш значений одного вида. Значения отдаются всем читателям без копирования
+// и не должны изменяться.
+type Cache[V any] struct {
+	name    string
+	remote  Remote
+	local   *lru[V]
+	group   singleflight.Group
+	clock   domain.Clock
+	rand    domain.Rand
+	options atomic.Pointer[Options]
+	// evictOn ошибки источника, означающие, что значения больше нет
+	evictOn []error
+	// generation меняется при сбросе: загрузки, начатые до сброса, не сохраняют результат
+	generation atomic.Uint64
+
+	localHits  atomic.Uint64
+	remoteHits atomic.Uint64
+	loads      atomic.Uint64
+	stale      atomic.Uint64
+	failures   atomic.Uint64
+}
+
+// New создает кеш; ключи в Redis получают префикс name
+func New[V any](name string, remote Remote, clock domain.Clock, rand domain.Rand, options Options) *Cache[V] {
+	c := &Cache[V]{
+		name:   name,
+		remote: remote,
+		local:  newLRU[V](options.LocalSize),
+		clock:  clock,
+		rand:   rand,
+	}
+	c.options.Store(&options)
+	return c
+}
+
+// WithEvictOn задает ошибки источника, которые означают, что значения нет, например
+// «не найдено». На них устаревшее значение не отдается, а ключ удаляется из кеша.
+func (c *Cache[V]) WithEvictOn(errs ...error) *Cache[V] {
+	c.evictOn = errs
+	return c
+}
+
+// SetOptions меняет сроки работающего кеша. Записи, уже лежащие в кеше, сохраняют свои сроки.
+// Размер LRU задается только при создании, LocalSize игнорируется.
+func (c *Cache[V]) SetOptions(options Options) {
+	c.options.Store(&options)
+}
+
+// Get возвращает значение ключа, при промахе загружая его через load.
+// Пустой ключ используется кешами одного значения.
+func (c *Cache[V]) Get(ctx context.Context, key string, load Loader[V]) (V, error) {
+	now := c.clock.Now()
+	if e, ok := c.local.get(key); ok && now.Before(e.FreshUntil) {
+		if !e.refreshAt.IsZero() && !now.Before(e.refreshAt) {
+			e.refreshAt = time.Time{}
+			c.local.add(key, e)
+			c.refresh(ctx, key, e.FreshUntil, load)
+		}
+		c.localHits.Add(1)
+		return e.Value, nil
+	}
+
+	result := c.group.DoChan(key, func() (any, error) {
+		return c.load(ctx, key, time.Time{}, load)
+	})
+
+	select {
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	case r := <-result:
+		if r.Err != nil {
+			var zero V
+			return zero, r.Err
+		}
+		return r.Val.(V), nil
+	}
+}
+
+func (c *Cache[V]) Stats() Stats {
+	return Stats{
+		LocalHits:  c.localHits.Load(),
+		RemoteHits: c.remoteHits.Load(),
+		Loads:      c.loads.Load(),
+		Stale:      c.stale.Load(),
+		Errors:     c.failures.Load(),
+	}
+}
+
+// Invalidate сбрасывает ключ в процессе и в Redis
+func (c *Cache[V]) Invalidate(ctx context.Context, key string) {
+	c.generation.Add(1)
+	c.local.remove(key)
+	c.group.Forget(key)
+
+	if err := c.remote.Del(ctx, c.remoteKey(key)); err != nil && !errors.Is(err, ErrorUnavailable) {
+		log.Ctx(ctx).Error().Err(err).Str("cache", c.name).Str("key", key).Msg("failed to invalidate cache key")
+	}
+}
+
+// InvalidateAll сбрасывает все ключи кеша
+func (c *Cache[V]) InvalidateAll(ctx context.Context) {
+	c.generation.Add(1)
+	c.local.clear()
+
+	if err := c.remote.Del(ctx, c.name); err != nil && !errors.Is(err, ErrorUnavailable) {
+		log.Ctx(ctx).Error().Err(err).Str("cache", c.name).Msg("failed to invalidate cache")
+	}
+	if err := c.remote.DelPrefix(ctx, c.name+":"); err != nil && !errors.Is(err, ErrorUnavailable) {
+		log.Ctx(ctx).Error().Err(err).Str("cache", c.name).Msg("failed to invalidate cache")
+	}
+}
+
+// refresh обновляет запись в фоне; ошибка оставляет текущую запись до ее истечения
+func (c *Cache[V]) refresh(ctx context.Context, key string, freshUntil time.Time, load Loader[V]) {
+	go func() {
+		_, err, _ := c.group.Do(key, func() (any, error) {
+			return c.load(ctx, key, freshUntil, load)
+		})
+		if err != nil {
+			log.Ctx(ctx).Warn().Err(err).Str("cache", c.name).Str("key", key).Msg("failed to refresh cache entry")
+		}
+	}()
+}
+
+// load читает ключ из Redis, а если там нет записи свежее newerThan — из источника.
+// При ошибке источника отдается устаревшее значение, если оно еще не истекло,
+// кроме ошибок evictOn.
+// От ctx запроса, начавшего загрузку, берутся только значения, например трасса.
+func (c *Cache[V]) load(ctx context.Context, key string, newerThan time.Time, load Loader[V]) (any, error) {
+	options := c.options.Load()
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), options.LoadTimeout)
+	defer cancel()
+
+	generation := c.generation.Load()
+	now := c.clock.Now()
+
+	var stale *entry[V]
+	if e, ok := c.local.get(key); ok && now.Before(e.StaleUntil) {
+		stale = &e
+	}
+
+	if e, ok := c.getRemote(ctx, key); ok {
+		if now.Before(e.FreshUntil) && e.FreshUntil.After(newerThan) {
+			c.storeLocal(key, e, generation)
+			c.remoteHits.Add(1)
+			return e.Value, nil
+		}
+		if now.Before(e.StaleUntil) && (stale == nil || e.FreshUntil.After(stale.FreshUntil)) {
+			stale = &e
+		}
+	}
+
+	c.loads.Add(1)
+	value, err := load(ctx)
+	if err != nil {
+		if c.evicts(err) {
+			c.evict(ctx, key)
+			return nil, err
+		}
+		if stale == nil {
+			c.failures.Add(1)
+			return nil, errors.Wrapf(err, "load %s", c.name)
+		}
+		c.stale.Add(1)
+
+		log.Ctx(ctx).Warn().Err(err).Str("cache", c.name).Str("key", key).Msg("serving stale cache entry")
+		// Источник опрашивается снова не раньше StaleRetry
+		retry := *stale
+		retry.FreshUntil = minTime(now.Add(options.StaleRetry), stale.StaleUntil)
+		c.storeLocal(key, retry, generation)
+		return stale.Value, nil
+	}
+
+	e := entry[V]{
+		Value:      value,
+		FreshUntil: now.Add(options.TTL),
+		StaleUntil: now.Add(options.TTL + options.StaleTTL),
+	}
+	if c.generation.Load() == generation {
+		c.setRemote(ctx, key, e)
+		c.storeLocal(key, e, generation)
+	}
+	return value, nil
+}
+
+func (c *Cache[V]) evicts(err error) bool {
+	for _, target := range c.evictOn {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// evict удаляет ключ, который источник больше не возвращает
+func (c *Cache[V]) evict(ctx context.Context, key string) {
+	c.local.remove(key)
+	if err := c.remote.Del(ctx, c.remoteKey(key)); err != nil && !errors.Is(err, ErrorUnavailable) {
+		log.Ctx(ctx).Warn().Err(err).Str("cache", c.name).Str("key", key).Msg("failed to evict cache key")
+	}
+}
+
+// storeLocal сохраняет запись в LRU и назначает ей случайный момент фонового обновления
+func (c *Cache[V]) storeLocal(key string, e entry[V], generation uint64) {
+	if c.generation.Load() != generation {
+		return
+	}
+
+	e.refreshAt = time.Time{}
+	if options := c.options.Load(); options.EarlyRefresh > 0 {
+		early := time.Duration(float64(options.TTL) * options.EarlyRefresh * c.rand.Float64())
+		e.refreshAt = e.FreshUntil.Add(-early)
+	}
+	c.local.add(key, e)
+}
+
+func (c *Cache[V]) getRemote(ctx context.Context, key string) (entry[V], bool) {
+	data, err := c.remote.Get(ctx, c.remoteKey(key))
+	if err != nil {
+		if !errors.Is(err, ErrorMiss) && !errors.Is(err, ErrorUnavailable) {
+			log.Ctx(ctx).Warn().Err(err).Str("cache", c.name).Str("key", key).Msg("failed to read remote cache")
+		}
+		return entry[V]{}, false
+	}
+
+	var e entry[V]
+	if err := json.Unmarshal(data, &e); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("cache", c.name).Str("key", key).Msg("failed to decode remote cache entry")
+		return entry[V]{}, false
+	}
+	return e, true
+}
+
+func (c *Cache[V]) setRemote(ctx context.Context, key string, e entry[V]) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("cache", c.name).Msg("failed to encode cache entry")
+		return
+	}
+
+	options := c.options.Load()
+	err = c.remote.Set(ctx, c.remoteKey(key), data, options.TTL+options.StaleTTL)
+	if err != nil && !errors.Is(err, ErrorUnavailable) {
+		log.Ctx(ctx).Warn().Err(err).Str("cache", c.name).Str("key", key).Msg("failed to write remote cache")
+	}
+}
+
+func (c *Cache[V]) remoteKey(key string) string {
+	if key == "" {
+		return c.name
+	}
+	return c.name + ":" + key
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
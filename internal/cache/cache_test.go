@@ -0,0 +1,309 @@
+package cache_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mateo/internal/cache"
+	"mateo/internal/fake"
+)
+
+// memoryRemote Remote в памяти; сроки хранения не соблюдаются, их проверяет сам кеш
+type memoryRemote struct {
+	mu    sync.Mutex
+	items map[string][]byte
+}
+
+func newMemoryRemote() *memoryRemote {
+	return &memoryRemote{items: make(map[string][]byte)}
+}
+
+func (r *memoryRemote) Get(_ context.Context, key string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, ok := r.items[key]
+	if !ok {
+		return nil, cache.ErrorMiss
+	}
+	return value, nil
+}
+
+func (r *memoryRemote) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[key] = value
+	return nil
+}
+
+func (r *memoryRemote) Del(_ context.Context, keys ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range keys {
+		delete(r.items, key)
+	}
+	return nil
+}
+
+func (r *memoryRemote) DelPrefix(_ context.Context, prefix string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key := range r.items {
+		if strings.HasPrefix(key, prefix) {
+			delete(r.items, key)
+		}
+	}
+	return nil
+}
+
+func (r *memoryRemote) has(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.items[key]
+	return ok
+}
+
+var (
+	start         = time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC)
+	errorNotFound = errors.New("not found")
+	errorDatabase = errors.New("database is down")
+)
+
+func testOptions() cache.Options {
+	return cache.Options{
+		LocalSize:   10,
+		TTL:         100 * time.Second,
+		StaleTTL:    time.Hour,
+		StaleRetry:  5 * time.Second,
+		LoadTimeout: time.Second,
+	}
+}
+
+// loader источник с подсчетом вызовов; возвращает value или err
+type loader struct {
+	calls atomic.Int32
+	mu    sync.Mutex
+	value string
+	err   error
+}
+
+func (l *loader) set(value string, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.value, l.err = value, err
+}
+
+func (l *loader) load(context.Context) (string, error) {
+	l.calls.Add(1)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.value, l.err
+}
+
+func TestConcurrentMissesShareLoad(t *testing.T) {
+	c := cache.New[string]("merchant", newMemoryRemote(), fake.NewClock(start), fake.NewRand(), testOptions())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	load := func(context.Context) (string, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return "v1", nil
+	}
+
+	const readers = 8
+	values := make(chan string, readers)
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := c.Get(context.Background(), "merchant-1", load)
+			assert.NoError(t, err)
+			values <- value
+		}()
+	}
+
+	<-started
+	// Даем остальным читателям присоединиться к загрузке
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(values)
+
+	for value := range values {
+		assert.Equal(t, "v1", value)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, uint64(1), c.Stats().Loads)
+}
+
+func TestRemoteSharedBetweenReplicas(t *testing.T) {
+	remote := newMemoryRemote()
+	clock := fake.NewClock(start)
+	source := &loader{value: "v1"}
+
+	first := cache.New[string]("merchant", remote, clock, fake.NewRand(), testOptions())
+	second := cache.New[string]("merchant", remote, clock, fake.NewRand(), testOptions())
+
+	value, err := first.Get(context.Background(), "merchant-1", source.load)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", value)
+	assert.True(t, remote.has("merchant:merchant-1"))
+
+	value, err = second.Get(context.Background(), "merchant-1", source.load)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", value)
+	assert.Equal(t, int32(1), source.calls.Load())
+	assert.Equal(t, uint64(1), second.Stats().RemoteHits)
+}
+
+func TestEarlyRefreshWithJitter(t *testing.T) {
+	clock := fake.NewClock(start)
+	options := testOptions()
+	options.EarlyRefresh = 0.2
+	// Обновление в последней доле TTL: 0.5 * 0.2 * 100s = 10s до истечения
+	c := cache.New[string]("merchant", newMemoryRemote(), clock, fake.NewRand().WithFloats(0.5), options)
+	source := &loader{value: "v1"}
+
+	_, err := c.Get(context.Background(), "merchant-1", source.load)
+	require.NoError(t, err)
+
+	clock.Set(start.Add(90*time.Second - time.Nanosecond))
+	value, err := c.Get(context.Background(), "merchant-1", source.load)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", value)
+	assert.Equal(t, int32(1), source.calls.Load())
+
+	// В момент обновления читатель получает текущее значение, а новое загружается в фоне
+	source.set("v2", nil)
+	clock.Set(start.Add(90 * time.Second))
+	value, err = c.Get(context.Background(), "merchant-1", source.load)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", value)
+
+	require.Eventually(t, func() bool {
+		value, err := c.Get(context.Background(), "merchant-1", source.load)
+		return err == nil && value == "v2"
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(2), source.calls.Load())
+}
+
+func TestStaleFallback(t *testing.T) {
+	clock := fake.NewClock(start)
+	options := testOptions()
+	options.StaleTTL = time.Minute
+	c := cache.New[string]("merchant", newMemoryRemote(), clock, fake.NewRand(), options)
+	source := &loader{value: "v1"}
+
+	_, err := c.Get(context.Background(), "merchant-1", source.load)
+	require.NoError(t, err)
+
+	// Источник недоступен после TTL: отдается устаревшее значение
+	source.set("", errorDatabase)
+	clock.Advance(options.TTL)
+	value, err := c.Get(context.Background(), "merchant-1", source.load)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", value)
+	assert.Equal(t, int32(2), source.calls.Load())
+	assert.Equal(t, uint64(1), c.Stats().Stale)
+
+	// До StaleRetry источник не опрашивается
+	clock.Advance(options.StaleRetry - time.Nanosecond)
+	_, err = c.Get(context.Background(), "merchant-1", source.load)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), source.calls.Load())
+
+	clock.Advance(time.Nanosecond)
+	_, err = c.Get(context.Background(), "merchant-1", source.load)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), source.calls.Load())
+
+	// После StaleTTL ошибка источника возвращается читателю
+	clock.Set(start.Add(options.TTL + options.StaleTTL))
+	_, err = c.Get(context.Background(), "merchant-1", source.load)
+	assert.True(t, errors.Is(err, errorDatabase), "got error %v", err)
+	assert.Equal(t, uint64(1), c.Stats().Errors)
+}
+
+func TestEvictOn(t *testing.T) {
+	clock := fake.NewClock(start)
+	remote := newMemoryRemote()
+	c := cache.New[string]("merchant", remote, clock, fake.NewRand(), testOptions()).WithEvictOn(errorNotFound)
+	source := &loader{value: "v1"}
+
+	_, err := c.Get(context.Background(), "merchant-1", source.load)
+	require.NoError(t, err)
+
+	// Удаленное в источнике значение не отдается устаревшим и убирается из Redis
+	source.set("", errorNotFound)
+	clock.Advance(testOptions().TTL)
+	_, err = c.Get(context.Background(), "merchant-1", source.load)
+	assert.True(t, errors.Is(err, errorNotFound), "got error %v", err)
+	assert.False(t, remote.has("merchant:merchant-1"))
+	assert.Zero(t, c.Stats().Stale)
+
+	_, err = c.Get(context.Background(), "merchant-1", source.load)
+	assert.True(t, errors.Is(err, errorNotFound), "got error %v", err)
+	assert.Equal(t, int32(3), source.calls.Load())
+}
+
+func TestInvalidateDuringLoad(t *testing.T) {
+	remote := newMemoryRemote()
+	c := cache.New[string]("merchant", remote, fake.NewClock(start), fake.NewRand(), testOptions())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	stale := func(context.Context) (string, error) {
+		close(started)
+		<-release
+		return "before", nil
+	}
+
+	result := make(chan string)
+	go func() {
+		value, err := c.Get(context.Background(), "merchant-1", stale)
+		assert.NoError(t, err)
+		result <- value
+	}()
+
+	// Сброс во время загрузки: ее результат отдается ждущему читателю, но не сохраняется
+	<-started
+	c.Invalidate(context.Background(), "merchant-1")
+	close(release)
+	assert.Equal(t, "before", <-result)
+	assert.False(t, remote.has("merchant:merchant-1"))
+
+	source := &loader{value: "after"}
+	value, err := c.Get(context.Background(), "merchant-1", source.load)
+	require.NoError(t, err)
+	assert.Equal(t, "after", value)
+	assert.Equal(t, int32(1), source.calls.Load())
+}
+
+func TestInvalidateAll(t *testing.T) {
+	remote := newMemoryRemote()
+	c := cache.New[string]("merchant", remote, fake.NewClock(start), fake.NewRand(), testOptions())
+	source := &loader{value: "v1"}
+
+	for _, key := range []string{"merchant-1", "merchant-2"} {
+		_, err := c.Get(context.Background(), key, source.load)
+		require.NoError(t, err)
+	}
+	c.InvalidateAll(context.Background())
+	assert.False(t, remote.has("merchant:merchant-1"))
+	assert.False(t, remote.has("merchant:merchant-2"))
+
+	_, err := c.Get(context.Background(), "merchant-1", source.load)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), source.calls.Load())
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru локальный кеш процесса с вытеснением давно не читанных записей
+type lru[V any] struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type lruItem[V any] struct {
+	key   string
+	entry entry[V]
+}
+
+func newLRU[V any](capacity int) *lru[V] {
+	return &lru[V]{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (l *lru[V]) get(key string) (entry[V], bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.items[key]
+	if !ok {
+		return entry[V]{}, false
+	}
+	l.order.MoveToFront(element)
+	return element.Value.(*lruItem[V]).entry, true
+}
+
+func (l *lru[V]) add(key string, e entry[V]) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if element, ok := l.items[key]; ok {
+		element.Value.(*lruItem[V]).entry = e
+		l.order.MoveToFront(element)
+		return
+	}
+
+	l.items[key] = l.order.PushFront(&lruItem[V]{key: key, entry: e})
+	for l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruItem[V]).key)
+	}
+}
+
+func (l *lru[V]) remove(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if element, ok := l.items[key]; ok {
+		l.order.Remove(element)
+		delete(l.items, key)
+	}
+}
+
+func (l *lru[V]) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.order.Init()
+	l.items = make(map[string]*list.Element)
+}
@@ -0,0 +1,143 @@
+// Package callback отправляет мерчанту уведомление о смене статуса Invoice на его CallbackURL.
+//
+// Тело запроса подписывается HMAC-SHA256 ключом callbackKey Invoice; подпись в hex передается
+// в заголовке X-Signature. Мерчант проверяет ее тем же ключом по сырому телу запроса.
+//
+// CallbackURL задает мерчант, поэтому соединения с loopback, частными и link-local адресами
+// запрещены. Адрес проверяется при подключении, уже после разрешения имени, так что запрет
+// не обходится ни DNS-записью на внутренний адрес, ни редиректом.
+package callback
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"mateo/internal/domain"
+)
+
+const (
+	SignatureHeader = "X-Signature"
+
+	currencyCode = "RUB"
+)
+
+var (
+	ErrorCallbackRejected = errors.New("callback rejected by merchant")
+	ErrorForbiddenHost    = errors.New("callback host is not a public address")
+)
+
+// Payload тело callback
+type Payload struct {
+	InvoiceID         string `json:"invoiceId"`
+	InvoiceStatus     string `json:"invoiceStatus"`
+	Amount            string `json:"amount"`
+	CurrencyCode      string `json:"currencyCode"`
+	MerchantID        string `json:"merchantId"`
+	InternalRequestID string `json:"internalRequestId"`
+	// Timestamp время отправки в unix-секундах; входит в подпись и защищает от повтора
+	Timestamp int64 `json:"timestamp"`
+}
+
+type Client struct {
+	http  *http.Client
+	clock domain.Clock
+}
+
+func NewClient(timeout time.Duration, clock domain.Clock) *Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: publicOnly}
+	return &Client{
+		http: &http.Client{
+			Timeout: timeout,
+			// Без прокси: через него проверка адреса при подключении теряет смысл
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+		},
+		clock: clock,
+	}
+}
+
+// publicOnly запрещает подключение к адресам, недоступным из интернета
+func publicOnly(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return errors.Wrap(err, "parse callback address")
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublic(ip) {
+		return errors.Wrapf(ErrorForbiddenHost, "address %s", host)
+	}
+	return nil
+}
+
+func isPublic(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// Send отправляет callback о текущем статусе Invoice. Ответ не из диапазона 2xx
+// возвращается как ErrorCallbackRejected.
+func (c *Client) Send(ctx context.Context, invoice *domain.Invoice) error {
+	body, err := json.Marshal(Payload{
+		InvoiceID:         invoice.ID,
+		InvoiceStatus:     string(invoice.Status),
+		Amount:            invoice.Amount.String(),
+		CurrencyCode:      currencyCode,
+		MerchantID:        invoice.MerchantID,
+		InternalRequestID: invoice.InternalRequestID,
+		Timestamp:         c.clock.Now().Unix(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "marshal callback")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, invoice.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "build callback request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(body, invoice.CallbackKey))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "send callback")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Wrapf(ErrorCallbackRejected, "status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign возвращает hex HMAC-SHA256 тела callback
+func Sign(body []byte, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет hex-подпись тела тем же способом, каким подписывается callback
+func Verify(body []byte, key string, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,58 @@
+package callback_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mateo/internal/callback"
+	"mateo/internal/domain"
+	"mateo/internal/fake"
+)
+
+func TestSendRejectsInternalHosts(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	client := callback.NewClient(time.Second, fake.NewClock(time.Unix(0, 0)))
+
+	for _, url := range []string{
+		server.URL,
+		"http://localhost:1/callback",
+		"http://10.0.0.1/callback",
+		"http://172.16.0.1/callback",
+		"http://192.168.1.1/callback",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/callback",
+		"http://[fe80::1]/callback",
+		"http://0.0.0.0/callback",
+	} {
+		t.Run(url, func(t *testing.T) {
+			invoice := &domain.Invoice{ID: "invoice-1", Amount: decimal.NewFromInt(500), CallbackURL: url, CallbackKey: "key"}
+			err := client.Send(context.Background(), invoice)
+			assert.True(t, errors.Is(err, callback.ErrorForbiddenHost), "got error %v", err)
+		})
+	}
+	assert.False(t, called, "request reached a loopback server")
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"merchantID":"merchant-1","outcome":"success"}`)
+	signature := callback.Sign(body, "key")
+
+	assert.True(t, callback.Verify(body, "key", signature))
+	assert.False(t, callback.Verify(body, "other", signature))
+	assert.False(t, callback.Verify([]byte(`{}`), "key", signature))
+	assert.False(t, callback.Verify(body, "key", "not hex"))
+	assert.False(t, callback.Verify(body, "key", ""))
+	require.Len(t, signature, 64)
+}
@@ -3,83 +3,479 @@ package config
 import (
 	"fmt"
 	"os"
-	"strconv"
+	"strings"
 	"time"
+	// Встраиваем базу часовых поясов: в alpine-образе ее нет
+	_ "time/tzdata"
 
 	"github.com/joho/godotenv"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	HTTP  HTTPConfig
-	DB    DBConfig
-	Redis RedisConfig
+	HTTP       HTTPConfig       `yaml:"http"`
+	DB         DBConfig         `yaml:"db"`
+	Redis      RedisConfig      `yaml:"redis"`
+	Invoice    InvoiceConfig    `yaml:"invoice"`
+	Business   BusinessConfig   `yaml:"business"`
+	Sandbox    SandboxConfig    `yaml:"sandbox"`
+	Admin      AdminConfig      `yaml:"admin"`
+	Quarantine QuarantineConfig `yaml:"quarantine"`
+	Scoring    ScoringConfig    `yaml:"scoring"`
+	Payer      PayerConfig      `yaml:"payer"`
+	Cache      CacheConfig      `yaml:"cache"`
+	Health     HealthConfig     `yaml:"health"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
 }
 
 type HTTPConfig struct {
-	Port            string
-	ShutdownTimeout time.Duration
+	Port            string        `yaml:"port"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// DrainDelay сколько сервис отвечает неготовым до закрытия сервера при остановке
+	DrainDelay time.Duration `yaml:"drain_delay"`
+	// MetricsPort порт внутреннего слушателя /metrics; пустой выключает метрики
+	MetricsPort string `yaml:"metrics_port"`
+	// ReadTimeout, WriteTimeout и IdleTimeout таймауты соединений API; 0 — без ограничения
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// BodyLimit наибольший размер тела запроса в байтах
+	BodyLimit int `yaml:"body_limit"`
 }
 
 type DBConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	DBName   string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+	// ConnectTimeout ограничивает проверку подключения при старте
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	Pool           PoolConfig    `yaml:"pool"`
+}
+
+// PoolConfig параметры pgxpool
+type PoolConfig struct {
+	MaxConns          int32         `yaml:"max_conns"`
+	MinConns          int32         `yaml:"min_conns"`
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime"`
+	MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period"`
 }
 
 type RedisConfig struct {
-	Addr     string
-	Password string
-	DB       int
+	Addr     string `yaml:"addr"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+	// Optional разрешает старт без Redis; кеш тогда работает в памяти процесса
+	Optional bool `yaml:"optional"`
+	// BreakerFailures сколько ошибок Redis подряд отключают его на BreakerCooldown
+	BreakerFailures int           `yaml:"breaker_failures"`
+	BreakerCooldown time.Duration `yaml:"breaker_cooldown"`
 }
 
-// Load loads configuration from environment variables
-func Load() (*Config, error) {
-	// Load .env file if it exists
-	_ = godotenv.Load()
+type InvoiceConfig struct {
+	// ExpirationInterval как часто закрываются просроченные Invoice и освобождается hold
+	ExpirationInterval time.Duration `yaml:"expiration_interval"`
+	// DefaultTTL время жизни Invoice, если мерчант не передал activeTime
+	DefaultTTL time.Duration `yaml:"default_ttl"`
+	// FlexibleStep шаг гибкой суммы; FlexibleRange диапазон, если мерчант не передал flexibleRange
+	FlexibleStep  int `yaml:"flexible_step"`
+	FlexibleRange int `yaml:"flexible_range"`
+}
+
+type BusinessConfig struct {
+	// Timezone часовой пояс бизнес-дня: в его полночь сбрасываются дневные лимиты
+	Timezone string         `yaml:"timezone"`
+	Location *time.Location `yaml:"-"`
+	// MaxRollingWindow верхняя граница скользящих окон лимитов терминалов и реквизитов
+	MaxRollingWindow time.Duration `yaml:"max_rolling_window"`
+	// CounterPruneInterval как часто удаляются корзины счетчиков за пределами окон лимитов
+	CounterPruneInterval time.Duration `yaml:"counter_prune_interval"`
+}
+
+type SandboxConfig struct {
+	// Enabled разрешает Invoice мерчантов песочницы; при выключенной песочнице они отклоняются
+	Enabled bool `yaml:"enabled"`
+	// CallbackTimeout таймаут запроса callback мерчанту
+	CallbackTimeout time.Duration `yaml:"callback_timeout"`
+	// InvoiceRetention сколько хранятся закрытые Invoice песочницы
+	InvoiceRetention time.Duration `yaml:"invoice_retention"`
+}
+
+type AdminConfig struct {
+	// Tokens токены админки по именам; имя попадает в журнал изменений как автор.
+	// Пустой список выключает админку.
+	Tokens map[string]string `yaml:"tokens"`
+}
+
+type QuarantineConfig struct {
+	// Enabled включает автоматический карантин реквизитов и терминалов
+	Enabled bool `yaml:"enabled"`
+	// ConsecutiveExpired после скольких истекших подряд Invoice наступает карантин; 0 выключает правило
+	ConsecutiveExpired int `yaml:"consecutive_expired"`
+	// MinSuccessRate минимальная доля оплаченных Invoice за Window; 0 выключает правило
+	MinSuccessRate float64       `yaml:"min_success_rate"`
+	Window         time.Duration `yaml:"window"`
+	// MinInvoices с какого числа закрытых Invoice в окне проверяется MinSuccessRate
+	MinInvoices int           `yaml:"min_invoices"`
+	Duration    time.Duration `yaml:"duration"`
+	// WebhookURL адрес, на который отправляются уведомления команд; пустой адрес оставляет их в outbox
+	WebhookURL     string        `yaml:"webhook_url"`
+	WebhookTimeout time.Duration `yaml:"webhook_timeout"`
+	NotifyInterval time.Duration `yaml:"notify_interval"`
+}
+
+// ScoringConfig параметры оценки кандидатов при выборе реквизита
+type ScoringConfig struct {
+	PriorSuccessRate float64       `yaml:"prior_success_rate"`
+	PriorWeight      float64       `yaml:"prior_weight"`
+	RateWeight       float64       `yaml:"rate_weight"`
+	RecencyWeight    float64       `yaml:"recency_weight"`
+	LoadWeight       float64       `yaml:"load_weight"`
+	RecencyHorizon   time.Duration `yaml:"recency_horizon"`
+	Exploration      float64       `yaml:"exploration"`
+	MinObservations  int           `yaml:"min_observations"`
+}
+
+// PayerConfig проверки плательщика, переданного мерчантом в userId
+type PayerConfig struct {
+	// Enabled включает список блокировки мерчанта и лимиты частоты; выбор реквизитов учитывает
+	// плательщика независимо от флага
+	Enabled bool `yaml:"enabled"`
+	// MaxInvoices сколько Invoice плательщик может создать за Window; 0 выключает лимит
+	MaxInvoices int           `yaml:"max_invoices"`
+	Window      time.Duration `yaml:"window"`
+	// MaxActiveInvoices сколько неоплаченных Invoice у плательщика может быть одновременно; 0 выключает лимит
+	MaxActiveInvoices int `yaml:"max_active_invoices"`
+}
 
-	port := getEnv("HTTP_PORT", "8080")
+// CacheConfig кеш мерчантов, ускоренных команд и курса: LRU процесса перед Redis
+type CacheConfig struct {
+	LocalSize int           `yaml:"local_size"`
+	TTL       time.Duration `yaml:"ttl"`
+	// StaleTTL сколько после TTL значение отдается, если Postgres недоступен
+	StaleTTL time.Duration `yaml:"stale_ttl"`
+	// StaleRetry через сколько после отдачи устаревшего значения Postgres опрашивается снова
+	StaleRetry time.Duration `yaml:"stale_retry"`
+	// EarlyRefresh доля TTL в конце срока, в которой запись обновляется в фоне
+	EarlyRefresh float64       `yaml:"early_refresh"`
+	LoadTimeout  time.Duration `yaml:"load_timeout"`
+}
+
+// HealthConfig проверки /readyz
+type HealthConfig struct {
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+	// MaxExchangeRateAge возраст курса, после которого сервис не готов; 0 выключает проверку
+	MaxExchangeRateAge time.Duration `yaml:"max_exchange_rate_age"`
+}
+
+// TracingConfig экспорт спанов OpenTelemetry
+type TracingConfig struct {
+	// Exporter none, stdout, file или otlp
+	Exporter string `yaml:"exporter"`
+	// File куда пишет экспортер file
+	File        string  `yaml:"file"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// RateLimitConfig лимиты запросов создания Invoice одного мерчанта
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Default лимиты мерчантов, которых нет в Merchants
+	Default MerchantRateLimit `yaml:"default"`
+	// Merchants лимиты отдельных мерчантов по merchantID
+	Merchants map[string]MerchantRateLimit `yaml:"merchants"`
+	// RedisTimeout сколько запрос ждет Redis; после этого запрос пропускается без проверки частоты
+	RedisTimeout time.Duration `yaml:"redis_timeout"`
+}
+
+type MerchantRateLimit struct {
+	// Rate запросов в секунду в среднем; 0 выключает лимит частоты
+	Rate float64 `yaml:"rate"`
+	// Burst сколько запросов может прийти разом после паузы
+	Burst int `yaml:"burst"`
+	// MaxConcurrent одновременных запросов на одной реплике; 0 выключает лимит
+	MaxConcurrent int `yaml:"max_concurrent"`
+}
 
-	shutdownTimeout := getEnvAsInt("SHUTDOWN_TIMEOUT", 20)
+// ValidationError все ошибки конфигурации сразу, чтобы их не приходилось исправлять по одной
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
 
+// Default значения, которые действуют без файла и переменных окружения
+func Default() *Config {
 	return &Config{
 		HTTP: HTTPConfig{
-			Port:            port,
-			ShutdownTimeout: time.Duration(shutdownTimeout) * time.Second,
+			Port:            "8080",
+			ShutdownTimeout: 20 * time.Second,
+			DrainDelay:      5 * time.Second,
+			MetricsPort:     "9090",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     2 * time.Minute,
+			BodyLimit:       1024 * 1024,
 		},
 		DB: DBConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			DBName:   getEnv("DB_NAME", "mateo_db"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+			Host:           "localhost",
+			Port:           "5432",
+			User:           "postgres",
+			Password:       "postgres",
+			DBName:         "mateo_db",
+			SSLMode:        "disable",
+			ConnectTimeout: 5 * time.Second,
+			Pool: PoolConfig{
+				MaxConns:          50,
+				MinConns:          10,
+				MaxConnLifetime:   time.Hour,
+				MaxConnIdleTime:   30 * time.Minute,
+				HealthCheckPeriod: time.Minute,
+			},
 		},
 		Redis: RedisConfig{
-			Addr:     getEnv("REDIS_ADDR", "localhost:6379"),
-			Password: getEnv("REDIS_PASSWORD", ""),
-			DB:       getEnvAsInt("REDIS_DB", 0),
+			Addr:            "localhost:6379",
+			BreakerFailures: 5,
+			BreakerCooldown: 30 * time.Second,
+		},
+		Invoice: InvoiceConfig{
+			ExpirationInterval: 30 * time.Second,
+			DefaultTTL:         15 * time.Minute,
+			FlexibleStep:       5,
+			FlexibleRange:      20,
+		},
+		Business: BusinessConfig{
+			Timezone:             "UTC",
+			MaxRollingWindow:     24 * time.Hour,
+			CounterPruneInterval: time.Hour,
+		},
+		Sandbox: SandboxConfig{
+			Enabled:          true,
+			CallbackTimeout:  10 * time.Second,
+			InvoiceRetention: 24 * time.Hour,
+		},
+		Admin: AdminConfig{
+			Tokens: map[string]string{},
+		},
+		Quarantine: QuarantineConfig{
+			Enabled:            true,
+			ConsecutiveExpired: 3,
+			MinSuccessRate:     0.3,
+			Window:             time.Hour,
+			MinInvoices:        10,
+			Duration:           30 * time.Minute,
+			WebhookTimeout:     10 * time.Second,
+			NotifyInterval:     10 * time.Second,
+		},
+		Scoring: ScoringConfig{
+			PriorSuccessRate: 0.5,
+			PriorWeight:      10,
+			RateWeight:       0.6,
+			RecencyWeight:    0.25,
+			LoadWeight:       0.15,
+			RecencyHorizon:   30 * time.Minute,
+			Exploration:      0.1,
+			MinObservations:  5,
 		},
-	}, nil
+		Payer: PayerConfig{
+			Enabled:           true,
+			MaxInvoices:       10,
+			Window:            time.Hour,
+			MaxActiveInvoices: 3,
+		},
+		Cache: CacheConfig{
+			LocalSize:    1000,
+			TTL:          5 * time.Minute,
+			StaleTTL:     time.Hour,
+			StaleRetry:   5 * time.Second,
+			EarlyRefresh: 0.2,
+			LoadTimeout:  5 * time.Second,
+		},
+		Health: HealthConfig{
+			CheckTimeout: 2 * time.Second,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			File:        "traces.jsonl",
+			SampleRatio: 1,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Default: MerchantRateLimit{
+				Rate:          20,
+				Burst:         40,
+				MaxConcurrent: 10,
+			},
+			Merchants:    map[string]MerchantRateLimit{},
+			RedisTimeout: 100 * time.Millisecond,
+		},
+	}
+}
+
+// Load собирает конфигурацию: значения по умолчанию, затем YAML-файл из CONFIG_FILE,
+// затем переменные окружения. Ошибки разбора и проверки возвращаются вместе в ValidationError.
+func Load() (*Config, error) {
+	// Load .env file if it exists
+	_ = godotenv.Load()
+
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	env := &envReader{}
+	cfg.applyEnv(env)
+
+	problems := append(env.problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
 }
 
-// getEnv gets an environment variable or returns a default value
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// loadFile накладывает файл на текущие значения; неизвестные ключи считаются ошибкой
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "open config file")
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return errors.Wrapf(err, "parse config file %s", path)
 	}
-	return defaultValue
+	return nil
 }
 
-// getEnvAsInt gets an environment variable as integer or returns a default value
-func getEnvAsInt(key string, defaultValue int) int {
-	valueStr := getEnv(key, "")
-	if value, err := strconv.Atoi(valueStr); err == nil {
-		return value
+// applyEnv накладывает переменные окружения. Длительности задаются целым числом
+// в единицах, указанных в имени переменной.
+func (c *Config) applyEnv(env *envReader) {
+	env.string("HTTP_PORT", &c.HTTP.Port)
+	env.duration("SHUTDOWN_TIMEOUT", time.Second, &c.HTTP.ShutdownTimeout)
+	env.duration("SHUTDOWN_DRAIN_SECONDS", time.Second, &c.HTTP.DrainDelay)
+	env.string("METRICS_PORT", &c.HTTP.MetricsPort)
+	env.duration("HTTP_READ_TIMEOUT_SECONDS", time.Second, &c.HTTP.ReadTimeout)
+	env.duration("HTTP_WRITE_TIMEOUT_SECONDS", time.Second, &c.HTTP.WriteTimeout)
+	env.duration("HTTP_IDLE_TIMEOUT_SECONDS", time.Second, &c.HTTP.IdleTimeout)
+	env.int("HTTP_BODY_LIMIT_BYTES", &c.HTTP.BodyLimit)
+
+	env.string("DB_HOST", &c.DB.Host)
+	env.string("DB_PORT", &c.DB.Port)
+	env.string("DB_USER", &c.DB.User)
+	env.string("DB_PASSWORD", &c.DB.Password)
+	env.string("DB_NAME", &c.DB.DBName)
+	env.string("DB_SSLMODE", &c.DB.SSLMode)
+	env.duration("DB_CONNECT_TIMEOUT_SECONDS", time.Second, &c.DB.ConnectTimeout)
+	env.int32("DB_POOL_MAX_CONNS", &c.DB.Pool.MaxConns)
+	env.int32("DB_POOL_MIN_CONNS", &c.DB.Pool.MinConns)
+	env.duration("DB_POOL_MAX_CONN_LIFETIME_MINUTES", time.Minute, &c.DB.Pool.MaxConnLifetime)
+	env.duration("DB_POOL_MAX_CONN_IDLE_MINUTES", time.Minute, &c.DB.Pool.MaxConnIdleTime)
+	env.duration("DB_POOL_HEALTH_CHECK_SECONDS", time.Second, &c.DB.Pool.HealthCheckPeriod)
+
+	env.string("REDIS_ADDR", &c.Redis.Addr)
+	env.string("REDIS_PASSWORD", &c.Redis.Password)
+	env.int("REDIS_DB", &c.Redis.DB)
+	env.bool("REDIS_OPTIONAL", &c.Redis.Optional)
+	env.int("REDIS_BREAKER_FAILURES", &c.Redis.BreakerFailures)
+	env.duration("REDIS_BREAKER_COOLDOWN_SECONDS", time.Second, &c.Redis.BreakerCooldown)
+
+	env.duration("INVOICE_EXPIRATION_INTERVAL", time.Second, &c.Invoice.ExpirationInterval)
+	env.duration("INVOICE_DEFAULT_TTL_MINUTES", time.Minute, &c.Invoice.DefaultTTL)
+	env.int("INVOICE_FLEXIBLE_STEP", &c.Invoice.FlexibleStep)
+	env.int("INVOICE_FLEXIBLE_RANGE", &c.Invoice.FlexibleRange)
+
+	env.string("BUSINESS_TIMEZONE", &c.Business.Timezone)
+	env.duration("LIMIT_MAX_ROLLING_WINDOW_HOURS", time.Hour, &c.Business.MaxRollingWindow)
+	env.duration("COUNTER_PRUNE_INTERVAL_MINUTES", time.Minute, &c.Business.CounterPruneInterval)
+
+	env.bool("SANDBOX_ENABLED", &c.Sandbox.Enabled)
+	env.duration("SANDBOX_CALLBACK_TIMEOUT", time.Second, &c.Sandbox.CallbackTimeout)
+	env.duration("SANDBOX_INVOICE_RETENTION_HOURS", time.Hour, &c.Sandbox.InvoiceRetention)
+
+	if value, ok := os.LookupEnv("ADMIN_TOKENS"); ok {
+		tokens, err := parseAdminTokens(value)
+		if err != nil {
+			env.problems = append(env.problems, err.Error())
+		} else {
+			c.Admin.Tokens = tokens
+		}
+	}
+
+	env.bool("QUARANTINE_ENABLED", &c.Quarantine.Enabled)
+	env.int("QUARANTINE_CONSECUTIVE_EXPIRED", &c.Quarantine.ConsecutiveExpired)
+	env.float("QUARANTINE_MIN_SUCCESS_RATE", &c.Quarantine.MinSuccessRate)
+	env.duration("QUARANTINE_WINDOW_MINUTES", time.Minute, &c.Quarantine.Window)
+	env.int("QUARANTINE_MIN_INVOICES", &c.Quarantine.MinInvoices)
+	env.duration("QUARANTINE_DURATION_MINUTES", time.Minute, &c.Quarantine.Duration)
+	env.string("TEAM_NOTIFICATION_WEBHOOK_URL", &c.Quarantine.WebhookURL)
+	env.duration("TEAM_NOTIFICATION_WEBHOOK_TIMEOUT", time.Second, &c.Quarantine.WebhookTimeout)
+	env.duration("TEAM_NOTIFICATION_INTERVAL", time.Second, &c.Quarantine.NotifyInterval)
+
+	env.float("SCORING_PRIOR_SUCCESS_RATE", &c.Scoring.PriorSuccessRate)
+	env.float("SCORING_PRIOR_WEIGHT", &c.Scoring.PriorWeight)
+	env.float("SCORING_RATE_WEIGHT", &c.Scoring.RateWeight)
+	env.float("SCORING_RECENCY_WEIGHT", &c.Scoring.RecencyWeight)
+	env.float("SCORING_LOAD_WEIGHT", &c.Scoring.LoadWeight)
+	env.duration("SCORING_RECENCY_MINUTES", time.Minute, &c.Scoring.RecencyHorizon)
+	env.float("SCORING_EXPLORATION", &c.Scoring.Exploration)
+	env.int("SCORING_MIN_OBSERVATIONS", &c.Scoring.MinObservations)
+
+	env.bool("PAYER_CHECKS_ENABLED", &c.Payer.Enabled)
+	env.int("PAYER_MAX_INVOICES", &c.Payer.MaxInvoices)
+	env.duration("PAYER_VELOCITY_WINDOW_MINUTES", time.Minute, &c.Payer.Window)
+	env.int("PAYER_MAX_ACTIVE_INVOICES", &c.Payer.MaxActiveInvoices)
+
+	env.int("CACHE_LOCAL_SIZE", &c.Cache.LocalSize)
+	env.duration("CACHE_TTL_SECONDS", time.Second, &c.Cache.TTL)
+	env.duration("CACHE_STALE_SECONDS", time.Second, &c.Cache.StaleTTL)
+	env.duration("CACHE_STALE_RETRY_SECONDS", time.Second, &c.Cache.StaleRetry)
+	env.float("CACHE_EARLY_REFRESH", &c.Cache.EarlyRefresh)
+	env.duration("CACHE_LOAD_TIMEOUT_SECONDS", time.Second, &c.Cache.LoadTimeout)
+
+	env.duration("HEALTH_CHECK_TIMEOUT_SECONDS", time.Second, &c.Health.CheckTimeout)
+	env.duration("HEALTH_EXCHANGE_RATE_MAX_AGE_MINUTES", time.Minute, &c.Health.MaxExchangeRateAge)
+
+	env.string("TRACING_EXPORTER", &c.Tracing.Exporter)
+	env.string("TRACING_FILE", &c.Tracing.File)
+	env.float("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+
+	env.bool("RATE_LIMIT_ENABLED", &c.RateLimit.Enabled)
+	env.float("RATE_LIMIT_RPS", &c.RateLimit.Default.Rate)
+	env.int("RATE_LIMIT_BURST", &c.RateLimit.Default.Burst)
+	env.int("RATE_LIMIT_MAX_CONCURRENT", &c.RateLimit.Default.MaxConcurrent)
+	env.duration("RATE_LIMIT_REDIS_TIMEOUT_MS", time.Millisecond, &c.RateLimit.RedisTimeout)
+}
+
+// parseAdminTokens разбирает список вида "name:token,name2:token2"
+func parseAdminTokens(value string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, token, ok := strings.Cut(pair, ":")
+		if !ok || name == "" || token == "" {
+			return nil, errors.New("invalid ADMIN_TOKENS entry: expected name:token")
+		}
+		if _, exists := tokens[name]; exists {
+			return nil, errors.Errorf("duplicate ADMIN_TOKENS name %q", name)
+		}
+		tokens[name] = token
 	}
-	return defaultValue
+	return tokens, nil
 }
 
 // DSN returns the database connection string
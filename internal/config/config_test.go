@@ -0,0 +1,134 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mateo/internal/config"
+)
+
+// writeConfig записывает YAML во временный файл и указывает его в CONFIG_FILE
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	t.Setenv("CONFIG_FILE", path)
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "8080", cfg.HTTP.Port)
+	assert.Equal(t, 15*time.Minute, cfg.Invoice.DefaultTTL)
+	assert.Equal(t, time.UTC, cfg.Business.Location)
+}
+
+func TestLoadFileAndEnv(t *testing.T) {
+	writeConfig(t, `
+http:
+  port: "8081"
+  shutdown_timeout: 40s
+db:
+  host: db.internal
+  name: mateo_file
+  pool:
+    max_conns: 20
+invoice:
+  default_ttl: 20m
+  flexible_step: 10
+business:
+  timezone: Europe/Moscow
+rate_limit:
+  default:
+    rate: 5
+    burst: 10
+  merchants:
+    merchant-1:
+      rate: 50
+      burst: 100
+`)
+	// Переменные окружения перекрывают файл, незаданные в обоих ключи остаются по умолчанию
+	t.Setenv("HTTP_PORT", "9000")
+	t.Setenv("DB_NAME", "mateo_env")
+	t.Setenv("INVOICE_DEFAULT_TTL_MINUTES", "30")
+	t.Setenv("RATE_LIMIT_BURST", "15")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, "9000", cfg.HTTP.Port)
+	assert.Equal(t, 40*time.Second, cfg.HTTP.ShutdownTimeout)
+	assert.Equal(t, 5*time.Second, cfg.HTTP.DrainDelay)
+
+	assert.Equal(t, "db.internal", cfg.DB.Host)
+	assert.Equal(t, "mateo_env", cfg.DB.DBName)
+	assert.Equal(t, "5432", cfg.DB.Port)
+	assert.Equal(t, int32(20), cfg.DB.Pool.MaxConns)
+	assert.Equal(t, int32(10), cfg.DB.Pool.MinConns)
+
+	assert.Equal(t, 30*time.Minute, cfg.Invoice.DefaultTTL)
+	assert.Equal(t, 10, cfg.Invoice.FlexibleStep)
+	assert.Equal(t, 20, cfg.Invoice.FlexibleRange)
+
+	assert.Equal(t, "Europe/Moscow", cfg.Business.Location.String())
+
+	assert.Equal(t, config.MerchantRateLimit{Rate: 5, Burst: 15, MaxConcurrent: 10}, cfg.RateLimit.Default)
+	assert.Equal(t, map[string]config.MerchantRateLimit{"merchant-1": {Rate: 50, Burst: 100}}, cfg.RateLimit.Merchants)
+}
+
+func TestLoadAggregatesProblems(t *testing.T) {
+	writeConfig(t, `
+http:
+  port: "8080"
+  metrics_port: "8080"
+db:
+  sslmode: sometimes
+cache:
+  early_refresh: 1
+rate_limit:
+  merchants:
+    merchant-1:
+      rate: 5
+`)
+	t.Setenv("DB_POOL_MAX_CONNS", "many")
+	t.Setenv("INVOICE_DEFAULT_TTL_MINUTES", "15m")
+	t.Setenv("BUSINESS_TIMEZONE", "Mars/Olympus")
+	t.Setenv("QUARANTINE_MIN_SUCCESS_RATE", "1.5")
+
+	_, err := config.Load()
+	var validationErr *config.ValidationError
+	require.True(t, errors.As(err, &validationErr), "got error %v", err)
+
+	// Ошибки разбора окружения идут первыми, затем проверки значений в порядке полей
+	assert.Equal(t, []string{
+		`DB_POOL_MAX_CONNS="many": expected integer`,
+		`INVOICE_DEFAULT_TTL_MINUTES="15m": expected integer number of minutes`,
+		"http.metrics_port must differ from http.port",
+		`db.sslmode "sometimes" is not a libpq sslmode`,
+		`business.timezone "Mars/Olympus": unknown time zone Mars/Olympus`,
+		"quarantine.min_success_rate must be in [0, 1]",
+		"cache.early_refresh must be in [0, 1)",
+		"rate_limit.merchants.merchant-1.burst must be at least 1 when rate is set",
+	}, validationErr.Problems)
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, `
+http:
+  prot: "8081"
+`)
+
+	_, err := config.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), path)
+	assert.Contains(t, err.Error(), "field prot not found")
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// envReader накладывает переменные окружения на поля конфигурации. Неразбираемые значения
+// не заменяются значением по умолчанию молча, а копятся в problems.
+type envReader struct {
+	problems []string
+}
+
+func (r *envReader) lookup(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+func (r *envReader) invalid(key string, value string, expected string) {
+	r.problems = append(r.problems, fmt.Sprintf("%s=%q: expected %s", key, value, expected))
+}
+
+func (r *envReader) string(key string, target *string) {
+	if value, ok := r.lookup(key); ok {
+		*target = value
+	}
+}
+
+func (r *envReader) int(key string, target *int) {
+	value, ok := r.lookup(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		r.invalid(key, value, "integer")
+		return
+	}
+	*target = parsed
+}
+
+func (r *envReader) int32(key string, target *int32) {
+	value, ok := r.lookup(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		r.invalid(key, value, "integer")
+		return
+	}
+	*target = int32(parsed)
+}
+
+func (r *envReader) bool(key string, target *bool) {
+	value, ok := r.lookup(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		r.invalid(key, value, "true or false")
+		return
+	}
+	*target = parsed
+}
+
+func (r *envReader) float(key string, target *float64) {
+	value, ok := r.lookup(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		r.invalid(key, value, "number")
+		return
+	}
+	*target = parsed
+}
+
+// duration читает целое число единиц unit, как в именах переменных *_SECONDS и *_MINUTES
+func (r *envReader) duration(key string, unit time.Duration, target *time.Duration) {
+	value, ok := r.lookup(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		r.invalid(key, value, "integer number of "+unitName(unit))
+		return
+	}
+	*target = time.Duration(parsed) * unit
+}
+
+func unitName(unit time.Duration) string {
+	switch unit {
+	case time.Hour:
+		return "hours"
+	case time.Minute:
+		return "minutes"
+	case time.Millisecond:
+		return "milliseconds"
+	default:
+		return "seconds"
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Reload заново собирает конфигурацию и переносит в копию current только настройки,
+// которые можно менять на ходу. Возвращает ее и пути измененных настроек, требующих
+// перезапуска: их новые значения не применяются. Ошибка означает, что новая
+// конфигурация не прошла проверку и current остается в силе.
+func Reload(current *Config) (*Config, []string, error) {
+	next, err := Load()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fixed := *next
+	copyReloadable(&fixed, current)
+	rejected := diff(reflect.ValueOf(*current), reflect.ValueOf(fixed), "")
+
+	merged := *current
+	copyReloadable(&merged, next)
+	return &merged, rejected, nil
+}
+
+// copyReloadable переносит из src настройки, которые сервисы умеют менять на ходу
+func copyReloadable(dst *Config, src *Config) {
+	dst.HTTP.ShutdownTimeout = src.HTTP.ShutdownTimeout
+	dst.HTTP.DrainDelay = src.HTTP.DrainDelay
+
+	dst.Invoice.DefaultTTL = src.Invoice.DefaultTTL
+	dst.Invoice.FlexibleStep = src.Invoice.FlexibleStep
+	dst.Invoice.FlexibleRange = src.Invoice.FlexibleRange
+
+	// Адрес и таймаут webhook задаются клиенту уведомлений при старте
+	webhookURL, webhookTimeout, notifyInterval :=
+		dst.Quarantine.WebhookURL, dst.Quarantine.WebhookTimeout, dst.Quarantine.NotifyInterval
+	dst.Quarantine = src.Quarantine
+	dst.Quarantine.WebhookURL = webhookURL
+	dst.Quarantine.WebhookTimeout = webhookTimeout
+	dst.Quarantine.NotifyInterval = notifyInterval
+
+	dst.Scoring = src.Scoring
+	dst.Payer = src.Payer
+
+	// Размер LRU задается при создании кеша
+	localSize := dst.Cache.LocalSize
+	dst.Cache = src.Cache
+	dst.Cache.LocalSize = localSize
+
+	dst.Health = src.Health
+
+	redisTimeout := dst.RateLimit.RedisTimeout
+	dst.RateLimit = src.RateLimit
+	dst.RateLimit.RedisTimeout = redisTimeout
+}
+
+// diff возвращает yaml-пути полей, различающихся в a и b. Значения не выводятся:
+// среди них есть пароли и токены.
+func diff(a reflect.Value, b reflect.Value, path string) []string {
+	if a.Kind() != reflect.Struct {
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return nil
+		}
+		return []string{path}
+	}
+
+	var changed []string
+	for i := 0; i < a.NumField(); i++ {
+		name, _, _ := strings.Cut(a.Type().Field(i).Tag.Get("yaml"), ",")
+		if name == "-" || name == "" {
+			continue
+		}
+		if path != "" {
+			name = path + "." + name
+		}
+		changed = append(changed, diff(a.Field(i), b.Field(i), name)...)
+	}
+	return changed
+}
+
+// Watch вызывает onChange, когда у файла path меняется время изменения или размер.
+// Файл опрашивается раз в interval: так замечается и подмена файла целиком,
+// как при обновлении ConfigMap в Kubernetes. Возвращается при отмене ctx.
+func Watch(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	last, _ := os.Stat(path)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil {
+				// Файл может временно отсутствовать, пока его заменяют
+				continue
+			}
+			if last == nil || !info.ModTime().Equal(last.ModTime()) || info.Size() != last.Size() {
+				last = info
+				onChange()
+			}
+		}
+	}
+}
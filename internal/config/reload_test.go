@@ -0,0 +1,136 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mateo/internal/config"
+)
+
+const reloadBase = `
+http:
+  port: "8080"
+db:
+  host: db-1.internal
+  password: first
+invoice:
+  default_ttl: 15m
+quarantine:
+  consecutive_expired: 3
+  webhook_url: https://hooks.example.com/first
+payer:
+  max_invoices: 10
+cache:
+  local_size: 1000
+  ttl: 5m
+`
+
+func TestReload(t *testing.T) {
+	path := writeConfig(t, reloadBase)
+	current, err := config.Load()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+http:
+  port: "9000"
+db:
+  host: db-2.internal
+  password: second
+invoice:
+  default_ttl: 20m
+quarantine:
+  consecutive_expired: 5
+  webhook_url: https://hooks.example.com/second
+payer:
+  max_invoices: 20
+cache:
+  local_size: 5000
+  ttl: 1m
+`), 0o600))
+	t.Setenv("RATE_LIMIT_RPS", "7")
+
+	merged, rejected, err := config.Reload(current)
+	require.NoError(t, err)
+
+	// Изменения, требующие перезапуска, перечисляются по путям без значений
+	assert.Equal(t, []string{
+		"http.port",
+		"db.host",
+		"db.password",
+		"quarantine.webhook_url",
+		"cache.local_size",
+	}, rejected)
+
+	assert.Equal(t, "8080", merged.HTTP.Port)
+	assert.Equal(t, "db-1.internal", merged.DB.Host)
+	assert.Equal(t, "first", merged.DB.Password)
+	assert.Equal(t, "https://hooks.example.com/first", merged.Quarantine.WebhookURL)
+	assert.Equal(t, 1000, merged.Cache.LocalSize)
+
+	assert.Equal(t, 20*time.Minute, merged.Invoice.DefaultTTL)
+	assert.Equal(t, 5, merged.Quarantine.ConsecutiveExpired)
+	assert.Equal(t, 20, merged.Payer.MaxInvoices)
+	assert.Equal(t, time.Minute, merged.Cache.TTL)
+	assert.Equal(t, float64(7), merged.RateLimit.Default.Rate)
+
+	// Текущая конфигурация не меняется
+	assert.Equal(t, 15*time.Minute, current.Invoice.DefaultTTL)
+	assert.Equal(t, 1000, current.Cache.LocalSize)
+}
+
+func TestReloadWithoutChanges(t *testing.T) {
+	writeConfig(t, reloadBase)
+	current, err := config.Load()
+	require.NoError(t, err)
+
+	merged, rejected, err := config.Reload(current)
+	require.NoError(t, err)
+	assert.Empty(t, rejected)
+	assert.Equal(t, current, merged)
+}
+
+func TestReloadInvalid(t *testing.T) {
+	path := writeConfig(t, reloadBase)
+	current, err := config.Load()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+invoice:
+  default_ttl: 0s
+`), 0o600))
+
+	merged, _, err := config.Reload(current)
+	var validationErr *config.ValidationError
+	require.True(t, errors.As(err, &validationErr), "got error %v", err)
+	assert.Equal(t, []string{"invoice.default_ttl must be positive"}, validationErr.Problems)
+	assert.Nil(t, merged)
+	assert.Equal(t, 15*time.Minute, current.Invoice.DefaultTTL)
+}
+
+func TestWatch(t *testing.T) {
+	path := writeConfig(t, reloadBase)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go config.Watch(ctx, path, 5*time.Millisecond, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, os.WriteFile(path, []byte(reloadBase+"\nscoring:\n  exploration: 0.2\n"), 0o600))
+
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("config change was not noticed")
+	}
+}
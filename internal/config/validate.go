@@ -0,0 +1,205 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// validate проверяет значения и вычисляет производные поля. Возвращает все найденные проблемы.
+func (c *Config) validate() []string {
+	var v validator
+
+	v.port("http.port", c.HTTP.Port, false)
+	v.port("http.metrics_port", c.HTTP.MetricsPort, true)
+	if c.HTTP.MetricsPort != "" && c.HTTP.MetricsPort == c.HTTP.Port {
+		v.add("http.metrics_port must differ from http.port")
+	}
+	v.positive("http.shutdown_timeout", c.HTTP.ShutdownTimeout)
+	v.notNegative("http.drain_delay", c.HTTP.DrainDelay)
+	v.notNegative("http.read_timeout", c.HTTP.ReadTimeout)
+	v.notNegative("http.write_timeout", c.HTTP.WriteTimeout)
+	v.notNegative("http.idle_timeout", c.HTTP.IdleTimeout)
+	if c.HTTP.BodyLimit <= 0 {
+		v.add("http.body_limit must be positive")
+	}
+
+	if c.DB.Host == "" {
+		v.add("db.host is required")
+	}
+	v.port("db.port", c.DB.Port, false)
+	if c.DB.DBName == "" {
+		v.add("db.name is required")
+	}
+	switch c.DB.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		v.add(fmt.Sprintf("db.sslmode %q is not a libpq sslmode", c.DB.SSLMode))
+	}
+	v.positive("db.connect_timeout", c.DB.ConnectTimeout)
+	if c.DB.Pool.MaxConns <= 0 {
+		v.add("db.pool.max_conns must be positive")
+	}
+	if c.DB.Pool.MinConns < 0 || c.DB.Pool.MinConns > c.DB.Pool.MaxConns {
+		v.add("db.pool.min_conns must be between 0 and db.pool.max_conns")
+	}
+	v.positive("db.pool.max_conn_lifetime", c.DB.Pool.MaxConnLifetime)
+	v.positive("db.pool.max_conn_idle_time", c.DB.Pool.MaxConnIdleTime)
+	v.positive("db.pool.health_check_period", c.DB.Pool.HealthCheckPeriod)
+
+	if c.Redis.Addr == "" {
+		v.add("redis.addr is required")
+	}
+	if c.Redis.DB < 0 {
+		v.add("redis.db must not be negative")
+	}
+	if c.Redis.BreakerFailures <= 0 {
+		v.add("redis.breaker_failures must be positive")
+	}
+	v.positive("redis.breaker_cooldown", c.Redis.BreakerCooldown)
+
+	v.positive("invoice.expiration_interval", c.Invoice.ExpirationInterval)
+	v.positive("invoice.default_ttl", c.Invoice.DefaultTTL)
+	if c.Invoice.FlexibleStep <= 0 {
+		v.add("invoice.flexible_step must be positive")
+	}
+	if c.Invoice.FlexibleRange < 0 {
+		v.add("invoice.flexible_range must not be negative")
+	}
+
+	location, err := time.LoadLocation(c.Business.Timezone)
+	if err != nil {
+		v.add(fmt.Sprintf("business.timezone %q: %v", c.Business.Timezone, err))
+	}
+	c.Business.Location = location
+	v.positive("business.max_rolling_window", c.Business.MaxRollingWindow)
+	v.positive("business.counter_prune_interval", c.Business.CounterPruneInterval)
+
+	v.positive("sandbox.callback_timeout", c.Sandbox.CallbackTimeout)
+	v.positive("sandbox.invoice_retention", c.Sandbox.InvoiceRetention)
+
+	for name, token := range c.Admin.Tokens {
+		if name == "" || token == "" {
+			v.add("admin.tokens must have non-empty names and tokens")
+			break
+		}
+	}
+
+	if c.Quarantine.ConsecutiveExpired < 0 {
+		v.add("quarantine.consecutive_expired must not be negative")
+	}
+	v.fraction("quarantine.min_success_rate", c.Quarantine.MinSuccessRate)
+	v.positive("quarantine.window", c.Quarantine.Window)
+	if c.Quarantine.MinInvoices < 0 {
+		v.add("quarantine.min_invoices must not be negative")
+	}
+	v.positive("quarantine.duration", c.Quarantine.Duration)
+	if c.Quarantine.WebhookURL != "" {
+		if u, err := url.Parse(c.Quarantine.WebhookURL); err != nil || u.Scheme == "" || u.Host == "" {
+			v.add(fmt.Sprintf("quarantine.webhook_url %q is not an absolute URL", c.Quarantine.WebhookURL))
+		}
+	}
+	v.positive("quarantine.webhook_timeout", c.Quarantine.WebhookTimeout)
+	v.positive("quarantine.notify_interval", c.Quarantine.NotifyInterval)
+
+	v.fraction("scoring.prior_success_rate", c.Scoring.PriorSuccessRate)
+	if c.Scoring.PriorWeight < 0 || c.Scoring.RateWeight < 0 || c.Scoring.RecencyWeight < 0 || c.Scoring.LoadWeight < 0 {
+		v.add("scoring weights must not be negative")
+	}
+	v.positive("scoring.recency_horizon", c.Scoring.RecencyHorizon)
+	v.fraction("scoring.exploration", c.Scoring.Exploration)
+	if c.Scoring.MinObservations < 0 {
+		v.add("scoring.min_observations must not be negative")
+	}
+
+	if c.Payer.MaxInvoices < 0 {
+		v.add("payer.max_invoices must not be negative")
+	}
+	v.positive("payer.window", c.Payer.Window)
+	if c.Payer.MaxActiveInvoices < 0 {
+		v.add("payer.max_active_invoices must not be negative")
+	}
+
+	if c.Cache.LocalSize <= 0 {
+		v.add("cache.local_size must be positive")
+	}
+	v.positive("cache.ttl", c.Cache.TTL)
+	v.notNegative("cache.stale_ttl", c.Cache.StaleTTL)
+	v.positive("cache.stale_retry", c.Cache.StaleRetry)
+	if c.Cache.EarlyRefresh < 0 || c.Cache.EarlyRefresh >= 1 {
+		v.add("cache.early_refresh must be in [0, 1)")
+	}
+	v.positive("cache.load_timeout", c.Cache.LoadTimeout)
+
+	v.positive("health.check_timeout", c.Health.CheckTimeout)
+	v.notNegative("health.max_exchange_rate_age", c.Health.MaxExchangeRateAge)
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	case "file":
+		if c.Tracing.File == "" {
+			v.add("tracing.file is required for the file exporter")
+		}
+	default:
+		v.add(fmt.Sprintf("tracing.exporter %q must be none, stdout, file or otlp", c.Tracing.Exporter))
+	}
+	v.fraction("tracing.sample_ratio", c.Tracing.SampleRatio)
+
+	v.rateLimit("rate_limit.default", c.RateLimit.Default)
+	for _, merchantID := range slices.Sorted(maps.Keys(c.RateLimit.Merchants)) {
+		v.rateLimit("rate_limit.merchants."+merchantID, c.RateLimit.Merchants[merchantID])
+	}
+	v.positive("rate_limit.redis_timeout", c.RateLimit.RedisTimeout)
+
+	return v.problems
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) add(problem string) {
+	v.problems = append(v.problems, problem)
+}
+
+func (v *validator) positive(name string, value time.Duration) {
+	if value <= 0 {
+		v.add(name + " must be positive")
+	}
+}
+
+func (v *validator) notNegative(name string, value time.Duration) {
+	if value < 0 {
+		v.add(name + " must not be negative")
+	}
+}
+
+func (v *validator) fraction(name string, value float64) {
+	if value < 0 || value > 1 {
+		v.add(name + " must be in [0, 1]")
+	}
+}
+
+func (v *validator) rateLimit(name string, limit MerchantRateLimit) {
+	if limit.Rate < 0 {
+		v.add(name + ".rate must not be negative")
+	}
+	if limit.Rate > 0 && limit.Burst < 1 {
+		v.add(name + ".burst must be at least 1 when rate is set")
+	}
+	if limit.MaxConcurrent < 0 {
+		v.add(name + ".max_concurrent must not be negative")
+	}
+}
+
+func (v *validator) port(name string, value string, optional bool) {
+	if value == "" && optional {
+		return
+	}
+	if port, err := strconv.Atoi(value); err != nil || port <= 0 || port > 65535 {
+		v.add(fmt.Sprintf("%s %q is not a port number", name, value))
+	}
+}
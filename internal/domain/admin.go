@@ -0,0 +1,188 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Операции админки. actor — имя токена администратора, от которого пишется журнал изменений.
+
+func (a *App) ListMerchants(ctx context.Context) ([]*Merchant, error) {
+	if a.admin == nil {
+		return nil, ErrorAdminDisabled
+	}
+	merchants, err := a.admin.ListMerchants(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "list merchants")
+	}
+	return merchants, nil
+}
+
+func (a *App) GetMerchant(ctx context.Context, merchantID string) (*Merchant, error) {
+	if a.admin == nil {
+		return nil, ErrorAdminDisabled
+	}
+	merchant, err := a.admin.GetMerchant(ctx, merchantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get merchant")
+	}
+	return merchant, nil
+}
+
+func (a *App) CreateMerchant(ctx context.Context, actor string, merchant *Merchant) (*Merchant, error) {
+	if a.admin == nil {
+		return nil, ErrorAdminDisabled
+	}
+	created, err := a.admin.CreateMerchant(ctx, actor, merchant)
+	if err != nil {
+		return nil, errors.Wrap(err, "create merchant")
+	}
+	return created, nil
+}
+
+// UpdateMerchant меняет лимиты и признак песочницы мерчанта; поля patch со значением nil не меняются
+func (a *App) UpdateMerchant(ctx context.Context, actor string, merchantID string, patch MerchantPatch) (*Merchant, error) {
+	if a.admin == nil {
+		return nil, ErrorAdminDisabled
+	}
+	updated, err := a.admin.UpdateMerchant(ctx, actor, merchantID, patch)
+	if err != nil {
+		return nil, errors.Wrap(err, "update merchant")
+	}
+	return updated, nil
+}
+
+func (a *App) DeleteMerchant(ctx context.Context, actor string, merchantID string) error {
+	if a.admin == nil {
+		return ErrorAdminDisabled
+	}
+	if err := a.admin.DeleteMerchant(ctx, actor, merchantID); err != nil {
+		return errors.Wrap(err, "delete merchant")
+	}
+	return nil
+}
+
+func (a *App) ListMerchantTraderAccounts(ctx context.Context, merchantID string) ([]string, error) {
+	if a.admin == nil {
+		return nil, ErrorAdminDisabled
+	}
+	accountIDs, err := a.admin.ListMerchantTraderAccounts(ctx, merchantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "list merchant trader accounts")
+	}
+	return accountIDs, nil
+}
+
+func (a *App) LinkTraderAccount(ctx context.Context, actor string, merchantID string, traiderAccountID string) error {
+	if a.admin == nil {
+		return ErrorAdminDisabled
+	}
+	if err := a.admin.LinkTraderAccount(ctx, actor, merchantID, traiderAccountID); err != nil {
+		return errors.Wrap(err, "link trader account")
+	}
+	return nil
+}
+
+func (a *App) UnlinkTraderAccount(ctx context.Context, actor string, merchantID string, traiderAccountID string) error {
+	if a.admin == nil {
+		return ErrorAdminDisabled
+	}
+	if err := a.admin.UnlinkTraderAccount(ctx, actor, merchantID, traiderAccountID); err != nil {
+		return errors.Wrap(err, "unlink trader account")
+	}
+	return nil
+}
+
+func (a *App) ListAuditLog(ctx context.Context, entityType string, entityID string, limit int) ([]*AuditEntry, error) {
+	if a.admin == nil {
+		return nil, ErrorAdminDisabled
+	}
+	entries, err := a.admin.ListAuditLog(ctx, entityType, entityID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "list audit log")
+	}
+	return entries, nil
+}
+
+func (a *App) GetBlock(ctx context.Context, entity BlockEntity, entityID string) (*Block, error) {
+	if a.admin == nil {
+		return nil, ErrorAdminDisabled
+	}
+	block, err := a.admin.GetBlock(ctx, entity, entityID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get block")
+	}
+	return block, nil
+}
+
+// Block исключает сущность из выбора реквизитов до blockedUntil или до явного снятия
+func (a *App) Block(
+	ctx context.Context,
+	actor string,
+	entity BlockEntity,
+	entityID string,
+	reason string,
+	blockedUntil *time.Time,
+) (*Block, error) {
+	if a.admin == nil {
+		return nil, ErrorAdminDisabled
+	}
+	block, err := a.admin.Block(ctx, actor, entity, entityID, reason, blockedUntil)
+	if err != nil {
+		return nil, errors.Wrap(err, "block")
+	}
+	return block, nil
+}
+
+func (a *App) Unblock(ctx context.Context, actor string, entity BlockEntity, entityID string) (*Block, error) {
+	if a.admin == nil {
+		return nil, ErrorAdminDisabled
+	}
+	block, err := a.admin.Unblock(ctx, actor, entity, entityID)
+	if err != nil {
+		return nil, errors.Wrap(err, "unblock")
+	}
+	return block, nil
+}
+
+func (a *App) ListPayerBlocks(ctx context.Context, scope PayerBlockScope, scopeID string) ([]*PayerBlock, error) {
+	if a.admin == nil {
+		return nil, ErrorAdminDisabled
+	}
+	blocks, err := a.admin.ListPayerBlocks(ctx, scope, scopeID)
+	if err != nil {
+		return nil, errors.Wrap(err, "list payer blocks")
+	}
+	return blocks, nil
+}
+
+// BlockPayer добавляет плательщика в список блокировки мерчанта или команды
+func (a *App) BlockPayer(
+	ctx context.Context,
+	actor string,
+	scope PayerBlockScope,
+	scopeID string,
+	payerID string,
+	reason string,
+) (*PayerBlock, error) {
+	if a.admin == nil {
+		return nil, ErrorAdminDisabled
+	}
+	block, err := a.admin.BlockPayer(ctx, actor, scope, scopeID, payerID, reason)
+	if err != nil {
+		return nil, errors.Wrap(err, "block payer")
+	}
+	return block, nil
+}
+
+func (a *App) UnblockPayer(ctx context.Context, actor string, scope PayerBlockScope, scopeID string, payerID string) error {
+	if a.admin == nil {
+		return ErrorAdminDisabled
+	}
+	if err := a.admin.UnblockPayer(ctx, actor, scope, scopeID, payerID); err != nil {
+		return errors.Wrap(err, "unblock payer")
+	}
+	return nil
+}
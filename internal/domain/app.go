@@ -12,7 +12,7 @@ type MerchantService interface {
 		merchantID string,
 		amount decimal.Decimal,
 		requisiteType RequisiteType,
-	) error
+	) (*Merchant, error)
 }
 
 type RequisiteService interface {
@@ -21,9 +21,11 @@ type RequisiteService interface {
 		merchantID string,
 		amount decimal.Decimal,
 		requisiteType RequisiteType,
-		bankID string,
+		banks BankPreference,
+		payerID string,
 		flexibleRange int,
 		allowFlexibleAmount bool,
+		excludedAccounts []string,
 	) (*Requisite, error)
 }
 
@@ -36,17 +38,129 @@ type InvoiceService interface {
 		callbackURL string,
 		callbackKey string,
 		merchantID string,
+		payerID string,
 		timeExpires time.Duration,
 		requisite *Requisite,
 	) (*Invoice, error)
 }
 
+// PayerService проверяет, может ли плательщик создать Invoice у мерчанта
+type PayerService interface {
+	CheckPayer(ctx context.Context, merchantID string, payerID string) error
+}
+
+// SandboxService имитирует оплату Invoice песочницы и отправляет мерчанту callback
+type SandboxService interface {
+	Simulate(
+		ctx context.Context,
+		merchantID string,
+		invoiceID string,
+		outcome SandboxOutcome,
+		signed SignedBody,
+	) (*SandboxSimulation, error)
+}
+
+// AdminService изменяет мерчантов и их привязки к аккаунтам трейдеров. Каждое изменение
+// записывается в журнал от имени actor.
+type AdminService interface {
+	ListMerchants(ctx context.Context) ([]*Merchant, error)
+	GetMerchant(ctx context.Context, merchantID string) (*Merchant, error)
+	CreateMerchant(ctx context.Context, actor string, merchant *Merchant) (*Merchant, error)
+	UpdateMerchant(ctx context.Context, actor string, merchantID string, patch MerchantPatch) (*Merchant, error)
+	DeleteMerchant(ctx context.Context, actor string, merchantID string) error
+
+	ListMerchantTraderAccounts(ctx context.Context, merchantID string) ([]string, error)
+	LinkTraderAccount(ctx context.Context, actor string, merchantID string, traiderAccountID string) error
+	UnlinkTraderAccount(ctx context.Context, actor string, merchantID string, traiderAccountID string) error
+
+	ListAuditLog(ctx context.Context, entityType string, entityID string, limit int) ([]*AuditEntry, error)
+
+	GetBlock(ctx context.Context, entity BlockEntity, entityID string) (*Block, error)
+	// Block блокирует сущность с причиной; blockedUntil nil — до явного снятия
+	Block(
+		ctx context.Context,
+		actor string,
+		entity BlockEntity,
+		entityID string,
+		reason string,
+		blockedUntil *time.Time,
+	) (*Block, error)
+	Unblock(ctx context.Context, actor string, entity BlockEntity, entityID string) (*Block, error)
+
+	ListPayerBlocks(ctx context.Context, scope PayerBlockScope, scopeID string) ([]*PayerBlock, error)
+	BlockPayer(
+		ctx context.Context,
+		actor string,
+		scope PayerBlockScope,
+		scopeID string,
+		payerID string,
+		reason string,
+	) (*PayerBlock, error)
+	UnblockPayer(ctx context.Context, actor string, scope PayerBlockScope, scopeID string, payerID string) error
+}
+
 type App struct {
 	merchant  MerchantService
 	requisite RequisiteService
 	invoice   InvoiceService
+
+	// Сервисы песочницы; nil, если песочница выключена
+	sandboxRequisite RequisiteService
+	sandboxInvoice   InvoiceService
+	sandbox          SandboxService
+
+	admin AdminService
+
+	// payer nil, если проверки плательщиков выключены
+	payer PayerService
+
+	health HealthService
+
+	// limiter nil, если лимиты мерчантов выключены
+	limiter RateLimiter
+
+	metrics Metrics
 }
 
 func NewApp(merchant MerchantService, requisite RequisiteService, invoice InvoiceService) *App {
-	return &App{merchant: merchant, requisite: requisite, invoice: invoice}
+	return &App{merchant: merchant, requisite: requisite, invoice: invoice, metrics: NoopMetrics{}}
+}
+
+// WithAdmin подключает сервис админки
+func (a *App) WithAdmin(admin AdminService) *App {
+	a.admin = admin
+	return a
+}
+
+// WithPayer включает блокировки и лимиты плательщиков
+func (a *App) WithPayer(payer PayerService) *App {
+	a.payer = payer
+	return a
+}
+
+// WithRateLimiter включает лимиты частоты и параллельности запросов мерчантов
+func (a *App) WithRateLimiter(limiter RateLimiter) *App {
+	a.limiter = limiter
+	return a
+}
+
+// WithHealth подключает проверки готовности
+func (a *App) WithHealth(health HealthService) *App {
+	a.health = health
+	return a
+}
+
+// WithMetrics подключает сбор метрик создания Invoice
+func (a *App) WithMetrics(metrics Metrics) *App {
+	a.metrics = metrics
+	return a
+}
+
+// WithSandbox включает песочницу: Invoice мерчантов с IsSandbox создаются через
+// переданные сервисы и не затрагивают реальных трейдеров
+func (a *App) WithSandbox(requisite RequisiteService, invoice InvoiceService, sandbox SandboxService) *App {
+	a.sandboxRequisite = requisite
+	a.sandboxInvoice = invoice
+	a.sandbox = sandbox
+	return a
 }
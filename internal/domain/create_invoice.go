@@ -5,9 +5,14 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 	"github.com/shopspring/decimal"
+	"mateo/internal/tracing"
 )
 
+// maxCreateInvoiceAttempts сколько реквизитов пробуется, если баланс кошелька заняли параллельные запросы
+const maxCreateInvoiceAttempts = 3
+
 func (a *App) CreateInvoice(
 	ctx context.Context,
 	amount decimal.Decimal,
@@ -17,55 +22,171 @@ func (a *App) CreateInvoice(
 	callbackURL string,
 	callbackKey string,
 	activeTime time.Duration,
-	bankID string,
+	banks BankPreference,
+	payerID string,
 	flexibleRange int,
 	allowFlexibleAmount bool,
 ) (*Invoice, *Requisite, error) {
-	// Может ли Merchant принять такой Invoice?
-	err := a.merchant.ValidateMerchantInvoice(
+	ctx, end := a.stage(ctx, StageTotal)
+	invoice, requisite, merchant, err := a.createInvoice(
 		ctx,
-		merchantID,
 		amount,
+		merchantID,
 		requisiteType,
+		internalRequestID,
+		callbackURL,
+		callbackKey,
+		activeTime,
+		banks,
+		payerID,
+		flexibleRange,
+		allowFlexibleAmount,
 	)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "cannot create invoice for this merchant")
+	end(err)
+
+	// Метки берутся только из найденных записей: произвольные merchantID и банки
+	// из запроса не должны порождать новые серии
+	outcome := OutcomeOf(err)
+	merchantLabel := MetricsUnknownMerchant
+	if merchant != nil || outcome.MerchantResolved() {
+		merchantLabel = merchantID
+	}
+	bankID := ""
+	if requisite != nil {
+		bankID = requisite.BankID
 	}
+	a.metrics.ObserveInvoice(merchantLabel, requisiteType, bankID, outcome)
 
-	// Выбираем доступный реквизит
-	requisite, err := a.requisite.SelectAvailableRequisite(
-		ctx,
+	return invoice, requisite, err
+}
+
+func (a *App) createInvoice(
+	ctx context.Context,
+	amount decimal.Decimal,
+	merchantID string,
+	requisiteType RequisiteType,
+	internalRequestID string,
+	callbackURL string,
+	callbackKey string,
+	activeTime time.Duration,
+	banks BankPreference,
+	payerID string,
+	flexibleRange int,
+	allowFlexibleAmount bool,
+) (*Invoice, *Requisite, *Merchant, error) {
+	// Лимиты мерчанта проверяются до обращений к Postgres, чтобы поток повторов
+	// одного мерчанта не занял весь пул соединений
+	if a.limiter != nil {
+		stageCtx, end := a.stage(ctx, StageRateLimit)
+		release, err := a.limiter.Acquire(stageCtx, merchantID)
+		end(err)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		defer release()
+	}
+
+	// Может ли Merchant принять такой Invoice?
+	stageCtx, end := a.stage(ctx, StageMerchant)
+	merchant, err := a.merchant.ValidateMerchantInvoice(
+		stageCtx,
 		merchantID,
 		amount,
 		requisiteType,
-		bankID,
-		flexibleRange,
-		allowFlexibleAmount,
 	)
+	end(err)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "cannot select requisite")
+		return nil, nil, nil, errors.Wrap(err, "cannot create invoice for this merchant")
 	}
 
-	if requisite.FlexibleSelectedAmount.GreaterThan(decimal.Zero) {
-		amount = requisite.FlexibleSelectedAmount
+	// Мерчанты песочницы получают реквизиты фиктивного пула
+	requisiteService, invoiceService := a.requisite, a.invoice
+	if merchant.IsSandbox {
+		if a.sandbox == nil {
+			return nil, nil, merchant, ErrorSandboxDisabled
+		}
+		requisiteService, invoiceService = a.sandboxRequisite, a.sandboxInvoice
 	}
 
-	// Создаем Invoice
-	invoice, err := a.invoice.CreateInvoice(
-		ctx,
-		amount,
-		requisite.FlexibleSelectedAmount.GreaterThan(decimal.Zero),
-		internalRequestID,
-		callbackURL,
-		callbackKey,
-		merchantID,
-		activeTime,
-		requisite,
-	)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to create invoice")
+	// Плательщик из списка блокировки мерчанта или превысивший лимиты не получает реквизит.
+	// В песочнице истории плательщиков нет, проверка не выполняется.
+	if payerID != "" && a.payer != nil && !merchant.IsSandbox {
+		stageCtx, end := a.stage(ctx, StagePayer)
+		err := a.payer.CheckPayer(stageCtx, merchantID, payerID)
+		end(err)
+		if err != nil {
+			return nil, nil, merchant, errors.Wrap(err, "cannot accept payer")
+		}
+	}
+
+	// Свободный баланс кошелька перепроверяется под блокировкой при создании Invoice.
+	// Если его уже заняли параллельные запросы, выбираем кандидата с другого аккаунта.
+	var excludedAccounts []string
+	for attempt := 1; ; attempt++ {
+		// Выбираем доступный реквизит
+		stageCtx, end = a.stage(ctx, StageRequisite)
+		requisite, err := requisiteService.SelectAvailableRequisite(
+			stageCtx,
+			merchantID,
+			amount,
+			requisiteType,
+			banks,
+			payerID,
+			flexibleRange,
+			allowFlexibleAmount,
+			excludedAccounts,
+		)
+		end(err)
+		if err != nil {
+			return nil, nil, merchant, errors.Wrap(err, "cannot select requisite")
+		}
+
+		invoiceAmount := amount
+		if requisite.FlexibleSelectedAmount.GreaterThan(decimal.Zero) {
+			invoiceAmount = requisite.FlexibleSelectedAmount
+		}
+
+		// Создаем Invoice
+		stageCtx, end = a.stage(ctx, StageInvoice)
+		invoice, err := invoiceService.CreateInvoice(
+			stageCtx,
+			invoiceAmount,
+			requisite.FlexibleSelectedAmount.GreaterThan(decimal.Zero),
+			internalRequestID,
+			callbackURL,
+			callbackKey,
+			merchantID,
+			payerID,
+			activeTime,
+			requisite,
+		)
+		end(err)
+		if errors.Is(err, ErrorInsufficientBalance) && attempt < maxCreateInvoiceAttempts {
+			log.Ctx(ctx).Info().Str("traider_account_id", requisite.TraiderAccountID).Int("attempt", attempt).
+				Msg("wallet balance is taken, selecting another requisite")
+			excludedAccounts = append(excludedAccounts, requisite.TraiderAccountID)
+			continue
+		}
+		if err != nil {
+			return nil, nil, merchant, errors.Wrap(err, "failed to create invoice")
+		}
+
+		// Собираем ответ
+		return invoice, requisite, merchant, nil
+	}
+}
+
+// stage открывает спан этапа CreateInvoice; end закрывает его и записывает длительность этапа
+func (a *App) stage(ctx context.Context, name string) (context.Context, func(err error)) {
+	spanName := "App.CreateInvoice"
+	if name != StageTotal {
+		spanName += "/" + name
 	}
 
-	// Собираем ответ
-	return invoice, requisite, nil
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, spanName)
+	return ctx, func(err error) {
+		a.metrics.ObserveStage(name, time.Since(start))
+		tracing.End(span, err)
+	}
 }
@@ -9,11 +9,51 @@ var (
 	ErrorFailedFindMerchant  = errors.New("failed to find merchant")
 	ErrorAmountLessThanLimit = errors.New("amount less than limit")
 	ErrorFailedCreateInvoice = errors.New("failed to create invoice")
+	ErrorFailedUpdateInvoice = errors.New("failed to update invoice")
+	ErrorInvoiceNotFound     = errors.New("invoice not found")
+	ErrorInvoiceNotActive    = errors.New("invoice is not active")
+	ErrorInvalidStatus       = errors.New("invalid invoice status")
+	ErrorInsufficientBalance = errors.New("insufficient wallet balance")
+
+	ErrorAmountGreaterThanLimit        = errors.New("amount greater than limit")
+	ErrorMerchantSuspended             = errors.New("merchant is suspended")
+	ErrorRequisiteTypeDisabled         = errors.New("requisite type is disabled for merchant")
+	ErrorMerchantDailyTurnoverExceeded = errors.New("merchant daily turnover limit exceeded")
+	ErrorMerchantDailyInvoicesExceeded = errors.New("merchant daily invoice limit exceeded")
+	ErrorFailedGetMerchantTurnover     = errors.New("failed to get merchant turnover")
+	ErrorUnknownMerchantStatus         = errors.New("unknown merchant status")
 
 	ErrorFailedGetExchangeRate = errors.New("failed to get exchange rate")
 
 	ErrorNoAvailableRequisites = errors.New("no available requisites")
 
+	ErrorAdminDisabled          = errors.New("admin api is disabled")
+	ErrorMerchantAlreadyExists  = errors.New("merchant already exists")
+	ErrorMerchantHasInvoices    = errors.New("merchant has invoices")
+	ErrorTraderAccountNotFound  = errors.New("trader account not found")
+	ErrorFailedAdminChange      = errors.New("failed to apply admin change")
+	ErrorFailedGetBlock         = errors.New("failed to get block")
+	ErrorFailedGetAuditLog      = errors.New("failed to get audit log")
+	ErrorInvalidLimit           = errors.New("invalid limit")
+	ErrorInvalidTraderAccountID = errors.New("invalid trader account id")
+	ErrorBlockEntityNotFound    = errors.New("block entity not found")
+	ErrorUnknownBlockEntity     = errors.New("unknown block entity")
+	ErrorEmptyBlockReason       = errors.New("empty block reason")
+	ErrorInvalidBlockedUntil    = errors.New("blocked until must be in the future")
+
+	ErrorPayerBlocked            = errors.New("payer is blocked")
+	ErrorPayerVelocityExceeded   = errors.New("payer invoice limit exceeded")
+	ErrorFailedCheckPayer        = errors.New("failed to check payer")
+	ErrorUnknownPayerBlockScope  = errors.New("unknown payer block scope")
+	ErrorPayerBlockScopeNotFound = errors.New("payer block scope not found")
+
+	ErrorMerchantRateLimited        = errors.New("merchant request rate limit exceeded")
+	ErrorMerchantConcurrencyLimited = errors.New("too many concurrent requests for merchant")
+
+	ErrorSandboxDisabled       = errors.New("sandbox is disabled")
+	ErrorUnknownSandboxOutcome = errors.New("unknown sandbox outcome")
+	ErrorInvalidSignature      = errors.New("invalid request signature")
+
 	ErrorInvalidAmount      = errors.New("invalid amount")
 	ErrorInvalidMerchantID  = errors.New("invalid merchant id")
 	ErrorInvalidCallbackURL = errors.New("invalid callback url")
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"context"
+)
+
+type HealthStatus string
+
+const (
+	HealthStatusOK HealthStatus = "ok"
+	// HealthStatusDegraded зависимость не работает, но сервис обслуживает запросы без нее
+	HealthStatusDegraded HealthStatus = "degraded"
+	HealthStatusFailed   HealthStatus = "failed"
+)
+
+// HealthCheck результат проверки одной зависимости
+type HealthCheck struct {
+	Name   string
+	Status HealthStatus
+	// Error причина статуса, отличного от ok
+	Error string
+	// Details дополнительные сведения, например состояние автомата Redis или возраст курса
+	Details map[string]string
+}
+
+type HealthReport struct {
+	Ready  bool
+	Checks []HealthCheck
+}
+
+// HealthService проверяет готовность сервиса принимать запросы
+type HealthService interface {
+	Readiness(ctx context.Context) HealthReport
+}
+
+// Readiness проверяет зависимости; без сервиса проверок сервис считается готовым
+func (a *App) Readiness(ctx context.Context) HealthReport {
+	if a.health == nil {
+		return HealthReport{Ready: true}
+	}
+	return a.health.Readiness(ctx)
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Metrics принимает измерения создания Invoice и отбора реквизитов
+type Metrics interface {
+	// ObserveInvoice результат создания Invoice; merchantID — MetricsUnknownMerchant,
+	// если мерчант не найден, bankID — банк выданного реквизита или пустая строка
+	ObserveInvoice(merchantID string, requisiteType RequisiteType, bankID string, outcome InvoiceOutcome)
+	// ObserveStage длительность этапа CreateInvoice
+	ObserveStage(stage string, duration time.Duration)
+	// ObserveCandidates размер пула кандидатов; flexible — отбор по гибкой сумме после пустого точного
+	ObserveCandidates(requisiteType RequisiteType, flexible bool, count int)
+}
+
+// Этапы CreateInvoice
+const (
+	StageRateLimit = "rate_limit"
+	StageMerchant  = "merchant"
+	StagePayer     = "payer"
+	StageRequisite = "requisite"
+	StageInvoice   = "invoice"
+	StageTotal     = "total"
+)
+
+// MetricsUnknownMerchant метка мерчанта, который не был найден
+const MetricsUnknownMerchant = "unknown"
+
+type InvoiceOutcome string
+
+const (
+	InvoiceOutcomeCreated          InvoiceOutcome = "created"
+	InvoiceOutcomeRateLimited      InvoiceOutcome = "rate_limited"
+	InvoiceOutcomeMerchantNotFound InvoiceOutcome = "merchant_not_found"
+	InvoiceOutcomeAmountOutOfLimit InvoiceOutcome = "amount_out_of_limit"
+	InvoiceOutcomeMerchantRejected InvoiceOutcome = "merchant_rejected"
+	InvoiceOutcomeMerchantCap      InvoiceOutcome = "merchant_cap_exceeded"
+	InvoiceOutcomePayerBlocked     InvoiceOutcome = "payer_blocked"
+	InvoiceOutcomePayerVelocity    InvoiceOutcome = "payer_velocity_exceeded"
+	InvoiceOutcomeNoRequisites     InvoiceOutcome = "no_requisites"
+	InvoiceOutcomeError            InvoiceOutcome = "error"
+)
+
+// OutcomeOf причина отказа в создании Invoice для метрик
+func OutcomeOf(err error) InvoiceOutcome {
+	switch {
+	case err == nil:
+		return InvoiceOutcomeCreated
+	case errors.Is(err, ErrorMerchantRateLimited), errors.Is(err, ErrorMerchantConcurrencyLimited):
+		return InvoiceOutcomeRateLimited
+	case errors.Is(err, ErrorMerchantNotFound):
+		return InvoiceOutcomeMerchantNotFound
+	case errors.Is(err, ErrorAmountLessThanLimit), errors.Is(err, ErrorAmountGreaterThanLimit):
+		return InvoiceOutcomeAmountOutOfLimit
+	case errors.Is(err, ErrorMerchantSuspended), errors.Is(err, ErrorRequisiteTypeDisabled),
+		errors.Is(err, ErrorSandboxDisabled):
+		return InvoiceOutcomeMerchantRejected
+	case errors.Is(err, ErrorMerchantDailyTurnoverExceeded), errors.Is(err, ErrorMerchantDailyInvoicesExceeded):
+		return InvoiceOutcomeMerchantCap
+	case errors.Is(err, ErrorPayerBlocked):
+		return InvoiceOutcomePayerBlocked
+	case errors.Is(err, ErrorPayerVelocityExceeded):
+		return InvoiceOutcomePayerVelocity
+	case errors.Is(err, ErrorNoAvailableRequisites):
+		return InvoiceOutcomeNoRequisites
+	default:
+		return InvoiceOutcomeError
+	}
+}
+
+// MerchantResolved отказ по настройкам мерчанта: мерчант найден, хотя проверка вернула ошибку
+func (o InvoiceOutcome) MerchantResolved() bool {
+	switch o {
+	case InvoiceOutcomeAmountOutOfLimit, InvoiceOutcomeMerchantRejected, InvoiceOutcomeMerchantCap:
+		return true
+	default:
+		return false
+	}
+}
+
+// NoopMetrics отбрасывает измерения
+type NoopMetrics struct{}
+
+func (NoopMetrics) ObserveInvoice(string, RequisiteType, string, InvoiceOutcome) {}
+
+func (NoopMetrics) ObserveStage(string, time.Duration) {}
+
+func (NoopMetrics) ObserveCandidates(RequisiteType, bool, int) {}
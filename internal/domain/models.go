@@ -2,6 +2,7 @@ package domain
 
 import (
 	"github.com/shopspring/decimal"
+	"regexp"
 	"time"
 )
 
@@ -16,15 +17,240 @@ const (
 type InvoiceStatus string
 
 const (
-	InvoiceStatusCreated InvoiceStatus = "CREATED"
+	InvoiceStatusCreated       InvoiceStatus = "CREATED"
+	InvoiceStatusSuccess       InvoiceStatus = "SUCCESS"
+	InvoiceStatusSuccessHand   InvoiceStatus = "SUCCESS_HAND"
+	InvoiceStatusSuccessAppeal InvoiceStatus = "SUCCESS_APPEAL"
+	InvoiceStatusExpired       InvoiceStatus = "EXPIRED"
+	InvoiceStatusCanceled      InvoiceStatus = "CANCELED"
+)
+
+// IsSuccess сообщает, что Invoice оплачен и hold нужно списать с баланса
+func (s InvoiceStatus) IsSuccess() bool {
+	switch s {
+	case InvoiceStatusSuccess, InvoiceStatusSuccessHand, InvoiceStatusSuccessAppeal:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsFinal сообщает, что Invoice больше не может сменить статус
+func (s InvoiceStatus) IsFinal() bool {
+	return s.IsSuccess() || s == InvoiceStatusExpired || s == InvoiceStatusCanceled
+}
+
+// HoldStatus статус блокировки средств на кошельке трейдера под Invoice
+type HoldStatus string
+
+const (
+	HoldStatusActive   HoldStatus = "ACTIVE"
+	HoldStatusReleased HoldStatus = "RELEASED"
+	HoldStatusCaptured HoldStatus = "CAPTURED"
+)
+
+// MerchantStatus приостановленный мерчант не может создавать Invoice
+type MerchantStatus string
+
+const (
+	MerchantStatusActive    MerchantStatus = "ACTIVE"
+	MerchantStatusSuspended MerchantStatus = "SUSPENDED"
 )
 
 type Merchant struct {
 	ID string
+	// IsSandbox мерчант песочницы: его Invoice обслуживаются фиктивным пулом реквизитов
+	IsSandbox bool
+	Status    MerchantStatus
 
+	// InLimit* минимальная сумма Invoice по типу реквизита
 	InLimitCard   decimal.Decimal
 	InLimitWallet decimal.Decimal
 	InLimitSBP    decimal.Decimal
+
+	// InMax* максимальная сумма Invoice по типу реквизита; ноль — без ограничения
+	InMaxCard   decimal.Decimal
+	InMaxWallet decimal.Decimal
+	InMaxSBP    decimal.Decimal
+
+	// InDisabled* выключает прием Invoice по типу реквизита
+	InDisabledCard   bool
+	InDisabledWallet bool
+	InDisabledSBP    bool
+
+	// DailyTurnoverLimit и DailyInvoiceLimit дневные лимиты суммы и числа Invoice мерчанта
+	// за бизнес-день; ноль — без ограничения
+	DailyTurnoverLimit decimal.Decimal
+	DailyInvoiceLimit  int
+}
+
+// MerchantInLimits ограничения мерчанта для одного типа реквизита
+type MerchantInLimits struct {
+	Min      decimal.Decimal
+	Max      decimal.Decimal
+	Disabled bool
+}
+
+// InLimits возвращает ограничения мерчанта для типа реквизита
+func (m *Merchant) InLimits(requisiteType RequisiteType) (MerchantInLimits, error) {
+	switch requisiteType {
+	case RequisiteTypeCard:
+		return MerchantInLimits{Min: m.InLimitCard, Max: m.InMaxCard, Disabled: m.InDisabledCard}, nil
+	case RequisiteTypeWallet:
+		return MerchantInLimits{Min: m.InLimitWallet, Max: m.InMaxWallet, Disabled: m.InDisabledWallet}, nil
+	case RequisiteTypeSBP:
+		return MerchantInLimits{Min: m.InLimitSBP, Max: m.InMaxSBP, Disabled: m.InDisabledSBP}, nil
+	default:
+		return MerchantInLimits{}, ErrorUnknownRequisiteType
+	}
+}
+
+// IsSuspended сообщает, что мерчант приостановлен; пустой статус считается активным
+func (m *Merchant) IsSuspended() bool {
+	return m.Status == MerchantStatusSuspended
+}
+
+// ValidateLimits проверяет, что лимиты неотрицательны, а максимальная сумма не меньше минимальной
+func (m *Merchant) ValidateLimits() error {
+	for _, requisiteType := range []RequisiteType{RequisiteTypeCard, RequisiteTypeWallet, RequisiteTypeSBP} {
+		limits, _ := m.InLimits(requisiteType)
+		if limits.Min.IsNegative() || limits.Max.IsNegative() {
+			return ErrorInvalidLimit
+		}
+		if limits.Max.IsPositive() && limits.Max.LessThan(limits.Min) {
+			return ErrorInvalidLimit
+		}
+	}
+	if m.DailyTurnoverLimit.IsNegative() || m.DailyInvoiceLimit < 0 {
+		return ErrorInvalidLimit
+	}
+	return nil
+}
+
+// MerchantTurnover созданные и оплаченные Invoice мерчанта за бизнес-день; истекшие и отмененные не учитываются
+type MerchantTurnover struct {
+	Count int
+	Sum   decimal.Decimal
+}
+
+// Quarantine временное исключение реквизита или терминала из выбора после серии неоплаченных Invoice
+type Quarantine struct {
+	Entity   BlockEntity
+	EntityID string
+	Reason   string
+	Until    time.Time
+}
+
+// OutcomeStats исходы закрытых Invoice сущности: всего и оплачено
+type OutcomeStats struct {
+	Total   int
+	Success int
+}
+
+// TeamNotificationKindQuarantine уведомление о карантине реквизита или терминала
+const TeamNotificationKindQuarantine = "quarantine"
+
+// TeamNotification уведомление команды трейдеров из outbox
+type TeamNotification struct {
+	ID        string
+	TeamID    string
+	Kind      string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// MerchantPatch изменение мерчанта из админки; nil-поля не меняются
+type MerchantPatch struct {
+	InLimitCard        *decimal.Decimal
+	InLimitWallet      *decimal.Decimal
+	InLimitSBP         *decimal.Decimal
+	InMaxCard          *decimal.Decimal
+	InMaxWallet        *decimal.Decimal
+	InMaxSBP           *decimal.Decimal
+	InDisabledCard     *bool
+	InDisabledWallet   *bool
+	InDisabledSBP      *bool
+	DailyTurnoverLimit *decimal.Decimal
+	DailyInvoiceLimit  *int
+	IsSandbox          *bool
+	Status             *MerchantStatus
+}
+
+// Apply возвращает мерчанта с примененными изменениями
+func (p MerchantPatch) Apply(m Merchant) Merchant {
+	setDecimal := func(dst *decimal.Decimal, src *decimal.Decimal) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	setBool := func(dst *bool, src *bool) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+
+	setDecimal(&m.InLimitCard, p.InLimitCard)
+	setDecimal(&m.InLimitWallet, p.InLimitWallet)
+	setDecimal(&m.InLimitSBP, p.InLimitSBP)
+	setDecimal(&m.InMaxCard, p.InMaxCard)
+	setDecimal(&m.InMaxWallet, p.InMaxWallet)
+	setDecimal(&m.InMaxSBP, p.InMaxSBP)
+	setBool(&m.InDisabledCard, p.InDisabledCard)
+	setBool(&m.InDisabledWallet, p.InDisabledWallet)
+	setBool(&m.InDisabledSBP, p.InDisabledSBP)
+	setDecimal(&m.DailyTurnoverLimit, p.DailyTurnoverLimit)
+	setBool(&m.IsSandbox, p.IsSandbox)
+	if p.DailyInvoiceLimit != nil {
+		m.DailyInvoiceLimit = *p.DailyInvoiceLimit
+	}
+	if p.Status != nil {
+		m.Status = *p.Status
+	}
+	return m
+}
+
+// AuditEntry запись журнала изменений, сделанных через админку
+type AuditEntry struct {
+	ID         string
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   string
+	// Before и After состояние сущности в JSON до и после изменения; пусто при создании и удалении
+	Before    []byte
+	After     []byte
+	CreatedAt time.Time
+}
+
+// SandboxOutcome исход оплаты, который мерчант песочницы может сымитировать для Invoice
+type SandboxOutcome string
+
+const (
+	SandboxOutcomeSuccess SandboxOutcome = "success"
+	SandboxOutcomeExpire  SandboxOutcome = "expire"
+	SandboxOutcomeAppeal  SandboxOutcome = "appeal"
+)
+
+func ParseSandboxOutcome(o string) (SandboxOutcome, error) {
+	switch SandboxOutcome(o) {
+	case SandboxOutcomeSuccess, SandboxOutcomeExpire, SandboxOutcomeAppeal:
+		return SandboxOutcome(o), nil
+	default:
+		return "", ErrorUnknownSandboxOutcome
+	}
+}
+
+// SignedBody сырое тело запроса мерчанта и его hex-подпись HMAC-SHA256 ключом callbackKey Invoice
+type SignedBody struct {
+	Body      []byte
+	Signature string
+}
+
+// SandboxSimulation результат имитации оплаты в песочнице
+type SandboxSimulation struct {
+	Invoice *Invoice
+	// CallbackDelivered мерчант ответил на callback статусом 2xx
+	CallbackDelivered bool
 }
 
 type Provider struct {
@@ -35,14 +261,16 @@ type Provider struct {
 }
 
 type Invoice struct {
-	ID                string
-	MerchantID        string
-	Amount            decimal.Decimal
-	Status            InvoiceStatus
-	Type              RequisiteType
-	IsFlexibleAmount  bool
-	TerminalID        string
-	UserID            string
+	ID               string
+	MerchantID       string
+	Amount           decimal.Decimal
+	Status           InvoiceStatus
+	Type             RequisiteType
+	IsFlexibleAmount bool
+	TerminalID       string
+	UserID           string
+	// PayerID необязательный идентификатор плательщика на стороне мерчанта
+	PayerID           string
 	BankID            string
 	TraiderAccountID  string
 	RequisiteID       string
@@ -51,6 +279,15 @@ type Invoice struct {
 	InternalRequestID string
 	TimeExpires       time.Time
 	Exchange          decimal.Decimal
+	CreatedAt         time.Time
+}
+
+type WalletHold struct {
+	ID        string
+	WalletID  string
+	InvoiceID string
+	Amount    decimal.Decimal
+	Status    HoldStatus
 }
 
 type Team struct {
@@ -65,7 +302,74 @@ type TraderAccount struct {
 	Balance      decimal.Decimal
 	DailyLimit   decimal.Decimal
 	IsActive     bool
-	BlockedUntil *int64
+	BlockedUntil *time.Time
+}
+
+// BlockEntity сущность пула, которую можно заблокировать из админки
+type BlockEntity string
+
+const (
+	BlockEntityTraderAccount BlockEntity = "trader_account"
+	BlockEntityTerminal      BlockEntity = "terminal"
+	BlockEntityRequisite     BlockEntity = "requisite"
+)
+
+// Block состояние блокировки сущности. Блокировка с BlockedUntil снимается сама,
+// когда этот момент наступает; без BlockedUntil действует до явного снятия.
+type Block struct {
+	Entity       BlockEntity
+	EntityID     string
+	IsBlocked    bool
+	Reason       string
+	BlockedUntil *time.Time
+	BlockedBy    string
+}
+
+// ActiveAt сообщает, действует ли блокировка в момент now
+func (b *Block) ActiveAt(now time.Time) bool {
+	return b.IsBlocked && (b.BlockedUntil == nil || b.BlockedUntil.After(now))
+}
+
+// PayerIDPattern допустимые идентификаторы плательщиков
+var PayerIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:@-]{1,128}$`)
+
+// PayerBlockScope чей список блокировки плательщиков: мерчанта или команды трейдеров
+type PayerBlockScope string
+
+const (
+	PayerBlockScopeMerchant PayerBlockScope = "merchant"
+	PayerBlockScopeTeam     PayerBlockScope = "team"
+)
+
+// PayerBlock плательщик в списке блокировки. Плательщик в списке мерчанта не может создать
+// Invoice у этого мерчанта, в списке команды — не получает реквизиты ее трейдеров.
+type PayerBlock struct {
+	Scope     PayerBlockScope
+	ScopeID   string
+	PayerID   string
+	Reason    string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// PayerActivity Invoice плательщика у мерчанта: созданные с начала окна и активные сейчас
+type PayerActivity struct {
+	Created int
+	Active  int
+}
+
+// BankPreference пожелания к банку реквизита. Реквизиты банков из Preferred выбираются в первую
+// очередь, но при их отсутствии подходят и другие банки; Strict разрешает только Preferred.
+// Банки из Excluded не выбираются никогда.
+type BankPreference struct {
+	Preferred []string
+	Excluded  []string
+	Strict    bool
+}
+
+// Prefers сообщает, что банк входит в предпочтительные
+func (p BankPreference) Prefers(bankID string) bool {
+	return Contains(p.Preferred, bankID)
 }
 
 type Requisite struct {
@@ -82,6 +386,20 @@ type Requisite struct {
 	TeamID                 string
 	TraiderAccountID       string
 	FlexibleSelectedAmount decimal.Decimal
+	Stats                  RequisiteStats
+}
+
+// RequisiteStats загрузка и исходы Invoice реквизита и его терминала для оценки кандидата.
+// Исходы считаются по Invoice, созданным в окне счетчиков; отмененные не учитываются.
+type RequisiteStats struct {
+	ActiveInvoices   int
+	LastInvoiceTime  *time.Time
+	TerminalClosed   int
+	TerminalSuccess  int
+	RequisiteClosed  int
+	RequisiteSuccess int
+	// PayerExpired сколько Invoice текущего плательщика на реквизите истекли неоплаченными
+	PayerExpired int
 }
 
 type CreateInvoiceDTO struct {
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimiter ограничивает частоту и число одновременных запросов создания Invoice одного мерчанта
+type RateLimiter interface {
+	// Acquire занимает место для запроса мерчанта. release нужно вызвать после обработки запроса.
+	// При превышении лимита возвращает *RateLimitError.
+	Acquire(ctx context.Context, merchantID string) (release func(), err error)
+}
+
+// RateLimitError отказ по лимиту мерчанта; Err — ErrorMerchantRateLimited
+// или ErrorMerchantConcurrencyLimited
+type RateLimitError struct {
+	Err error
+	// RetryAfter через сколько запрос может пройти
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// SimulateSandboxPayment переводит Invoice песочницы в статус, соответствующий outcome,
+// и отправляет мерчанту подписанный callback. Запрос должен быть подписан ключом callbackKey Invoice.
+func (a *App) SimulateSandboxPayment(
+	ctx context.Context,
+	merchantID string,
+	invoiceID string,
+	outcome SandboxOutcome,
+	signed SignedBody,
+) (*SandboxSimulation, error) {
+	if a.sandbox == nil {
+		return nil, ErrorSandboxDisabled
+	}
+
+	simulation, err := a.sandbox.Simulate(ctx, merchantID, invoiceID, outcome, signed)
+	if err != nil {
+		return nil, errors.Wrap(err, "simulate sandbox payment")
+	}
+
+	return simulation, nil
+}
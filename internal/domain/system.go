@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// Clock источник текущего времени
+type Clock interface {
+	Now() time.Time
+}
+
+// Rand источник случайных чисел для выбора реквизита
+type Rand interface {
+	// Intn возвращает случайное число в [0, n)
+	Intn(n int) int
+	// Float64 возвращает случайное число в [0, 1)
+	Float64() float64
+}
+
+// IDGenerator выдает идентификаторы новых записей
+type IDGenerator interface {
+	NewID() string
+}
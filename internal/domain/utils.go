@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 func Contains(s []string, e string) bool {
 	for _, a := range s {
 		if a == e {
@@ -8,3 +10,9 @@ func Contains(s []string, e string) bool {
 	}
 	return false
 }
+
+// StartOfDay возвращает начало календарного дня для момента t в часовом поясе loc
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	year, month, day := t.In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
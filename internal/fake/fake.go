@@ -0,0 +1,115 @@
+// Package fake содержит детерминированные реализации domain.Clock, domain.Rand и
+// domain.IDGenerator для тестов.
+package fake
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock возвращает заданное время и сдвигается только явно
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set устанавливает текущее время
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance сдвигает текущее время на d
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Rand по кругу возвращает заданную последовательность, приводя каждое значение к [0, n).
+// Без заданных значений всегда возвращает 0. Float64 так же перебирает значения WithFloats.
+type Rand struct {
+	mu        sync.Mutex
+	values    []int
+	next      int
+	calls     []int
+	floats    []float64
+	nextFloat int
+}
+
+func NewRand(values ...int) *Rand {
+	return &Rand{values: values}
+}
+
+func (r *Rand) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, n)
+	if len(r.values) == 0 {
+		return 0
+	}
+
+	value := r.values[r.next%len(r.values)]
+	r.next++
+	return value % n
+}
+
+// WithFloats задает последовательность для Float64
+func (r *Rand) WithFloats(values ...float64) *Rand {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.floats = values
+	r.nextFloat = 0
+	return r
+}
+
+func (r *Rand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.floats) == 0 {
+		return 0
+	}
+
+	value := r.floats[r.nextFloat%len(r.floats)]
+	r.nextFloat++
+	return value
+}
+
+// Calls возвращает аргументы n всех вызовов Intn, например размеры пулов кандидатов
+func (r *Rand) Calls() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.calls...)
+}
+
+// IDGenerator выдает последовательные идентификаторы вида "<prefix>-1", "<prefix>-2", ...
+type IDGenerator struct {
+	mu     sync.Mutex
+	prefix string
+	next   int
+}
+
+func NewIDGenerator(prefix string) *IDGenerator {
+	return &IDGenerator{prefix: prefix}
+}
+
+func (g *IDGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.next++
+	return fmt.Sprintf("%s-%d", g.prefix, g.next)
+}
@@ -0,0 +1,18 @@
+// Package logging настраивает глобальный zerolog: маскирование персональных данных
+// и логгер по умолчанию для контекстов без логгера запроса.
+package logging
+
+import (
+	"io"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Setup направляет глобальный логгер в out через маскирование. Логгер из log.Ctx(ctx)
+// без логгера запроса в ctx — глобальный, поэтому фоновые задачи пишут так же.
+func Setup(out io.Writer) {
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	log.Logger = zerolog.New(maskingWriter{out: out}).With().Timestamp().Logger()
+	zerolog.DefaultContextLogger = &log.Logger
+}
@@ -0,0 +1,132 @@
+package logging
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	// stringPattern строковое значение JSON, возможно вместе с ключом
+	stringPattern = regexp.MustCompile(`(?:"((?:[^"\\]|\\.)*)"\s*:\s*)?"((?:[^"\\]|\\.)*)"`)
+	// numberPattern номер карты, телефона или кошелька: от 10 до 19 цифр, возможно с + в начале
+	// и пробелами или дефисами между группами
+	numberPattern = regexp.MustCompile(`\+?\d(?:[ -]?\d){9,18}`)
+)
+
+// sensitiveKeys ключи, значения которых маскируются целиком, в нижнем регистре и без "_".
+// true — значение секретное и не показывается даже частично.
+var sensitiveKeys = map[string]bool{
+	"cardnumber":   false,
+	"phonenumber":  false,
+	"phone":        false,
+	"walletnumber": false,
+	"pan":          false,
+	"callbackkey":  true,
+}
+
+// Mask скрывает в строке лога JSON номера карт, телефонов и кошельков и ключи callback.
+// Значения с известными ключами маскируются целиком, в остальных строках маскируются
+// длинные последовательности цифр. Числа вне строк, например время, не меняются.
+func Mask(line []byte) []byte {
+	return stringPattern.ReplaceAllFunc(line, func(match []byte) []byte {
+		groups := stringPattern.FindSubmatchIndex(match)
+		valueStart, valueEnd := groups[4], groups[5]
+		value := string(match[valueStart:valueEnd])
+
+		if groups[2] >= 0 {
+			key := strings.ToLower(strings.ReplaceAll(string(match[groups[2]:groups[3]]), "_", ""))
+			if secret, ok := sensitiveKeys[key]; ok {
+				if secret {
+					return replaceValue(match, valueStart, valueEnd, "***")
+				}
+				return replaceValue(match, valueStart, valueEnd, maskValue(value))
+			}
+		}
+
+		masked := maskNumbers(value)
+		if masked == value {
+			return match
+		}
+		return replaceValue(match, valueStart, valueEnd, masked)
+	})
+}
+
+func replaceValue(match []byte, start int, end int, value string) []byte {
+	out := make([]byte, 0, len(match))
+	out = append(out, match[:start]...)
+	out = append(out, value...)
+	return append(out, match[end:]...)
+}
+
+// maskValue оставляет последние 4 символа значений длиннее 8 символов
+func maskValue(value string) string {
+	if len(value) <= 8 {
+		return strings.Repeat("*", len(value))
+	}
+	return strings.Repeat("*", len(value)-4) + value[len(value)-4:]
+}
+
+// maskNumbers маскирует номера внутри строки. Цифры, соседние с буквами или дефисами,
+// относятся к идентификаторам вроде UUID и не маскируются.
+func maskNumbers(value string) string {
+	indexes := numberPattern.FindAllStringIndex(value, -1)
+	if indexes == nil {
+		return value
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, index := range indexes {
+		start, end := index[0], index[1]
+		if partOfIdentifier(value, start, end) {
+			continue
+		}
+		b.WriteString(value[last:start])
+		b.WriteString(maskDigits(value[start:end]))
+		last = end
+	}
+	b.WriteString(value[last:])
+	return b.String()
+}
+
+func partOfIdentifier(value string, start int, end int) bool {
+	identifier := func(c byte) bool {
+		return c == '-' || c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+	}
+	return start > 0 && identifier(value[start-1]) || end < len(value) && identifier(value[end])
+}
+
+// maskDigits заменяет все цифры, кроме последних 4, на *
+func maskDigits(number string) string {
+	digits := 0
+	for i := range number {
+		if number[i] >= '0' && number[i] <= '9' {
+			digits++
+		}
+	}
+
+	out := []byte(number)
+	for i := range out {
+		if digits <= 4 {
+			break
+		}
+		if out[i] >= '0' && out[i] <= '9' {
+			out[i] = '*'
+			digits--
+		}
+	}
+	return string(out)
+}
+
+// maskingWriter маскирует каждую запись zerolog перед выводом
+type maskingWriter struct {
+	out io.Writer
+}
+
+func (w maskingWriter) Write(p []byte) (int, error) {
+	if _, err := w.out.Write(Mask(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,77 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mateo/internal/logging"
+)
+
+func TestMask(t *testing.T) {
+	cases := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"card number key", `{"card_number":"4111111111111111"}`, `{"card_number":"************1111"}`},
+		{"camel case key", `{"cardNumber":"4111 1111 1111 1111"}`, `{"cardNumber":"***************1111"}`},
+		{"phone key", `{"phone":"+79161234567"}`, `{"phone":"********4567"}`},
+		{"short keyed value", `{"PAN":"12345678"}`, `{"PAN":"********"}`},
+		{"callback key", `{"callback_key":"7f3c9a1e5b2d4f60"}`, `{"callback_key":"***"}`},
+		{"short callback key", `{"callbackKey":"abc"}`, `{"callbackKey":"***"}`},
+
+		{"card in message", `{"message":"card 4111111111111111 declined"}`, `{"message":"card ************1111 declined"}`},
+		{"grouped card", `{"message":"4111-1111-1111-1111"}`, `{"message":"****-****-****-1111"}`},
+		{"grouped phone", `{"message":"call +7 916 123-45-67 now"}`, `{"message":"call +* *** ***-45-67 now"}`},
+		{"array value", `["4111111111111111"]`, `["************1111"]`},
+		{"nine digits", `{"message":"123456789"}`, `{"message":"123456789"}`},
+		{"twenty digits", `{"message":"12345678901234567890"}`, `{"message":"12345678901234567890"}`},
+
+		{"uuid", `{"id":"3f2a1c4e-1234-5678-9012-123456789012"}`, `{"id":"3f2a1c4e-1234-5678-9012-123456789012"}`},
+		{"identifier with underscore", `{"message":"order_1234567890"}`, `{"message":"order_1234567890"}`},
+		{"identifier with letters", `{"request_id":"req1234567890abc"}`, `{"request_id":"req1234567890abc"}`},
+		{"number outside string", `{"amount":1234567890123,"time":1736942400}`, `{"amount":1234567890123,"time":1736942400}`},
+		{"escaped quotes", `{"message":"card \"4111111111111111\""}`, `{"message":"card \"************1111\""}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, string(logging.Mask([]byte(c.line))))
+		})
+	}
+}
+
+// TestMaskCreateInvoiceLog строка лога pg.Store.CreateInvoice, когда ошибка Postgres
+// содержит значения вставляемой строки
+func TestMaskCreateInvoiceLog(t *testing.T) {
+	var out bytes.Buffer
+	logging.Setup(&out)
+	defer logging.Setup(&bytes.Buffer{})
+
+	err := errors.New(`ERROR: new row for relation "InvoiceIn" violates check constraint ` +
+		`"InvoiceIn_amount_check" (SQLSTATE 23514): failing row contains ` +
+		`(4111111111111111, +79161234567, 500.00)`)
+	log.Ctx(context.Background()).Error().Err(err).
+		Str("merchant_id", "3f2a1c4e-1234-5678-9012-123456789012").
+		Str("requisite_id", "5d6e7f80-9a1b-4c2d-8e3f-405162738495").
+		Str("internal_request_id", "req_1736942400123").
+		Msg("failed to create invoice")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &line), out.String())
+	assert.Equal(t, "error", line["level"])
+	assert.Equal(t, "failed to create invoice", line["message"])
+	assert.Equal(t, "3f2a1c4e-1234-5678-9012-123456789012", line["merchant_id"])
+	assert.Equal(t, "5d6e7f80-9a1b-4c2d-8e3f-405162738495", line["requisite_id"])
+	assert.Equal(t, "req_1736942400123", line["internal_request_id"])
+	assert.Equal(t, `ERROR: new row for relation "InvoiceIn" violates check constraint `+
+		`"InvoiceIn_amount_check" (SQLSTATE 23514): failing row contains `+
+		`(************1111, +*******4567, 500.00)`, line["error"])
+	assert.IsType(t, float64(0), line["time"])
+}
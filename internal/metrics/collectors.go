@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"mateo/internal/cache"
+)
+
+var (
+	poolAcquiredDesc = prometheus.NewDesc(namespace+"_pgxpool_acquired_conns",
+		"Connections currently acquired from the pool.", nil, nil)
+	poolIdleDesc = prometheus.NewDesc(namespace+"_pgxpool_idle_conns",
+		"Idle connections in the pool.", nil, nil)
+	poolTotalDesc = prometheus.NewDesc(namespace+"_pgxpool_total_conns",
+		"All connections in the pool, including ones being opened.", nil, nil)
+	poolMaxDesc = prometheus.NewDesc(namespace+"_pgxpool_max_conns",
+		"Maximum size of the pool.", nil, nil)
+	poolAcquiresDesc = prometheus.NewDesc(namespace+"_pgxpool_acquires_total",
+		"Successful connection acquires.", nil, nil)
+	poolEmptyAcquiresDesc = prometheus.NewDesc(namespace+"_pgxpool_empty_acquires_total",
+		"Acquires that had to wait for a connection.", nil, nil)
+	poolCanceledAcquiresDesc = prometheus.NewDesc(namespace+"_pgxpool_canceled_acquires_total",
+		"Acquires canceled by their context.", nil, nil)
+	poolAcquireSecondsDesc = prometheus.NewDesc(namespace+"_pgxpool_acquire_seconds_total",
+		"Total time spent acquiring connections.", nil, nil)
+
+	cacheRequestsDesc = prometheus.NewDesc(namespace+"_cache_requests_total",
+		"Cache lookups by result: local and remote hits, loads from Postgres, stale fallbacks and errors.",
+		[]string{"cache", "result"}, nil)
+)
+
+// poolCollector читает pgxpool.Stat при каждом сборе
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquiredDesc
+	ch <- poolIdleDesc
+	ch <- poolTotalDesc
+	ch <- poolMaxDesc
+	ch <- poolAcquiresDesc
+	ch <- poolEmptyAcquiresDesc
+	ch <- poolCanceledAcquiresDesc
+	ch <- poolAcquireSecondsDesc
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(poolAcquiredDesc, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalDesc, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxDesc, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquiresDesc, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquiresDesc, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceledAcquiresDesc, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireSecondsDesc, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
+
+// cacheCollector отдает счетчики кешей; доля попаданий считается в запросе Prometheus
+type cacheCollector struct {
+	stats func() map[string]cache.Stats
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheRequestsDesc
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, stats := range c.stats() {
+		for result, value := range map[string]uint64{
+			"local_hit":  stats.LocalHits,
+			"remote_hit": stats.RemoteHits,
+			"load":       stats.Loads,
+			"stale":      stats.Stale,
+			"error":      stats.Errors,
+		} {
+			ch <- prometheus.MustNewConstMetric(cacheRequestsDesc, prometheus.CounterValue, float64(value), name, result)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package metrics собирает метрики сервиса в формате Prometheus. Метрики отдаются
+// отдельным внутренним слушателем, а не основным API.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"mateo/internal/cache"
+	"mateo/internal/domain"
+)
+
+const namespace = "mateo"
+
+// Metrics реализует domain.Metrics
+type Metrics struct {
+	registry   *prometheus.Registry
+	invoices   *prometheus.CounterVec
+	stages     *prometheus.HistogramVec
+	candidates *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		invoices: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "invoices_total",
+			Help:      "Invoice creation attempts by merchant, requisite type, bank and outcome.",
+		}, []string{"merchant", "type", "bank", "outcome"}),
+		stages: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "create_invoice_stage_seconds",
+			Help:      "Duration of CreateInvoice stages.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"stage"}),
+		candidates: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "requisite_candidates",
+			Help:      "Requisite candidates returned by the store; flexible=true is the flexible amount fallback.",
+			Buckets:   []float64{0, 1, 2, 5, 10, 20, 50, 100, 200, 500},
+		}, []string{"type", "flexible"}),
+	}
+
+	m.registry.MustRegister(
+		m.invoices,
+		m.stages,
+		m.candidates,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+func (m *Metrics) ObserveInvoice(
+	merchantID string,
+	requisiteType domain.RequisiteType,
+	bankID string,
+	outcome domain.InvoiceOutcome,
+) {
+	m.invoices.WithLabelValues(merchantID, string(requisiteType), bankID, string(outcome)).Inc()
+}
+
+func (m *Metrics) ObserveStage(stage string, duration time.Duration) {
+	m.stages.WithLabelValues(stage).Observe(duration.Seconds())
+}
+
+func (m *Metrics) ObserveCandidates(requisiteType domain.RequisiteType, flexible bool, count int) {
+	label := "false"
+	if flexible {
+		label = "true"
+	}
+	m.candidates.WithLabelValues(string(requisiteType), label).Observe(float64(count))
+}
+
+// RegisterPool отдает статистику пула соединений Postgres
+func (m *Metrics) RegisterPool(pool *pgxpool.Pool) {
+	m.registry.MustRegister(&poolCollector{pool: pool})
+}
+
+// RegisterCaches отдает счетчики обращений к кешам; stats вызывается при каждом сборе
+func (m *Metrics) RegisterCaches(stats func() map[string]cache.Stats) {
+	m.registry.MustRegister(&cacheCollector{stats: stats})
+}
+
+// RegisterBreaker отдает состояние автомата Redis
+func (m *Metrics) RegisterBreaker(breaker *cache.Breaker) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "redis_available",
+		Help:      "1 while the Redis circuit breaker is closed, 0 while it is open or half-open.",
+	}, func() float64 {
+		if breaker.State() == cache.BreakerClosed {
+			return 1
+		}
+		return 0
+	}))
+}
+
+// Handler отдает метрики в текстовом формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// NewServer внутренний слушатель метрик на port
+func (m *Metrics) NewServer(port string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	return &http.Server{
+		Addr:              ":" + port,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
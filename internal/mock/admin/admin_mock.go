@@ -0,0 +1,245 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/service/admin/admin.go
+//
+// Generated by this command:
+//
+//	mockgen -destination ./internal/mock/admin/admin_mock.go --source ./internal/service/admin/admin.go Store
+//
+
+// Package mock_admin is a generated GoMock package.
+package mock_admin
+
+import (
+	context "context"
+	domain "mateo/internal/domain"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockStore is a mock of Store interface.
+type MockStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockStoreMockRecorder is the mock recorder for MockStore.
+type MockStoreMockRecorder struct {
+	mock *MockStore
+}
+
+// NewMockStore creates a new mock instance.
+func NewMockStore(ctrl *gomock.Controller) *MockStore {
+	mock := &MockStore{ctrl: ctrl}
+	mock.recorder = &MockStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStore) EXPECT() *MockStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateMerchant mocks base method.
+func (m *MockStore) CreateMerchant(ctx context.Context, actor string, merchant *domain.Merchant) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateMerchant", ctx, actor, merchant)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateMerchant indicates an expected call of CreateMerchant.
+func (mr *MockStoreMockRecorder) CreateMerchant(ctx, actor, merchant any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMerchant", reflect.TypeOf((*MockStore)(nil).CreateMerchant), ctx, actor, merchant)
+}
+
+// DeleteMerchant mocks base method.
+func (m *MockStore) DeleteMerchant(ctx context.Context, actor, merchantID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMerchant", ctx, actor, merchantID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteMerchant indicates an expected call of DeleteMerchant.
+func (mr *MockStoreMockRecorder) DeleteMerchant(ctx, actor, merchantID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMerchant", reflect.TypeOf((*MockStore)(nil).DeleteMerchant), ctx, actor, merchantID)
+}
+
+// DeletePayerBlock mocks base method.
+func (m *MockStore) DeletePayerBlock(ctx context.Context, actor string, scope domain.PayerBlockScope, scopeID, payerID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePayerBlock", ctx, actor, scope, scopeID, payerID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePayerBlock indicates an expected call of DeletePayerBlock.
+func (mr *MockStoreMockRecorder) DeletePayerBlock(ctx, actor, scope, scopeID, payerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePayerBlock", reflect.TypeOf((*MockStore)(nil).DeletePayerBlock), ctx, actor, scope, scopeID, payerID)
+}
+
+// GetBlock mocks base method.
+func (m *MockStore) GetBlock(ctx context.Context, entity domain.BlockEntity, entityID string) (*domain.Block, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlock", ctx, entity, entityID)
+	ret0, _ := ret[0].(*domain.Block)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlock indicates an expected call of GetBlock.
+func (mr *MockStoreMockRecorder) GetBlock(ctx, entity, entityID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlock", reflect.TypeOf((*MockStore)(nil).GetBlock), ctx, entity, entityID)
+}
+
+// GetMerchantByMerchantID mocks base method.
+func (m *MockStore) GetMerchantByMerchantID(ctx context.Context, merchantID string) (*domain.Merchant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMerchantByMerchantID", ctx, merchantID)
+	ret0, _ := ret[0].(*domain.Merchant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMerchantByMerchantID indicates an expected call of GetMerchantByMerchantID.
+func (mr *MockStoreMockRecorder) GetMerchantByMerchantID(ctx, merchantID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMerchantByMerchantID", reflect.TypeOf((*MockStore)(nil).GetMerchantByMerchantID), ctx, merchantID)
+}
+
+// LinkTraderAccount mocks base method.
+func (m *MockStore) LinkTraderAccount(ctx context.Context, actor, merchantID, traiderAccountID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkTraderAccount", ctx, actor, merchantID, traiderAccountID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkTraderAccount indicates an expected call of LinkTraderAccount.
+func (mr *MockStoreMockRecorder) LinkTraderAccount(ctx, actor, merchantID, traiderAccountID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkTraderAccount", reflect.TypeOf((*MockStore)(nil).LinkTraderAccount), ctx, actor, merchantID, traiderAccountID)
+}
+
+// ListAuditLog mocks base method.
+func (m *MockStore) ListAuditLog(ctx context.Context, entityType, entityID string, limit int) ([]*domain.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditLog", ctx, entityType, entityID, limit)
+	ret0, _ := ret[0].([]*domain.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditLog indicates an expected call of ListAuditLog.
+func (mr *MockStoreMockRecorder) ListAuditLog(ctx, entityType, entityID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditLog", reflect.TypeOf((*MockStore)(nil).ListAuditLog), ctx, entityType, entityID, limit)
+}
+
+// ListMerchantTraderAccounts mocks base method.
+func (m *MockStore) ListMerchantTraderAccounts(ctx context.Context, merchantID string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMerchantTraderAccounts", ctx, merchantID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMerchantTraderAccounts indicates an expected call of ListMerchantTraderAccounts.
+func (mr *MockStoreMockRecorder) ListMerchantTraderAccounts(ctx, merchantID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMerchantTraderAccounts", reflect.TypeOf((*MockStore)(nil).ListMerchantTraderAccounts), ctx, merchantID)
+}
+
+// ListMerchants mocks base method.
+func (m *MockStore) ListMerchants(ctx context.Context) ([]*domain.Merchant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMerchants", ctx)
+	ret0, _ := ret[0].([]*domain.Merchant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMerchants indicates an expected call of ListMerchants.
+func (mr *MockStoreMockRecorder) ListMerchants(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMerchants", reflect.TypeOf((*MockStore)(nil).ListMerchants), ctx)
+}
+
+// ListPayerBlocks mocks base method.
+func (m *MockStore) ListPayerBlocks(ctx context.Context, scope domain.PayerBlockScope, scopeID string) ([]*domain.PayerBlock, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPayerBlocks", ctx, scope, scopeID)
+	ret0, _ := ret[0].([]*domain.PayerBlock)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPayerBlocks indicates an expected call of ListPayerBlocks.
+func (mr *MockStoreMockRecorder) ListPayerBlocks(ctx, scope, scopeID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPayerBlocks", reflect.TypeOf((*MockStore)(nil).ListPayerBlocks), ctx, scope, scopeID)
+}
+
+// SetBlock mocks base method.
+func (m *MockStore) SetBlock(ctx context.Context, actor string, block *domain.Block) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetBlock", ctx, actor, block)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetBlock indicates an expected call of SetBlock.
+func (mr *MockStoreMockRecorder) SetBlock(ctx, actor, block any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBlock", reflect.TypeOf((*MockStore)(nil).SetBlock), ctx, actor, block)
+}
+
+// SetPayerBlock mocks base method.
+func (m *MockStore) SetPayerBlock(ctx context.Context, actor string, block *domain.PayerBlock) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPayerBlock", ctx, actor, block)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPayerBlock indicates an expected call of SetPayerBlock.
+func (mr *MockStoreMockRecorder) SetPayerBlock(ctx, actor, block any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPayerBlock", reflect.TypeOf((*MockStore)(nil).SetPayerBlock), ctx, actor, block)
+}
+
+// UnlinkTraderAccount mocks base method.
+func (m *MockStore) UnlinkTraderAccount(ctx context.Context, actor, merchantID, traiderAccountID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlinkTraderAccount", ctx, actor, merchantID, traiderAccountID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlinkTraderAccount indicates an expected call of UnlinkTraderAccount.
+func (mr *MockStoreMockRecorder) UnlinkTraderAccount(ctx, actor, merchantID, traiderAccountID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlinkTraderAccount", reflect.TypeOf((*MockStore)(nil).UnlinkTraderAccount), ctx, actor, merchantID, traiderAccountID)
+}
+
+// UpdateMerchant mocks base method.
+func (m *MockStore) UpdateMerchant(ctx context.Context, actor, merchantID string, patch domain.MerchantPatch) (*domain.Merchant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMerchant", ctx, actor, merchantID, patch)
+	ret0, _ := ret[0].(*domain.Merchant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateMerchant indicates an expected call of UpdateMerchant.
+func (mr *MockStoreMockRecorder) UpdateMerchant(ctx, actor, merchantID, patch any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMerchant", reflect.TypeOf((*MockStore)(nil).UpdateMerchant), ctx, actor, merchantID, patch)
+}
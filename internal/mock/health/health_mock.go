@@ -0,0 +1,186 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/service/health/health.go
+//
+// Generated by this command:
+//
+//	mockgen -destination ./internal/mock/health/health_mock.go --source ./internal/service/health/health.go Store,Schema,Redis,Breaker
+//
+
+// Package mock_health is a generated GoMock package.
+package mock_health
+
+import (
+	context "context"
+	cache "mateo/internal/cache"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockStore is a mock of Store interface.
+type MockStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockStoreMockRecorder is the mock recorder for MockStore.
+type MockStoreMockRecorder struct {
+	mock *MockStore
+}
+
+// NewMockStore creates a new mock instance.
+func NewMockStore(ctrl *gomock.Controller) *MockStore {
+	mock := &MockStore{ctrl: ctrl}
+	mock.recorder = &MockStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStore) EXPECT() *MockStoreMockRecorder {
+	return m.recorder
+}
+
+// GetExchangeRateUpdatedAt mocks base method.
+func (m *MockStore) GetExchangeRateUpdatedAt(ctx context.Context) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExchangeRateUpdatedAt", ctx)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExchangeRateUpdatedAt indicates an expected call of GetExchangeRateUpdatedAt.
+func (mr *MockStoreMockRecorder) GetExchangeRateUpdatedAt(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExchangeRateUpdatedAt", reflect.TypeOf((*MockStore)(nil).GetExchangeRateUpdatedAt), ctx)
+}
+
+// Ping mocks base method.
+func (m *MockStore) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockStoreMockRecorder) Ping(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStore)(nil).Ping), ctx)
+}
+
+// MockSchema is a mock of Schema interface.
+type MockSchema struct {
+	ctrl     *gomock.Controller
+	recorder *MockSchemaMockRecorder
+	isgomock struct{}
+}
+
+// MockSchemaMockRecorder is the mock recorder for MockSchema.
+type MockSchemaMockRecorder struct {
+	mock *MockSchema
+}
+
+// NewMockSchema creates a new mock instance.
+func NewMockSchema(ctrl *gomock.Controller) *MockSchema {
+	mock := &MockSchema{ctrl: ctrl}
+	mock.recorder = &MockSchemaMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSchema) EXPECT() *MockSchemaMockRecorder {
+	return m.recorder
+}
+
+// CheckVersion mocks base method.
+func (m *MockSchema) CheckVersion(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckVersion", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckVersion indicates an expected call of CheckVersion.
+func (mr *MockSchemaMockRecorder) CheckVersion(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckVersion", reflect.TypeOf((*MockSchema)(nil).CheckVersion), ctx)
+}
+
+// MockRedis is a mock of Redis interface.
+type MockRedis struct {
+	ctrl     *gomock.Controller
+	recorder *MockRedisMockRecorder
+	isgomock struct{}
+}
+
+// MockRedisMockRecorder is the mock recorder for MockRedis.
+type MockRedisMockRecorder struct {
+	mock *MockRedis
+}
+
+// NewMockRedis creates a new mock instance.
+func NewMockRedis(ctrl *gomock.Controller) *MockRedis {
+	mock := &MockRedis{ctrl: ctrl}
+	mock.recorder = &MockRedisMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRedis) EXPECT() *MockRedisMockRecorder {
+	return m.recorder
+}
+
+// Ping mocks base method.
+func (m *MockRedis) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockRedisMockRecorder) Ping(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockRedis)(nil).Ping), ctx)
+}
+
+// MockBreaker is a mock of Breaker interface.
+type MockBreaker struct {
+	ctrl     *gomock.Controller
+	recorder *MockBreakerMockRecorder
+	isgomock struct{}
+}
+
+// MockBreakerMockRecorder is the mock recorder for MockBreaker.
+type MockBreakerMockRecorder struct {
+	mock *MockBreaker
+}
+
+// NewMockBreaker creates a new mock instance.
+func NewMockBreaker(ctrl *gomock.Controller) *MockBreaker {
+	mock := &MockBreaker{ctrl: ctrl}
+	mock.recorder = &MockBreakerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBreaker) EXPECT() *MockBreakerMockRecorder {
+	return m.recorder
+}
+
+// State mocks base method.
+func (m *MockBreaker) State() cache.BreakerState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "State")
+	ret0, _ := ret[0].(cache.BreakerState)
+	return ret0
+}
+
+// State indicates an expected call of State.
+func (mr *MockBreakerMockRecorder) State() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "State", reflect.TypeOf((*MockBreaker)(nil).State))
+}
@@ -13,6 +13,7 @@ import (
 	context "context"
 	domain "mateo/internal/domain"
 	reflect "reflect"
+	time "time"
 
 	decimal "github.com/shopspring/decimal"
 	gomock "go.uber.org/mock/gomock"
@@ -57,6 +58,21 @@ func (mr *MockStoreMockRecorder) CreateInvoice(ctx, invoice any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInvoice", reflect.TypeOf((*MockStore)(nil).CreateInvoice), ctx, invoice)
 }
 
+// FinalizeInvoice mocks base method.
+func (m *MockStore) FinalizeInvoice(ctx context.Context, invoiceID string, status domain.InvoiceStatus) (*domain.Invoice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinalizeInvoice", ctx, invoiceID, status)
+	ret0, _ := ret[0].(*domain.Invoice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FinalizeInvoice indicates an expected call of FinalizeInvoice.
+func (mr *MockStoreMockRecorder) FinalizeInvoice(ctx, invoiceID, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinalizeInvoice", reflect.TypeOf((*MockStore)(nil).FinalizeInvoice), ctx, invoiceID, status)
+}
+
 // GetExchangeRate mocks base method.
 func (m *MockStore) GetExchangeRate(ctx context.Context) (decimal.Decimal, error) {
 	m.ctrl.T.Helper()
@@ -71,3 +87,54 @@ func (mr *MockStoreMockRecorder) GetExchangeRate(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExchangeRate", reflect.TypeOf((*MockStore)(nil).GetExchangeRate), ctx)
 }
+
+// SelectExpiredInvoiceIDs mocks base method.
+func (m *MockStore) SelectExpiredInvoiceIDs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectExpiredInvoiceIDs", ctx, now, limit)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectExpiredInvoiceIDs indicates an expected call of SelectExpiredInvoiceIDs.
+func (mr *MockStoreMockRecorder) SelectExpiredInvoiceIDs(ctx, now, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectExpiredInvoiceIDs", reflect.TypeOf((*MockStore)(nil).SelectExpiredInvoiceIDs), ctx, now, limit)
+}
+
+// MockObserver is a mock of Observer interface.
+type MockObserver struct {
+	ctrl     *gomock.Controller
+	recorder *MockObserverMockRecorder
+	isgomock struct{}
+}
+
+// MockObserverMockRecorder is the mock recorder for MockObserver.
+type MockObserverMockRecorder struct {
+	mock *MockObserver
+}
+
+// NewMockObserver creates a new mock instance.
+func NewMockObserver(ctrl *gomock.Controller) *MockObserver {
+	mock := &MockObserver{ctrl: ctrl}
+	mock.recorder = &MockObserverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockObserver) EXPECT() *MockObserverMockRecorder {
+	return m.recorder
+}
+
+// InvoiceFinalized mocks base method.
+func (m *MockObserver) InvoiceFinalized(ctx context.Context, invoice *domain.Invoice) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "InvoiceFinalized", ctx, invoice)
+}
+
+// InvoiceFinalized indicates an expected call of InvoiceFinalized.
+func (mr *MockObserverMockRecorder) InvoiceFinalized(ctx, invoice any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvoiceFinalized", reflect.TypeOf((*MockObserver)(nil).InvoiceFinalized), ctx, invoice)
+}
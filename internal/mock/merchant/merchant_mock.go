@@ -55,3 +55,18 @@ func (mr *MockStoreMockRecorder) GetMerchantByMerchantID(ctx, merchantID any) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMerchantByMerchantID", reflect.TypeOf((*MockStore)(nil).GetMerchantByMerchantID), ctx, merchantID)
 }
+
+// GetMerchantDailyTurnover mocks base method.
+func (m *MockStore) GetMerchantDailyTurnover(ctx context.Context, merchantID string) (domain.MerchantTurnover, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMerchantDailyTurnover", ctx, merchantID)
+	ret0, _ := ret[0].(domain.MerchantTurnover)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMerchantDailyTurnover indicates an expected call of GetMerchantDailyTurnover.
+func (mr *MockStoreMockRecorder) GetMerchantDailyTurnover(ctx, merchantID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMerchantDailyTurnover", reflect.TypeOf((*MockStore)(nil).GetMerchantDailyTurnover), ctx, merchantID)
+}
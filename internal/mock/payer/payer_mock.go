@@ -0,0 +1,73 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/service/payer/payer.go
+//
+// Generated by this command:
+//
+//	mockgen -destination ./internal/mock/payer/payer_mock.go --source ./internal/service/payer/payer.go Store
+//
+
+// Package mock_payer is a generated GoMock package.
+package mock_payer
+
+import (
+	context "context"
+	domain "mateo/internal/domain"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockStore is a mock of Store interface.
+type MockStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockStoreMockRecorder is the mock recorder for MockStore.
+type MockStoreMockRecorder struct {
+	mock *MockStore
+}
+
+// NewMockStore creates a new mock instance.
+func NewMockStore(ctrl *gomock.Controller) *MockStore {
+	mock := &MockStore{ctrl: ctrl}
+	mock.recorder = &MockStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStore) EXPECT() *MockStoreMockRecorder {
+	return m.recorder
+}
+
+// GetPayerActivity mocks base method.
+func (m *MockStore) GetPayerActivity(ctx context.Context, merchantID, payerID string, since time.Time) (domain.PayerActivity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPayerActivity", ctx, merchantID, payerID, since)
+	ret0, _ := ret[0].(domain.PayerActivity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPayerActivity indicates an expected call of GetPayerActivity.
+func (mr *MockStoreMockRecorder) GetPayerActivity(ctx, merchantID, payerID, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPayerActivity", reflect.TypeOf((*MockStore)(nil).GetPayerActivity), ctx, merchantID, payerID, since)
+}
+
+// IsPayerBlocked mocks base method.
+func (m *MockStore) IsPayerBlocked(ctx context.Context, merchantID, payerID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsPayerBlocked", ctx, merchantID, payerID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsPayerBlocked indicates an expected call of IsPayerBlocked.
+func (mr *MockStoreMockRecorder) IsPayerBlocked(ctx, merchantID, payerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsPayerBlocked", reflect.TypeOf((*MockStore)(nil).IsPayerBlocked), ctx, merchantID, payerID)
+}
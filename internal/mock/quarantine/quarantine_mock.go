@@ -0,0 +1,155 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/service/quarantine/quarantine.go
+//
+// Generated by this command:
+//
+//	mockgen -destination ./internal/mock/quarantine/quarantine_mock.go --source ./internal/service/quarantine/quarantine.go Store,Notifier
+//
+
+// Package mock_quarantine is a generated GoMock package.
+package mock_quarantine
+
+import (
+	context "context"
+	domain "mateo/internal/domain"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockStore is a mock of Store interface.
+type MockStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockStoreMockRecorder is the mock recorder for MockStore.
+type MockStoreMockRecorder struct {
+	mock *MockStore
+}
+
+// NewMockStore creates a new mock instance.
+func NewMockStore(ctrl *gomock.Controller) *MockStore {
+	mock := &MockStore{ctrl: ctrl}
+	mock.recorder = &MockStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStore) EXPECT() *MockStoreMockRecorder {
+	return m.recorder
+}
+
+// MarkNotificationSent mocks base method.
+func (m *MockStore) MarkNotificationSent(ctx context.Context, notificationID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkNotificationSent", ctx, notificationID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkNotificationSent indicates an expected call of MarkNotificationSent.
+func (mr *MockStoreMockRecorder) MarkNotificationSent(ctx, notificationID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkNotificationSent", reflect.TypeOf((*MockStore)(nil).MarkNotificationSent), ctx, notificationID)
+}
+
+// QuarantineEntity mocks base method.
+func (m *MockStore) QuarantineEntity(ctx context.Context, quarantine domain.Quarantine) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuarantineEntity", ctx, quarantine)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QuarantineEntity indicates an expected call of QuarantineEntity.
+func (mr *MockStoreMockRecorder) QuarantineEntity(ctx, quarantine any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuarantineEntity", reflect.TypeOf((*MockStore)(nil).QuarantineEntity), ctx, quarantine)
+}
+
+// SelectOutcomeStats mocks base method.
+func (m *MockStore) SelectOutcomeStats(ctx context.Context, entity domain.BlockEntity, entityID string, since time.Time) (domain.OutcomeStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectOutcomeStats", ctx, entity, entityID, since)
+	ret0, _ := ret[0].(domain.OutcomeStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectOutcomeStats indicates an expected call of SelectOutcomeStats.
+func (mr *MockStoreMockRecorder) SelectOutcomeStats(ctx, entity, entityID, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectOutcomeStats", reflect.TypeOf((*MockStore)(nil).SelectOutcomeStats), ctx, entity, entityID, since)
+}
+
+// SelectPendingNotifications mocks base method.
+func (m *MockStore) SelectPendingNotifications(ctx context.Context, limit int) ([]*domain.TeamNotification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectPendingNotifications", ctx, limit)
+	ret0, _ := ret[0].([]*domain.TeamNotification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectPendingNotifications indicates an expected call of SelectPendingNotifications.
+func (mr *MockStoreMockRecorder) SelectPendingNotifications(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectPendingNotifications", reflect.TypeOf((*MockStore)(nil).SelectPendingNotifications), ctx, limit)
+}
+
+// SelectRecentOutcomes mocks base method.
+func (m *MockStore) SelectRecentOutcomes(ctx context.Context, entity domain.BlockEntity, entityID string, limit int) ([]domain.InvoiceStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectRecentOutcomes", ctx, entity, entityID, limit)
+	ret0, _ := ret[0].([]domain.InvoiceStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectRecentOutcomes indicates an expected call of SelectRecentOutcomes.
+func (mr *MockStoreMockRecorder) SelectRecentOutcomes(ctx, entity, entityID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectRecentOutcomes", reflect.TypeOf((*MockStore)(nil).SelectRecentOutcomes), ctx, entity, entityID, limit)
+}
+
+// MockNotifier is a mock of Notifier interface.
+type MockNotifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotifierMockRecorder
+	isgomock struct{}
+}
+
+// MockNotifierMockRecorder is the mock recorder for MockNotifier.
+type MockNotifierMockRecorder struct {
+	mock *MockNotifier
+}
+
+// NewMockNotifier creates a new mock instance.
+func NewMockNotifier(ctrl *gomock.Controller) *MockNotifier {
+	mock := &MockNotifier{ctrl: ctrl}
+	mock.recorder = &MockNotifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotifier) EXPECT() *MockNotifierMockRecorder {
+	return m.recorder
+}
+
+// Send mocks base method.
+func (m *MockNotifier) Send(ctx context.Context, notification *domain.TeamNotification) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", ctx, notification)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockNotifierMockRecorder) Send(ctx, notification any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockNotifier)(nil).Send), ctx, notification)
+}
@@ -58,31 +58,31 @@ func (mr *MockStoreMockRecorder) GetBoostedTeamIds(ctx any) *gomock.Call {
 }
 
 // SelectAvailableRequisites mocks base method.
-func (m *MockStore) SelectAvailableRequisites(ctx context.Context, merchantID string, amount decimal.Decimal, requisiteType domain.RequisiteType, bankId string) ([]*domain.Requisite, error) {
+func (m *MockStore) SelectAvailableRequisites(ctx context.Context, merchantID string, amount decimal.Decimal, requisiteType domain.RequisiteType, banks domain.BankPreference, payerID string) ([]*domain.Requisite, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SelectAvailableRequisites", ctx, merchantID, amount, requisiteType, bankId)
+	ret := m.ctrl.Call(m, "SelectAvailableRequisites", ctx, merchantID, amount, requisiteType, banks, payerID)
 	ret0, _ := ret[0].([]*domain.Requisite)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SelectAvailableRequisites indicates an expected call of SelectAvailableRequisites.
-func (mr *MockStoreMockRecorder) SelectAvailableRequisites(ctx, merchantID, amount, requisiteType, bankId any) *gomock.Call {
+func (mr *MockStoreMockRecorder) SelectAvailableRequisites(ctx, merchantID, amount, requisiteType, banks, payerID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectAvailableRequisites", reflect.TypeOf((*MockStore)(nil).SelectAvailableRequisites), ctx, merchantID, amount, requisiteType, bankId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectAvailableRequisites", reflect.TypeOf((*MockStore)(nil).SelectAvailableRequisites), ctx, merchantID, amount, requisiteType, banks, payerID)
 }
 
 // SelectAvailableRequisitesFlexible mocks base method.
-func (m *MockStore) SelectAvailableRequisitesFlexible(ctx context.Context, merchantID string, flexibleAmountMin, flexibleAmountMax, flexibleAmountStep decimal.Decimal, requisiteType domain.RequisiteType, bankId string) ([]*domain.Requisite, error) {
+func (m *MockStore) SelectAvailableRequisitesFlexible(ctx context.Context, merchantID string, flexibleAmountMin, flexibleAmountMax, flexibleAmountStep decimal.Decimal, requisiteType domain.RequisiteType, banks domain.BankPreference, payerID string) ([]*domain.Requisite, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SelectAvailableRequisitesFlexible", ctx, merchantID, flexibleAmountMin, flexibleAmountMax, flexibleAmountStep, requisiteType, bankId)
+	ret := m.ctrl.Call(m, "SelectAvailableRequisitesFlexible", ctx, merchantID, flexibleAmountMin, flexibleAmountMax, flexibleAmountStep, requisiteType, banks, payerID)
 	ret0, _ := ret[0].([]*domain.Requisite)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SelectAvailableRequisitesFlexible indicates an expected call of SelectAvailableRequisitesFlexible.
-func (mr *MockStoreMockRecorder) SelectAvailableRequisitesFlexible(ctx, merchantID, flexibleAmountMin, flexibleAmountMax, flexibleAmountStep, requisiteType, bankId any) *gomock.Call {
+func (mr *MockStoreMockRecorder) SelectAvailableRequisitesFlexible(ctx, merchantID, flexibleAmountMin, flexibleAmountMax, flexibleAmountStep, requisiteType, banks, payerID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectAvailableRequisitesFlexible", reflect.TypeOf((*MockStore)(nil).SelectAvailableRequisitesFlexible), ctx, merchantID, flexibleAmountMin, flexibleAmountMax, flexibleAmountStep, requisiteType, bankId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectAvailableRequisitesFlexible", reflect.TypeOf((*MockStore)(nil).SelectAvailableRequisitesFlexible), ctx, merchantID, flexibleAmountMin, flexibleAmountMax, flexibleAmountStep, requisiteType, banks, payerID)
 }
@@ -0,0 +1,140 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/service/sandbox/sandbox.go
+//
+// Generated by this command:
+//
+//	mockgen -destination ./internal/mock/sandbox/sandbox_mock.go --source ./internal/service/sandbox/sandbox.go Store,Notifier
+//
+
+// Package mock_sandbox is a generated GoMock package.
+package mock_sandbox
+
+import (
+	context "context"
+	domain "mateo/internal/domain"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockStore is a mock of Store interface.
+type MockStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockStoreMockRecorder is the mock recorder for MockStore.
+type MockStoreMockRecorder struct {
+	mock *MockStore
+}
+
+// NewMockStore creates a new mock instance.
+func NewMockStore(ctrl *gomock.Controller) *MockStore {
+	mock := &MockStore{ctrl: ctrl}
+	mock.recorder = &MockStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStore) EXPECT() *MockStoreMockRecorder {
+	return m.recorder
+}
+
+// AppealInvoice mocks base method.
+func (m *MockStore) AppealInvoice(ctx context.Context, invoiceID string) (*domain.Invoice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AppealInvoice", ctx, invoiceID)
+	ret0, _ := ret[0].(*domain.Invoice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AppealInvoice indicates an expected call of AppealInvoice.
+func (mr *MockStoreMockRecorder) AppealInvoice(ctx, invoiceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppealInvoice", reflect.TypeOf((*MockStore)(nil).AppealInvoice), ctx, invoiceID)
+}
+
+// FinalizeInvoice mocks base method.
+func (m *MockStore) FinalizeInvoice(ctx context.Context, invoiceID string, status domain.InvoiceStatus) (*domain.Invoice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinalizeInvoice", ctx, invoiceID, status)
+	ret0, _ := ret[0].(*domain.Invoice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FinalizeInvoice indicates an expected call of FinalizeInvoice.
+func (mr *MockStoreMockRecorder) FinalizeInvoice(ctx, invoiceID, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinalizeInvoice", reflect.TypeOf((*MockStore)(nil).FinalizeInvoice), ctx, invoiceID, status)
+}
+
+// GetInvoice mocks base method.
+func (m *MockStore) GetInvoice(ctx context.Context, invoiceID string) (*domain.Invoice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInvoice", ctx, invoiceID)
+	ret0, _ := ret[0].(*domain.Invoice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInvoice indicates an expected call of GetInvoice.
+func (mr *MockStoreMockRecorder) GetInvoice(ctx, invoiceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvoice", reflect.TypeOf((*MockStore)(nil).GetInvoice), ctx, invoiceID)
+}
+
+// PruneInvoices mocks base method.
+func (m *MockStore) PruneInvoices(ctx context.Context, before time.Time) int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PruneInvoices", ctx, before)
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// PruneInvoices indicates an expected call of PruneInvoices.
+func (mr *MockStoreMockRecorder) PruneInvoices(ctx, before any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneInvoices", reflect.TypeOf((*MockStore)(nil).PruneInvoices), ctx, before)
+}
+
+// MockNotifier is a mock of Notifier interface.
+type MockNotifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotifierMockRecorder
+	isgomock struct{}
+}
+
+// MockNotifierMockRecorder is the mock recorder for MockNotifier.
+type MockNotifierMockRecorder struct {
+	mock *MockNotifier
+}
+
+// NewMockNotifier creates a new mock instance.
+func NewMockNotifier(ctrl *gomock.Controller) *MockNotifier {
+	mock := &MockNotifier{ctrl: ctrl}
+	mock.recorder = &MockNotifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotifier) EXPECT() *MockNotifierMockRecorder {
+	return m.recorder
+}
+
+// Send mocks base method.
+func (m *MockNotifier) Send(ctx context.Context, invoice *domain.Invoice) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", ctx, invoice)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockNotifierMockRecorder) Send(ctx, invoice any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockNotifier)(nil).Send), ctx, invoice)
+}
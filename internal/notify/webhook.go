@@ -0,0 +1,72 @@
+// Package notify доставляет уведомления команд трейдеров во внешнюю систему.
+//
+// Уведомление отправляется POST-запросом с JSON-телом на адрес вебхука. Получатель
+// должен быть идемпотентен по id: после сбоя уведомление отправляется повторно.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"mateo/internal/domain"
+)
+
+var ErrorWebhookRejected = errors.New("team notification rejected by webhook")
+
+// Message тело запроса вебхука
+type Message struct {
+	ID        string          `json:"id"`
+	TeamID    string          `json:"teamId"`
+	Kind      string          `json:"kind"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type Webhook struct {
+	url  string
+	http *http.Client
+}
+
+func NewWebhook(url string, timeout time.Duration) *Webhook {
+	return &Webhook{
+		url:  url,
+		http: &http.Client{Timeout: timeout},
+	}
+}
+
+// Send отправляет уведомление. Ответ не из диапазона 2xx возвращается как ErrorWebhookRejected.
+func (w *Webhook) Send(ctx context.Context, notification *domain.TeamNotification) error {
+	body, err := json.Marshal(Message{
+		ID:        notification.ID,
+		TeamID:    notification.TeamID,
+		Kind:      notification.Kind,
+		Payload:   notification.Payload,
+		CreatedAt: notification.CreatedAt,
+	})
+	if err != nil {
+		return errors.Wrap(err, "marshal team notification")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "build webhook request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.http.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "send webhook")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Wrapf(ErrorWebhookRejected, "status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"mateo/internal/cache"
+)
+
+const bucketKeyPrefix = "ratelimit:"
+
+// takeScript списывает токен из корзины KEYS[1], пополняемой на ARGV[1] токенов в секунду
+// до ARGV[2]. Время берется у Redis, чтобы реплики с расходящимися часами делили одну корзину.
+// Возвращает {1, 0}, если токен списан, иначе {0, миллисекунды до появления токена}.
+var takeScript = redis.NewScript(`
+redis.replicate_commands()
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) + tonumber(clock[2]) / 1000000
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, wait}
+`)
+
+// RedisBuckets корзины токенов в Redis, общие для всех реплик. Запросы идут через автомат
+// кеша: пока Redis недоступен, корзины не опрашиваются.
+type RedisBuckets struct {
+	client  redis.Scripter
+	breaker *cache.Breaker
+	timeout time.Duration
+}
+
+// NewRedisBuckets создает корзины; timeout ограничивает ожидание Redis одним запросом
+func NewRedisBuckets(client redis.Scripter, breaker *cache.Breaker, timeout time.Duration) *RedisBuckets {
+	return &RedisBuckets{client: client, breaker: breaker, timeout: timeout}
+}
+
+func (b *RedisBuckets) Take(
+	ctx context.Context,
+	key string,
+	rate float64,
+	burst int,
+) (bool, time.Duration, error) {
+	var result []int64
+	err := b.breaker.Do(ctx, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, b.timeout)
+		defer cancel()
+
+		var err error
+		result, err = takeScript.Run(ctx, b.client, []string{bucketKeyPrefix + key}, rate, burst).Int64Slice()
+		return err
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	if len(result) != 2 {
+		return false, 0, errors.Errorf("unexpected token bucket reply %v", result)
+	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
@@ -0,0 +1,138 @@
+// Package ratelimit ограничивает запросы создания Invoice одного мерчанта: частоту — корзиной
+// токенов в Redis, общей для реплик, и число одновременных запросов — в памяти реплики.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"mateo/internal/cache"
+	"mateo/internal/domain"
+)
+
+// concurrencyRetryAfter что отвечать в Retry-After при превышении числа одновременных запросов
+const concurrencyRetryAfter = time.Second
+
+// Limits лимиты одного мерчанта. Нулевой лимит не проверяется.
+type Limits struct {
+	// Rate сколько запросов в секунду мерчант может делать в среднем
+	Rate float64
+	// Burst сколько запросов может прийти разом после паузы
+	Burst int
+	// MaxConcurrent сколько запросов мерчанта одна реплика обрабатывает одновременно
+	MaxConcurrent int
+}
+
+// Policy общие лимиты и лимиты отдельных мерчантов
+type Policy struct {
+	Default   Limits
+	Merchants map[string]Limits
+}
+
+// For лимиты мерчанта
+func (p Policy) For(merchantID string) Limits {
+	if limits, ok := p.Merchants[merchantID]; ok {
+		return limits
+	}
+	return p.Default
+}
+
+// Buckets корзины токенов
+type Buckets interface {
+	// Take списывает токен из корзины key; если токена нет, возвращает, через сколько он появится
+	Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error)
+}
+
+// Limiter реализует domain.RateLimiter. При ошибке Redis запрос пропускается: лимитер
+// защищает Postgres и не должен останавливать прием Invoice вместе с Redis.
+type Limiter struct {
+	buckets Buckets
+	policy  atomic.Pointer[Policy]
+	enabled atomic.Bool
+
+	mu       sync.Mutex
+	inflight map[string]int
+}
+
+func New(buckets Buckets, policy Policy) *Limiter {
+	l := &Limiter{buckets: buckets, inflight: make(map[string]int)}
+	l.policy.Store(&policy)
+	l.enabled.Store(true)
+	return l
+}
+
+// SetPolicy меняет лимиты на ходу; занятые места не освобождаются
+func (l *Limiter) SetPolicy(policy Policy) {
+	l.policy.Store(&policy)
+}
+
+// SetEnabled включает и выключает лимиты
+func (l *Limiter) SetEnabled(enabled bool) {
+	l.enabled.Store(enabled)
+}
+
+func (l *Limiter) Acquire(ctx context.Context, merchantID string) (func(), error) {
+	if !l.enabled.Load() {
+		return func() {}, nil
+	}
+	limits := l.policy.Load().For(merchantID)
+
+	// Место занимается до списания токена: отказ по параллельности не тратит токен
+	release, ok := l.acquireSlot(merchantID, limits.MaxConcurrent)
+	if !ok {
+		return nil, &domain.RateLimitError{
+			Err:        domain.ErrorMerchantConcurrencyLimited,
+			RetryAfter: concurrencyRetryAfter,
+		}
+	}
+
+	if limits.Rate <= 0 {
+		return release, nil
+	}
+
+	allowed, retryAfter, err := l.buckets.Take(ctx, merchantID, limits.Rate, limits.Burst)
+	if err != nil {
+		if !errors.Is(err, cache.ErrorUnavailable) && ctx.Err() == nil {
+			log.Ctx(ctx).Warn().Err(err).Str("merchant_id", merchantID).
+				Msg("failed to check merchant rate limit, allowing request")
+		}
+		return release, nil
+	}
+	if !allowed {
+		release()
+		return nil, &domain.RateLimitError{
+			Err:        domain.ErrorMerchantRateLimited,
+			RetryAfter: retryAfter,
+		}
+	}
+	return release, nil
+}
+
+// acquireSlot занимает место среди одновременных запросов мерчанта
+func (l *Limiter) acquireSlot(merchantID string, limit int) (func(), bool) {
+	if limit <= 0 {
+		return func() {}, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inflight[merchantID] >= limit {
+		return nil, false
+	}
+	l.inflight[merchantID]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.inflight[merchantID]--; l.inflight[merchantID] <= 0 {
+				delete(l.inflight, merchantID)
+			}
+		})
+	}, true
+}
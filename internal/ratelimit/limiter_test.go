@@ -0,0 +1,197 @@
+package ratelimit_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mateo/internal/cache"
+	"mateo/internal/domain"
+	"mateo/internal/fake"
+	"mateo/internal/ratelimit"
+)
+
+// fakeBuckets корзины токенов в памяти по тем же правилам, что и скрипт Redis.
+// Если задан err, Take возвращает его, не трогая корзины.
+type fakeBuckets struct {
+	clock   *fake.Clock
+	err     error
+	calls   int
+	buckets map[string]*fakeBucket
+}
+
+type fakeBucket struct {
+	tokens float64
+	ts     time.Time
+}
+
+func newFakeBuckets(clock *fake.Clock) *fakeBuckets {
+	return &fakeBuckets{clock: clock, buckets: make(map[string]*fakeBucket)}
+}
+
+func (b *fakeBuckets) Take(_ context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	b.calls++
+	if b.err != nil {
+		return false, 0, b.err
+	}
+
+	now := b.clock.Now()
+	bucket, ok := b.buckets[key]
+	if !ok {
+		bucket = &fakeBucket{tokens: float64(burst), ts: now}
+		b.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(float64(burst), bucket.tokens+math.Max(0, now.Sub(bucket.ts).Seconds())*rate)
+	bucket.ts = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0, nil
+	}
+	wait := math.Ceil((1 - bucket.tokens) / rate * 1000)
+	return false, time.Duration(wait) * time.Millisecond, nil
+}
+
+var start = time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC)
+
+func acquire(t *testing.T, limiter *ratelimit.Limiter, merchantID string) (func(), *domain.RateLimitError) {
+	t.Helper()
+
+	release, err := limiter.Acquire(context.Background(), merchantID)
+	if err == nil {
+		require.NotNil(t, release)
+		return release, nil
+	}
+	var rateLimitErr *domain.RateLimitError
+	require.True(t, errors.As(err, &rateLimitErr), "got error %v", err)
+	return nil, rateLimitErr
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := fake.NewClock(start)
+	buckets := newFakeBuckets(clock)
+	limiter := ratelimit.New(buckets, ratelimit.Policy{Default: ratelimit.Limits{Rate: 4, Burst: 2}})
+
+	// Всплеск в пределах burst проходит, следующий запрос ждет токен: 1/4 секунды
+	for i := 0; i < 2; i++ {
+		release, denied := acquire(t, limiter, "merchant-1")
+		require.Nil(t, denied)
+		release()
+	}
+	_, denied := acquire(t, limiter, "merchant-1")
+	require.NotNil(t, denied)
+	assert.True(t, errors.Is(denied, domain.ErrorMerchantRateLimited))
+	assert.Equal(t, 250*time.Millisecond, denied.RetryAfter)
+
+	// Корзины мерчантов независимы
+	_, denied = acquire(t, limiter, "merchant-2")
+	assert.Nil(t, denied)
+
+	clock.Advance(100 * time.Millisecond)
+	_, denied = acquire(t, limiter, "merchant-1")
+	require.NotNil(t, denied)
+	assert.Equal(t, 150*time.Millisecond, denied.RetryAfter)
+
+	clock.Advance(150 * time.Millisecond)
+	_, denied = acquire(t, limiter, "merchant-1")
+	assert.Nil(t, denied)
+}
+
+func TestMerchantPolicy(t *testing.T) {
+	buckets := newFakeBuckets(fake.NewClock(start))
+	limiter := ratelimit.New(buckets, ratelimit.Policy{
+		Default:   ratelimit.Limits{Rate: 1, Burst: 1},
+		Merchants: map[string]ratelimit.Limits{"unlimited": {}},
+	})
+
+	for i := 0; i < 5; i++ {
+		_, denied := acquire(t, limiter, "unlimited")
+		require.Nil(t, denied)
+	}
+	assert.Zero(t, buckets.calls, "merchant without a rate must not touch the buckets")
+
+	_, denied := acquire(t, limiter, "merchant-1")
+	require.Nil(t, denied)
+	_, denied = acquire(t, limiter, "merchant-1")
+	assert.NotNil(t, denied)
+}
+
+func TestConcurrencySlots(t *testing.T) {
+	buckets := newFakeBuckets(fake.NewClock(start))
+	limiter := ratelimit.New(buckets, ratelimit.Policy{Default: ratelimit.Limits{MaxConcurrent: 2}})
+
+	first, denied := acquire(t, limiter, "merchant-1")
+	require.Nil(t, denied)
+	_, denied = acquire(t, limiter, "merchant-1")
+	require.Nil(t, denied)
+
+	_, denied = acquire(t, limiter, "merchant-1")
+	require.NotNil(t, denied)
+	assert.True(t, errors.Is(denied, domain.ErrorMerchantConcurrencyLimited))
+	assert.Equal(t, time.Second, denied.RetryAfter)
+
+	// Повторный release не освобождает чужое место
+	first()
+	first()
+	_, denied = acquire(t, limiter, "merchant-1")
+	require.Nil(t, denied)
+	_, denied = acquire(t, limiter, "merchant-1")
+	assert.NotNil(t, denied)
+}
+
+func TestRateDenialReleasesSlot(t *testing.T) {
+	buckets := newFakeBuckets(fake.NewClock(start))
+	limiter := ratelimit.New(buckets, ratelimit.Policy{Default: ratelimit.Limits{Rate: 1, Burst: 1, MaxConcurrent: 1}})
+
+	release, denied := acquire(t, limiter, "merchant-1")
+	require.Nil(t, denied)
+	release()
+
+	_, denied = acquire(t, limiter, "merchant-1")
+	require.NotNil(t, denied)
+	require.True(t, errors.Is(denied, domain.ErrorMerchantRateLimited))
+
+	// Отказ по частоте вернул место: следующий отказ снова по частоте, а не по параллельности
+	_, denied = acquire(t, limiter, "merchant-1")
+	require.NotNil(t, denied)
+	assert.True(t, errors.Is(denied, domain.ErrorMerchantRateLimited))
+}
+
+func TestFailOpen(t *testing.T) {
+	for _, err := range []error{cache.ErrorUnavailable, errors.New("redis timeout")} {
+		t.Run(err.Error(), func(t *testing.T) {
+			buckets := newFakeBuckets(fake.NewClock(start))
+			buckets.err = err
+			limiter := ratelimit.New(buckets, ratelimit.Policy{Default: ratelimit.Limits{Rate: 1, Burst: 1, MaxConcurrent: 1}})
+
+			release, denied := acquire(t, limiter, "merchant-1")
+			require.Nil(t, denied)
+			assert.Equal(t, 1, buckets.calls)
+
+			// Место среди одновременных запросов занято и без Redis
+			_, denied = acquire(t, limiter, "merchant-1")
+			require.NotNil(t, denied)
+			assert.True(t, errors.Is(denied, domain.ErrorMerchantConcurrencyLimited))
+
+			release()
+			_, denied = acquire(t, limiter, "merchant-1")
+			assert.Nil(t, denied)
+		})
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	buckets := newFakeBuckets(fake.NewClock(start))
+	limiter := ratelimit.New(buckets, ratelimit.Policy{Default: ratelimit.Limits{Rate: 1, Burst: 1, MaxConcurrent: 1}})
+	limiter.SetEnabled(false)
+
+	for i := 0; i < 3; i++ {
+		_, denied := acquire(t, limiter, "merchant-1")
+		require.Nil(t, denied)
+	}
+	assert.Zero(t, buckets.calls)
+}
@@ -0,0 +1,369 @@
+package admin
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+	"regexp"
+	"time"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// idPattern допустимые ID мерчантов и аккаунтов трейдеров
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type Store interface {
+	ListMerchants(ctx context.Context) ([]*domain.Merchant, error)
+
+	GetMerchantByMerchantID(
+		ctx context.Context,
+		merchantID string,
+	) (*domain.Merchant, error)
+
+	// CreateMerchant создает мерчанта; существующий ID возвращает domain.ErrorMerchantAlreadyExists
+	CreateMerchant(ctx context.Context, actor string, merchant *domain.Merchant) error
+
+	// UpdateMerchant применяет patch к мерчанту и возвращает его новое состояние; лимиты, нарушающие
+	// Merchant.ValidateLimits, возвращают domain.ErrorInvalidLimit
+	UpdateMerchant(
+		ctx context.Context,
+		actor string,
+		merchantID string,
+		patch domain.MerchantPatch,
+	) (*domain.Merchant, error)
+
+	// DeleteMerchant удаляет мерчанта без Invoice вместе с его привязками
+	DeleteMerchant(ctx context.Context, actor string, merchantID string) error
+
+	ListMerchantTraderAccounts(ctx context.Context, merchantID string) ([]string, error)
+
+	// LinkTraderAccount привязывает аккаунт трейдера к мерчанту; повторная привязка не ошибка
+	LinkTraderAccount(ctx context.Context, actor string, merchantID string, traiderAccountID string) error
+
+	UnlinkTraderAccount(ctx context.Context, actor string, merchantID string, traiderAccountID string) error
+
+	ListAuditLog(ctx context.Context, entityType string, entityID string, limit int) ([]*domain.AuditEntry, error)
+
+	// GetBlock возвращает сохраненное состояние блокировки; отсутствующая сущность
+	// возвращает domain.ErrorBlockEntityNotFound
+	GetBlock(ctx context.Context, entity domain.BlockEntity, entityID string) (*domain.Block, error)
+
+	// SetBlock сохраняет состояние блокировки сущности и записывает изменение в журнал
+	SetBlock(ctx context.Context, actor string, block *domain.Block) error
+
+	ListPayerBlocks(ctx context.Context, scope domain.PayerBlockScope, scopeID string) ([]*domain.PayerBlock, error)
+
+	// SetPayerBlock добавляет плательщика в список блокировки; владелец списка должен существовать,
+	// иначе возвращается domain.ErrorPayerBlockScopeNotFound
+	SetPayerBlock(ctx context.Context, actor string, block *domain.PayerBlock) error
+
+	DeletePayerBlock(
+		ctx context.Context,
+		actor string,
+		scope domain.PayerBlockScope,
+		scopeID string,
+		payerID string,
+	) error
+}
+
+type Service struct {
+	store Store
+	clock domain.Clock
+}
+
+func NewService(store Store, clock domain.Clock) *Service {
+	return &Service{store: store, clock: clock}
+}
+
+func (s *Service) ListMerchants(ctx context.Context) ([]*domain.Merchant, error) {
+	merchants, err := s.store.ListMerchants(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "list merchants")
+	}
+	return merchants, nil
+}
+
+func (s *Service) GetMerchant(ctx context.Context, merchantID string) (*domain.Merchant, error) {
+	merchant, err := s.store.GetMerchantByMerchantID(ctx, merchantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get merchant by id")
+	}
+	return merchant, nil
+}
+
+func (s *Service) CreateMerchant(ctx context.Context, actor string, merchant *domain.Merchant) (*domain.Merchant, error) {
+	if !idPattern.MatchString(merchant.ID) {
+		return nil, domain.ErrorInvalidMerchantID
+	}
+	if merchant.Status == "" {
+		merchant.Status = domain.MerchantStatusActive
+	}
+	if err := validateMerchantStatus(merchant.Status); err != nil {
+		return nil, err
+	}
+	if err := merchant.ValidateLimits(); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.CreateMerchant(ctx, actor, merchant); err != nil {
+		return nil, errors.Wrap(err, "create merchant")
+	}
+	return merchant, nil
+}
+
+func (s *Service) UpdateMerchant(
+	ctx context.Context,
+	actor string,
+	merchantID string,
+	patch domain.MerchantPatch,
+) (*domain.Merchant, error) {
+	if patch.Status != nil {
+		if err := validateMerchantStatus(*patch.Status); err != nil {
+			return nil, err
+		}
+	}
+	// Соотношение минимальной и максимальной суммы проверяет хранилище после применения patch
+	limits := []*decimal.Decimal{
+		patch.InLimitCard, patch.InLimitWallet, patch.InLimitSBP,
+		patch.InMaxCard, patch.InMaxWallet, patch.InMaxSBP,
+		patch.DailyTurnoverLimit,
+	}
+	for _, limit := range limits {
+		if limit != nil && limit.IsNegative() {
+			return nil, domain.ErrorInvalidLimit
+		}
+	}
+	if patch.DailyInvoiceLimit != nil && *patch.DailyInvoiceLimit < 0 {
+		return nil, domain.ErrorInvalidLimit
+	}
+
+	merchant, err := s.store.UpdateMerchant(ctx, actor, merchantID, patch)
+	if err != nil {
+		return nil, errors.Wrap(err, "update merchant")
+	}
+	return merchant, nil
+}
+
+func validateMerchantStatus(status domain.MerchantStatus) error {
+	switch status {
+	case domain.MerchantStatusActive, domain.MerchantStatusSuspended:
+		return nil
+	default:
+		return domain.ErrorUnknownMerchantStatus
+	}
+}
+
+func (s *Service) DeleteMerchant(ctx context.Context, actor string, merchantID string) error {
+	if err := s.store.DeleteMerchant(ctx, actor, merchantID); err != nil {
+		return errors.Wrap(err, "delete merchant")
+	}
+	return nil
+}
+
+func (s *Service) ListMerchantTraderAccounts(ctx context.Context, merchantID string) ([]string, error) {
+	// Пустой список у несуществующего мерчанта не отличить от мерчанта без привязок
+	if _, err := s.store.GetMerchantByMerchantID(ctx, merchantID); err != nil {
+		return nil, errors.Wrap(err, "get merchant by id")
+	}
+
+	accountIDs, err := s.store.ListMerchantTraderAccounts(ctx, merchantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "list merchant trader accounts")
+	}
+	return accountIDs, nil
+}
+
+func (s *Service) LinkTraderAccount(ctx context.Context, actor string, merchantID string, traiderAccountID string) error {
+	if !idPattern.MatchString(traiderAccountID) {
+		return domain.ErrorInvalidTraderAccountID
+	}
+
+	if err := s.store.LinkTraderAccount(ctx, actor, merchantID, traiderAccountID); err != nil {
+		return errors.Wrap(err, "link trader account")
+	}
+	return nil
+}
+
+func (s *Service) UnlinkTraderAccount(ctx context.Context, actor string, merchantID string, traiderAccountID string) error {
+	if err := s.store.UnlinkTraderAccount(ctx, actor, merchantID, traiderAccountID); err != nil {
+		return errors.Wrap(err, "unlink trader account")
+	}
+	return nil
+}
+
+func (s *Service) ListAuditLog(
+	ctx context.Context,
+	entityType string,
+	entityID string,
+	limit int,
+) ([]*domain.AuditEntry, error) {
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+
+	entries, err := s.store.ListAuditLog(ctx, entityType, entityID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "list audit log")
+	}
+	return entries, nil
+}
+
+func (s *Service) GetBlock(ctx context.Context, entity domain.BlockEntity, entityID string) (*domain.Block, error) {
+	if err := validateBlockEntity(entity); err != nil {
+		return nil, err
+	}
+
+	block, err := s.store.GetBlock(ctx, entity, entityID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get block")
+	}
+	return effectiveBlock(block, s.clock.Now()), nil
+}
+
+func (s *Service) Block(
+	ctx context.Context,
+	actor string,
+	entity domain.BlockEntity,
+	entityID string,
+	reason string,
+	blockedUntil *time.Time,
+) (*domain.Block, error) {
+	if err := validateBlockEntity(entity); err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		return nil, domain.ErrorEmptyBlockReason
+	}
+	if blockedUntil != nil && !blockedUntil.After(s.clock.Now()) {
+		return nil, domain.ErrorInvalidBlockedUntil
+	}
+
+	block := &domain.Block{
+		Entity:       entity,
+		EntityID:     entityID,
+		IsBlocked:    true,
+		Reason:       reason,
+		BlockedUntil: blockedUntil,
+		BlockedBy:    actor,
+	}
+	if err := s.store.SetBlock(ctx, actor, block); err != nil {
+		return nil, errors.Wrap(err, "set block")
+	}
+	return block, nil
+}
+
+func (s *Service) Unblock(
+	ctx context.Context,
+	actor string,
+	entity domain.BlockEntity,
+	entityID string,
+) (*domain.Block, error) {
+	if err := validateBlockEntity(entity); err != nil {
+		return nil, err
+	}
+
+	block := &domain.Block{Entity: entity, EntityID: entityID}
+	if err := s.store.SetBlock(ctx, actor, block); err != nil {
+		return nil, errors.Wrap(err, "set block")
+	}
+	return block, nil
+}
+
+func validateBlockEntity(entity domain.BlockEntity) error {
+	switch entity {
+	case domain.BlockEntityTraderAccount, domain.BlockEntityTerminal, domain.BlockEntityRequisite:
+		return nil
+	default:
+		return domain.ErrorUnknownBlockEntity
+	}
+}
+
+// effectiveBlock показывает истекшую блокировку снятой: в выборе реквизитов она уже не действует
+func effectiveBlock(block *domain.Block, now time.Time) *domain.Block {
+	if block.IsBlocked && !block.ActiveAt(now) {
+		return &domain.Block{Entity: block.Entity, EntityID: block.EntityID}
+	}
+	return block
+}
+
+func (s *Service) ListPayerBlocks(
+	ctx context.Context,
+	scope domain.PayerBlockScope,
+	scopeID string,
+) ([]*domain.PayerBlock, error) {
+	if err := validatePayerBlockScope(scope); err != nil {
+		return nil, err
+	}
+
+	blocks, err := s.store.ListPayerBlocks(ctx, scope, scopeID)
+	if err != nil {
+		return nil, errors.Wrap(err, "list payer blocks")
+	}
+	return blocks, nil
+}
+
+func (s *Service) BlockPayer(
+	ctx context.Context,
+	actor string,
+	scope domain.PayerBlockScope,
+	scopeID string,
+	payerID string,
+	reason string,
+) (*domain.PayerBlock, error) {
+	if err := validatePayerBlockScope(scope); err != nil {
+		return nil, err
+	}
+	if !domain.PayerIDPattern.MatchString(payerID) {
+		return nil, domain.ErrorInvalidUserID
+	}
+	if reason == "" {
+		return nil, domain.ErrorEmptyBlockReason
+	}
+
+	block := &domain.PayerBlock{
+		Scope:     scope,
+		ScopeID:   scopeID,
+		PayerID:   payerID,
+		Reason:    reason,
+		CreatedBy: actor,
+		CreatedAt: s.clock.Now(),
+	}
+	if err := s.store.SetPayerBlock(ctx, actor, block); err != nil {
+		return nil, errors.Wrap(err, "set payer block")
+	}
+	return block, nil
+}
+
+func (s *Service) UnblockPayer(
+	ctx context.Context,
+	actor string,
+	scope domain.PayerBlockScope,
+	scopeID string,
+	payerID string,
+) error {
+	if err := validatePayerBlockScope(scope); err != nil {
+		return err
+	}
+
+	if err := s.store.DeletePayerBlock(ctx, actor, scope, scopeID, payerID); err != nil {
+		return errors.Wrap(err, "delete payer block")
+	}
+	return nil
+}
+
+func validatePayerBlockScope(scope domain.PayerBlockScope) error {
+	switch scope {
+	case domain.PayerBlockScopeMerchant, domain.PayerBlockScopeTeam:
+		return nil
+	default:
+		return domain.ErrorUnknownPayerBlockScope
+	}
+}
@@ -0,0 +1,173 @@
+package health
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"mateo/internal/cache"
+	"mateo/internal/domain"
+)
+
+type Store interface {
+	Ping(ctx context.Context) error
+	// GetExchangeRateUpdatedAt время последнего изменения курса, минуя кеш
+	GetExchangeRateUpdatedAt(ctx context.Context) (time.Time, error)
+}
+
+// Schema сверяет версию схемы базы с миграциями сервиса
+type Schema interface {
+	CheckVersion(ctx context.Context) error
+}
+
+type Redis interface {
+	Ping(ctx context.Context) error
+}
+
+// Breaker автомат, через который кеш обращается к Redis
+type Breaker interface {
+	State() cache.BreakerState
+}
+
+type Options struct {
+	// CheckTimeout ограничивает каждую проверку
+	CheckTimeout time.Duration
+	// MaxExchangeRateAge возраст курса, после которого сервис не готов; 0 выключает проверку возраста
+	MaxExchangeRateAge time.Duration
+	// RedisOptional недоступный Redis не делает сервис неготовым
+	RedisOptional bool
+}
+
+type Service struct {
+	store   Store
+	schema  Schema
+	redis   Redis
+	breaker Breaker
+	clock   domain.Clock
+	options atomic.Pointer[Options]
+
+	draining atomic.Bool
+}
+
+func NewService(
+	store Store,
+	schema Schema,
+	redis Redis,
+	breaker Breaker,
+	clock domain.Clock,
+	options Options,
+) *Service {
+	s := &Service{
+		store:   store,
+		schema:  schema,
+		redis:   redis,
+		breaker: breaker,
+		clock:   clock,
+	}
+	s.options.Store(&options)
+	return s
+}
+
+// SetOptions меняет параметры проверок на ходу
+func (s *Service) SetOptions(options Options) {
+	s.options.Store(&options)
+}
+
+// Drain переводит сервис в неготовые перед остановкой, чтобы балансировщик
+// перестал присылать запросы до закрытия соединений
+func (s *Service) Drain() {
+	s.draining.Store(true)
+}
+
+// Readiness проверяет Postgres, версию схемы, Redis и возраст курса
+func (s *Service) Readiness(ctx context.Context) domain.HealthReport {
+	if s.draining.Load() {
+		return domain.HealthReport{
+			Checks: []domain.HealthCheck{{
+				Name:   "shutdown",
+				Status: domain.HealthStatusFailed,
+				Error:  "shutting down",
+			}},
+		}
+	}
+
+	checks := []domain.HealthCheck{
+		s.check(ctx, "postgres", s.checkPostgres),
+		s.check(ctx, "schema", s.checkSchema),
+		s.check(ctx, "redis", s.checkRedis),
+		s.check(ctx, "exchange_rate", s.checkExchangeRate),
+	}
+
+	report := domain.HealthReport{Ready: true, Checks: checks}
+	for _, check := range checks {
+		if check.Status == domain.HealthStatusFailed {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+func (s *Service) check(
+	ctx context.Context,
+	name string,
+	fn func(ctx context.Context) domain.HealthCheck,
+) domain.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, s.options.Load().CheckTimeout)
+	defer cancel()
+
+	check := fn(ctx)
+	check.Name = name
+	return check
+}
+
+func (s *Service) checkPostgres(ctx context.Context) domain.HealthCheck {
+	if err := s.store.Ping(ctx); err != nil {
+		return failed(err)
+	}
+	return domain.HealthCheck{Status: domain.HealthStatusOK}
+}
+
+func (s *Service) checkSchema(ctx context.Context) domain.HealthCheck {
+	if err := s.schema.CheckVersion(ctx); err != nil {
+		return failed(err)
+	}
+	return domain.HealthCheck{Status: domain.HealthStatusOK}
+}
+
+func (s *Service) checkRedis(ctx context.Context) domain.HealthCheck {
+	check := domain.HealthCheck{
+		Status:  domain.HealthStatusOK,
+		Details: map[string]string{"breaker": string(s.breaker.State())},
+	}
+
+	if err := s.redis.Ping(ctx); err != nil {
+		check.Status = domain.HealthStatusFailed
+		if s.options.Load().RedisOptional {
+			check.Status = domain.HealthStatusDegraded
+		}
+		check.Error = err.Error()
+	}
+	return check
+}
+
+func (s *Service) checkExchangeRate(ctx context.Context) domain.HealthCheck {
+	updatedAt, err := s.store.GetExchangeRateUpdatedAt(ctx)
+	if err != nil {
+		return failed(err)
+	}
+
+	age := s.clock.Now().Sub(updatedAt).Truncate(time.Second)
+	check := domain.HealthCheck{
+		Status:  domain.HealthStatusOK,
+		Details: map[string]string{"age": age.String()},
+	}
+	if maxAge := s.options.Load().MaxExchangeRateAge; maxAge > 0 && age > maxAge {
+		check.Status = domain.HealthStatusFailed
+		check.Error = "exchange rate is older than " + maxAge.String()
+	}
+	return check
+}
+
+func failed(err error) domain.HealthCheck {
+	return domain.HealthCheck{Status: domain.HealthStatusFailed, Error: err.Error()}
+}
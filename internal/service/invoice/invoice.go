@@ -3,13 +3,16 @@ package invoice
 import (
 	"context"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 	"github.com/shopspring/decimal"
 	"mateo/internal/domain"
+	"sync/atomic"
 	"time"
 )
 
 const (
-	defaultActiveTime = time.Minute * 15
+	// expireBatchSize ограничивает число Invoice, закрываемых за один проход
+	expireBatchSize = 500
 )
 
 type Store interface {
@@ -21,14 +24,57 @@ type Store interface {
 
 	// GetExchangeRate возвращает курс RUB/USD
 	GetExchangeRate(ctx context.Context) (decimal.Decimal, error)
+
+	// FinalizeInvoice переводит активный Invoice в финальный статус и закрывает hold на кошельке
+	FinalizeInvoice(
+		ctx context.Context,
+		invoiceID string,
+		status domain.InvoiceStatus,
+	) (*domain.Invoice, error)
+
+	// SelectExpiredInvoiceIDs возвращает ID активных Invoice с истекшим временем оплаты
+	SelectExpiredInvoiceIDs(ctx context.Context, now time.Time, limit int) ([]string, error)
+}
+
+// Observer получает Invoice, закрытые сервисом
+type Observer interface {
+	InvoiceFinalized(ctx context.Context, invoice *domain.Invoice)
 }
 
 type Service struct {
-	store Store
+	store      Store
+	clock      domain.Clock
+	defaultTTL atomic.Int64
+	observers  []Observer
+}
+
+func NewService(store Store, clock domain.Clock) *Service {
+	s := &Service{store: store, clock: clock}
+	s.SetDefaultTTL(15 * time.Minute)
+	return s
+}
+
+// WithDefaultTTL задает время жизни Invoice, для которых мерчант не передал activeTime
+func (s *Service) WithDefaultTTL(ttl time.Duration) *Service {
+	s.SetDefaultTTL(ttl)
+	return s
 }
 
-func NewService(store Store) *Service {
-	return &Service{store: store}
+// SetDefaultTTL меняет время жизни по умолчанию на ходу; созданные Invoice не меняются
+func (s *Service) SetDefaultTTL(ttl time.Duration) {
+	s.defaultTTL.Store(int64(ttl))
+}
+
+// WithObserver подписывает observer на закрытие Invoice
+func (s *Service) WithObserver(observer Observer) *Service {
+	s.observers = append(s.observers, observer)
+	return s
+}
+
+func (s *Service) notifyFinalized(ctx context.Context, invoice *domain.Invoice) {
+	for _, observer := range s.observers {
+		observer.InvoiceFinalized(ctx, invoice)
+	}
 }
 
 func (s *Service) CreateInvoice(
@@ -39,6 +85,7 @@ func (s *Service) CreateInvoice(
 	callbackURL string,
 	callbackKey string,
 	merchantID string,
+	payerID string,
 	activeTime time.Duration,
 	requisite *domain.Requisite,
 ) (*domain.Invoice, error) {
@@ -48,10 +95,10 @@ func (s *Service) CreateInvoice(
 	}
 
 	if activeTime == 0 {
-		activeTime = defaultActiveTime
+		activeTime = time.Duration(s.defaultTTL.Load())
 	}
 
-	timeExpires := time.Now().Add(activeTime)
+	timeExpires := s.clock.Now().Add(activeTime)
 
 	invoice := &domain.Invoice{
 		Amount:            amount,
@@ -61,6 +108,7 @@ func (s *Service) CreateInvoice(
 		CallbackKey:       callbackKey,
 		IsFlexibleAmount:  isFlexibleAmount,
 		UserID:            requisite.UserID,
+		PayerID:           payerID,
 		MerchantID:        merchantID,
 		BankID:            requisite.BankID,
 		TraiderAccountID:  requisite.TraiderAccountID,
@@ -80,3 +128,70 @@ func (s *Service) CreateInvoice(
 
 	return invoice, nil
 }
+
+// FinalizeInvoice закрывает Invoice: успешная оплата списывает hold с баланса трейдера,
+// истечение и отмена освобождают его
+func (s *Service) FinalizeInvoice(
+	ctx context.Context,
+	invoiceID string,
+	status domain.InvoiceStatus,
+) (*domain.Invoice, error) {
+	if !status.IsFinal() {
+		return nil, domain.ErrorInvalidStatus
+	}
+
+	invoice, err := s.store.FinalizeInvoice(ctx, invoiceID, status)
+	if err != nil {
+		return nil, errors.Wrap(err, "finalize invoice")
+	}
+
+	s.notifyFinalized(ctx, invoice)
+
+	return invoice, nil
+}
+
+// ExpireInvoices закрывает просроченные Invoice и освобождает их hold. Возвращает число закрытых Invoice
+func (s *Service) ExpireInvoices(ctx context.Context) (int, error) {
+	invoiceIDs, err := s.store.SelectExpiredInvoiceIDs(ctx, s.clock.Now(), expireBatchSize)
+	if err != nil {
+		return 0, errors.Wrap(err, "select expired invoices")
+	}
+
+	expired := 0
+	for _, invoiceID := range invoiceIDs {
+		invoice, err := s.store.FinalizeInvoice(ctx, invoiceID, domain.InvoiceStatusExpired)
+		if err != nil {
+			// Invoice мог быть оплачен между выборкой и обновлением
+			if errors.Is(err, domain.ErrorInvoiceNotActive) {
+				continue
+			}
+			return expired, errors.Wrap(err, "expire invoice")
+		}
+		s.notifyFinalized(ctx, invoice)
+		expired++
+	}
+
+	return expired, nil
+}
+
+// RunExpiration периодически закрывает просроченные Invoice, пока не отменен ctx
+func (s *Service) RunExpiration(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := s.ExpireInvoices(ctx)
+			if err != nil {
+				log.Ctx(ctx).Error().Err(err).Msg("failed to expire invoices")
+				continue
+			}
+			if expired > 0 {
+				log.Ctx(ctx).Info().Int("count", expired).Msg("expired invoices released")
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package invoice_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"mateo/internal/domain"
+	"mateo/internal/fake"
+	mock_invoice "mateo/internal/mock/invoice"
+	"mateo/internal/service/invoice"
+	"mateo/internal/store/memory"
+)
+
+var start = time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC)
+
+// recorder запоминает Invoice, о закрытии которых сообщил сервис
+type recorder struct {
+	finalized []*domain.Invoice
+}
+
+func (r *recorder) InvoiceFinalized(_ context.Context, invoice *domain.Invoice) {
+	r.finalized = append(r.finalized, invoice)
+}
+
+// newMemoryService сервис над memory.Store с кошельком, которого хватает на любые суммы теста
+func newMemoryService(t *testing.T, ttl time.Duration) (*invoice.Service, *memory.Store, *fake.Clock, *recorder) {
+	t.Helper()
+
+	clock := fake.NewClock(start)
+	store := memory.NewStore(clock, fake.NewIDGenerator("id"), time.UTC, 24*time.Hour)
+	store.PutWallet(memory.Wallet{ID: "wallet-1", PayInBalance: decimal.NewFromInt(100000)})
+	store.PutTraderAccount(memory.TraderAccount{ID: "account-1", WalletID: "wallet-1", IsCanWork: true})
+	store.SetExchangeRate(decimal.NewFromInt(90))
+
+	observer := &recorder{}
+	service := invoice.NewService(store, clock).WithDefaultTTL(ttl).WithObserver(observer)
+	return service, store, clock, observer
+}
+
+func createInvoice(t *testing.T, service *invoice.Service) *domain.Invoice {
+	t.Helper()
+
+	created, err := service.CreateInvoice(
+		context.Background(),
+		decimal.NewFromInt(500),
+		false,
+		"request-1",
+		"https://merchant.example/callback",
+		"key",
+		"merchant-1",
+		"",
+		0,
+		&domain.Requisite{ID: "requisite-1", TraiderAccountID: "account-1", Type: domain.RequisiteTypeCard},
+	)
+	require.NoError(t, err)
+	return created
+}
+
+func TestExpireInvoicesAtTTL(t *testing.T) {
+	const ttl = 15 * time.Minute
+	service, store, clock, observer := newMemoryService(t, ttl)
+
+	created := createInvoice(t, service)
+	assert.Equal(t, start.Add(ttl), created.TimeExpires)
+
+	// За мгновение до истечения Invoice еще активен
+	clock.Set(created.TimeExpires.Add(-time.Nanosecond))
+	expired, err := service.ExpireInvoices(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, expired)
+
+	// Ровно в момент истечения Invoice закрывается
+	clock.Set(created.TimeExpires)
+	expired, err = service.ExpireInvoices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+
+	stored, ok := store.Invoice(created.ID)
+	require.True(t, ok)
+	assert.Equal(t, domain.InvoiceStatusExpired, stored.Status)
+	require.Len(t, observer.finalized, 1)
+	assert.Equal(t, created.ID, observer.finalized[0].ID)
+
+	// Повторный проход ничего не делает
+	clock.Advance(time.Hour)
+	expired, err = service.ExpireInvoices(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, expired)
+	assert.Len(t, observer.finalized, 1)
+}
+
+func TestExpireInvoicesSkipsFinalized(t *testing.T) {
+	service, store, clock, observer := newMemoryService(t, 15*time.Minute)
+
+	created := createInvoice(t, service)
+	_, err := service.FinalizeInvoice(context.Background(), created.ID, domain.InvoiceStatusSuccess)
+	require.NoError(t, err)
+
+	clock.Set(created.TimeExpires.Add(time.Minute))
+	expired, err := service.ExpireInvoices(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, expired)
+
+	stored, ok := store.Invoice(created.ID)
+	require.True(t, ok)
+	assert.Equal(t, domain.InvoiceStatusSuccess, stored.Status)
+	require.Len(t, observer.finalized, 1)
+	assert.Equal(t, domain.InvoiceStatusSuccess, observer.finalized[0].Status)
+
+	// Закрытый Invoice нельзя закрыть еще раз
+	_, err = service.FinalizeInvoice(context.Background(), created.ID, domain.InvoiceStatusExpired)
+	assert.ErrorIs(t, err, domain.ErrorInvoiceNotActive)
+}
+
+func TestExpireInvoicesRacingPayment(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock_invoice.NewMockStore(ctrl)
+	clock := fake.NewClock(start)
+	observer := &recorder{}
+	service := invoice.NewService(store, clock).WithObserver(observer)
+
+	// invoice-1 оплачен между выборкой и обновлением
+	store.EXPECT().SelectExpiredInvoiceIDs(gomock.Any(), start, gomock.Any()).
+		Return([]string{"invoice-1", "invoice-2"}, nil)
+	store.EXPECT().FinalizeInvoice(gomock.Any(), "invoice-1", domain.InvoiceStatusExpired).
+		Return(nil, domain.ErrorInvoiceNotActive)
+	store.EXPECT().FinalizeInvoice(gomock.Any(), "invoice-2", domain.InvoiceStatusExpired).
+		Return(&domain.Invoice{ID: "invoice-2", Status: domain.InvoiceStatusExpired}, nil)
+
+	expired, err := service.ExpireInvoices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	require.Len(t, observer.finalized, 1)
+	assert.Equal(t, "invoice-2", observer.finalized[0].ID)
+}
+
+func TestExpireInvoicesStopsOnStoreError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock_invoice.NewMockStore(ctrl)
+	service := invoice.NewService(store, fake.NewClock(start))
+
+	store.EXPECT().SelectExpiredInvoiceIDs(gomock.Any(), start, gomock.Any()).
+		Return([]string{"invoice-1", "invoice-2"}, nil)
+	store.EXPECT().FinalizeInvoice(gomock.Any(), "invoice-1", domain.InvoiceStatusExpired).
+		Return(nil, domain.ErrorFailedUpdateInvoice)
+
+	expired, err := service.ExpireInvoices(context.Background())
+	assert.ErrorIs(t, err, domain.ErrorFailedUpdateInvoice)
+	assert.Zero(t, expired)
+}
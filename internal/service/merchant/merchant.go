@@ -12,6 +12,9 @@ type Store interface {
 		ctx context.Context,
 		merchantID string,
 	) (*domain.Merchant, error)
+
+	// GetMerchantDailyTurnover возвращает созданные и оплаченные Invoice мерчанта за текущий бизнес-день
+	GetMerchantDailyTurnover(ctx context.Context, merchantID string) (domain.MerchantTurnover, error)
 }
 
 type Service struct {
@@ -22,33 +25,52 @@ func NewService(store Store) *Service {
 	return &Service{store: store}
 }
 
+// ValidateMerchantInvoice проверяет статус мерчанта, ограничения типа реквизита и дневные лимиты.
+// Оборот читается только у мерчантов с дневными лимитами. Проверка не резервирует оборот,
+// поэтому параллельные запросы могут превысить лимит на несколько Invoice.
 func (s *Service) ValidateMerchantInvoice(
 	ctx context.Context,
 	merchantID string,
 	amount decimal.Decimal,
 	requisiteType domain.RequisiteType,
-) error {
+) (*domain.Merchant, error) {
 	merchant, err := s.store.GetMerchantByMerchantID(ctx, merchantID)
 	if err != nil {
-		return errors.Wrap(err, "get merchant by id")
-	}
-
-	switch requisiteType {
-	case domain.RequisiteTypeCard:
-		if amount.LessThan(merchant.InLimitCard) {
-			return domain.ErrorAmountLessThanLimit
-		}
-	case domain.RequisiteTypeWallet:
-		if amount.LessThan(merchant.InLimitWallet) {
-			return domain.ErrorAmountLessThanLimit
-		}
-	case domain.RequisiteTypeSBP:
-		if amount.LessThan(merchant.InLimitSBP) {
-			return domain.ErrorAmountLessThanLimit
-		}
-	default:
-		return domain.ErrorUnknownRequisiteType
-	}
-
-	return nil
+		return nil, errors.Wrap(err, "get merchant by id")
+	}
+
+	if merchant.IsSuspended() {
+		return nil, domain.ErrorMerchantSuspended
+	}
+
+	limits, err := merchant.InLimits(requisiteType)
+	if err != nil {
+		return nil, err
+	}
+	if limits.Disabled {
+		return nil, domain.ErrorRequisiteTypeDisabled
+	}
+	if amount.LessThan(limits.Min) {
+		return nil, domain.ErrorAmountLessThanLimit
+	}
+	if limits.Max.IsPositive() && amount.GreaterThan(limits.Max) {
+		return nil, domain.ErrorAmountGreaterThanLimit
+	}
+
+	if merchant.DailyInvoiceLimit <= 0 && !merchant.DailyTurnoverLimit.IsPositive() {
+		return merchant, nil
+	}
+
+	turnover, err := s.store.GetMerchantDailyTurnover(ctx, merchantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get merchant daily turnover")
+	}
+	if merchant.DailyInvoiceLimit > 0 && turnover.Count >= merchant.DailyInvoiceLimit {
+		return nil, domain.ErrorMerchantDailyInvoicesExceeded
+	}
+	if merchant.DailyTurnoverLimit.IsPositive() && turnover.Sum.Add(amount).GreaterThan(merchant.DailyTurnoverLimit) {
+		return nil, domain.ErrorMerchantDailyTurnoverExceeded
+	}
+
+	return merchant, nil
 }
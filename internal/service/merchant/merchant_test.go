@@ -0,0 +1,147 @@
+package merchant_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"mateo/internal/domain"
+	mock_merchant "mateo/internal/mock/merchant"
+	"mateo/internal/service/merchant"
+)
+
+func newService(t *testing.T) (*merchant.Service, *mock_merchant.MockStore) {
+	t.Helper()
+
+	store := mock_merchant.NewMockStore(gomock.NewController(t))
+	return merchant.NewService(store), store
+}
+
+func TestValidateMerchantInvoice(t *testing.T) {
+	base := domain.Merchant{
+		ID:            "merchant-1",
+		Status:        domain.MerchantStatusActive,
+		InLimitCard:   decimal.NewFromInt(100),
+		InMaxCard:     decimal.NewFromInt(10000),
+		InLimitWallet: decimal.NewFromInt(100),
+	}
+
+	cases := []struct {
+		name          string
+		merchant      func(m *domain.Merchant)
+		amount        int64
+		requisiteType domain.RequisiteType
+		// turnover nil — оборот не должен читаться
+		turnover *domain.MerchantTurnover
+		want     error
+	}{
+		{name: "within limits", amount: 500, requisiteType: domain.RequisiteTypeCard},
+		{name: "at minimum", amount: 100, requisiteType: domain.RequisiteTypeCard},
+		{name: "at maximum", amount: 10000, requisiteType: domain.RequisiteTypeCard},
+		{
+			name:          "suspended",
+			merchant:      func(m *domain.Merchant) { m.Status = domain.MerchantStatusSuspended },
+			amount:        500,
+			requisiteType: domain.RequisiteTypeCard,
+			want:          domain.ErrorMerchantSuspended,
+		},
+		{
+			name:          "disabled type",
+			merchant:      func(m *domain.Merchant) { m.InDisabledCard = true },
+			amount:        500,
+			requisiteType: domain.RequisiteTypeCard,
+			want:          domain.ErrorRequisiteTypeDisabled,
+		},
+		{name: "unknown type", amount: 500, requisiteType: "CRYPTO", want: domain.ErrorUnknownRequisiteType},
+		{name: "below minimum", amount: 99, requisiteType: domain.RequisiteTypeCard, want: domain.ErrorAmountLessThanLimit},
+		{name: "above maximum", amount: 10001, requisiteType: domain.RequisiteTypeCard, want: domain.ErrorAmountGreaterThanLimit},
+		{name: "no maximum", amount: 1000000, requisiteType: domain.RequisiteTypeWallet},
+		{
+			name:          "under daily invoices cap",
+			merchant:      func(m *domain.Merchant) { m.DailyInvoiceLimit = 10 },
+			amount:        500,
+			requisiteType: domain.RequisiteTypeCard,
+			turnover:      &domain.MerchantTurnover{Count: 9, Sum: decimal.NewFromInt(5000)},
+		},
+		{
+			name:          "daily invoices cap reached",
+			merchant:      func(m *domain.Merchant) { m.DailyInvoiceLimit = 10 },
+			amount:        500,
+			requisiteType: domain.RequisiteTypeCard,
+			turnover:      &domain.MerchantTurnover{Count: 10, Sum: decimal.NewFromInt(5000)},
+			want:          domain.ErrorMerchantDailyInvoicesExceeded,
+		},
+		{
+			name:          "turnover reaches cap",
+			merchant:      func(m *domain.Merchant) { m.DailyTurnoverLimit = decimal.NewFromInt(10000) },
+			amount:        500,
+			requisiteType: domain.RequisiteTypeCard,
+			turnover:      &domain.MerchantTurnover{Count: 30, Sum: decimal.NewFromInt(9500)},
+		},
+		{
+			name:          "turnover over cap",
+			merchant:      func(m *domain.Merchant) { m.DailyTurnoverLimit = decimal.NewFromInt(10000) },
+			amount:        501,
+			requisiteType: domain.RequisiteTypeCard,
+			turnover:      &domain.MerchantTurnover{Count: 30, Sum: decimal.NewFromInt(9500)},
+			want:          domain.ErrorMerchantDailyTurnoverExceeded,
+		},
+		{
+			// Сумма проверяется до оборота: отказ по лимиту суммы не читает счетчики
+			name:          "amount refused before turnover",
+			merchant:      func(m *domain.Merchant) { m.DailyInvoiceLimit = 10 },
+			amount:        99,
+			requisiteType: domain.RequisiteTypeCard,
+			want:          domain.ErrorAmountLessThanLimit,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			service, store := newService(t)
+			stored := base
+			if c.merchant != nil {
+				c.merchant(&stored)
+			}
+			store.EXPECT().GetMerchantByMerchantID(gomock.Any(), "merchant-1").Return(&stored, nil)
+			if c.turnover != nil {
+				store.EXPECT().GetMerchantDailyTurnover(gomock.Any(), "merchant-1").Return(*c.turnover, nil)
+			}
+
+			got, err := service.ValidateMerchantInvoice(context.Background(), "merchant-1", decimal.NewFromInt(c.amount), c.requisiteType)
+			if c.want != nil {
+				assert.True(t, errors.Is(err, c.want), "got error %v", err)
+				assert.Nil(t, got)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, &stored, got)
+		})
+	}
+}
+
+func TestValidateMerchantInvoiceStoreErrors(t *testing.T) {
+	errDatabase := errors.New("connection reset")
+
+	t.Run("merchant", func(t *testing.T) {
+		service, store := newService(t)
+		store.EXPECT().GetMerchantByMerchantID(gomock.Any(), "merchant-1").Return(nil, errDatabase)
+
+		_, err := service.ValidateMerchantInvoice(context.Background(), "merchant-1", decimal.NewFromInt(500), domain.RequisiteTypeCard)
+		assert.True(t, errors.Is(err, errDatabase), "got error %v", err)
+	})
+
+	t.Run("turnover", func(t *testing.T) {
+		service, store := newService(t)
+		store.EXPECT().GetMerchantByMerchantID(gomock.Any(), "merchant-1").
+			Return(&domain.Merchant{ID: "merchant-1", DailyInvoiceLimit: 10}, nil)
+		store.EXPECT().GetMerchantDailyTurnover(gomock.Any(), "merchant-1").Return(domain.MerchantTurnover{}, errDatabase)
+
+		_, err := service.ValidateMerchantInvoice(context.Background(), "merchant-1", decimal.NewFromInt(500), domain.RequisiteTypeCard)
+		assert.True(t, errors.Is(err, errDatabase), "got error %v", err)
+	})
+}
@@ -0,0 +1,91 @@
+package payer
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"mateo/internal/domain"
+	"sync/atomic"
+	"time"
+)
+
+type Store interface {
+	// IsPayerBlocked сообщает, что плательщик в списке блокировки мерчанта
+	IsPayerBlocked(ctx context.Context, merchantID string, payerID string) (bool, error)
+
+	// GetPayerActivity считает Invoice плательщика у мерчанта, созданные после since, и активные сейчас
+	GetPayerActivity(
+		ctx context.Context,
+		merchantID string,
+		payerID string,
+		since time.Time,
+	) (domain.PayerActivity, error)
+}
+
+// Limits лимиты частоты Invoice одного плательщика у мерчанта. Нулевой лимит не проверяется.
+type Limits struct {
+	// MaxInvoices сколько Invoice плательщик может создать за Window
+	MaxInvoices int
+	Window      time.Duration
+	// MaxActive сколько неоплаченных Invoice у плательщика может быть одновременно
+	MaxActive int
+}
+
+type Service struct {
+	store   Store
+	clock   domain.Clock
+	limits  atomic.Pointer[Limits]
+	enabled atomic.Bool
+}
+
+func NewService(store Store, clock domain.Clock, limits Limits) *Service {
+	s := &Service{store: store, clock: clock}
+	s.limits.Store(&limits)
+	s.enabled.Store(true)
+	return s
+}
+
+// SetLimits меняет лимиты на ходу
+func (s *Service) SetLimits(limits Limits) {
+	s.limits.Store(&limits)
+}
+
+// SetEnabled включает и выключает проверки; выключенный сервис пропускает всех плательщиков
+func (s *Service) SetEnabled(enabled bool) {
+	s.enabled.Store(enabled)
+}
+
+// CheckPayer возвращает domain.ErrorPayerBlocked для плательщика из списка блокировки мерчанта
+// и domain.ErrorPayerVelocityExceeded при превышении лимитов. Лимиты проверяются до создания
+// Invoice и без блокировок, поэтому параллельные запросы могут превысить их на единицы.
+func (s *Service) CheckPayer(ctx context.Context, merchantID string, payerID string) error {
+	if !s.enabled.Load() {
+		return nil
+	}
+
+	blocked, err := s.store.IsPayerBlocked(ctx, merchantID, payerID)
+	if err != nil {
+		return errors.Wrap(err, "check payer block")
+	}
+	if blocked {
+		return domain.ErrorPayerBlocked
+	}
+
+	limits := s.limits.Load()
+	if limits.MaxActive <= 0 && (limits.MaxInvoices <= 0 || limits.Window <= 0) {
+		return nil
+	}
+
+	activity, err := s.store.GetPayerActivity(ctx, merchantID, payerID, s.clock.Now().Add(-limits.Window))
+	if err != nil {
+		return errors.Wrap(err, "get payer activity")
+	}
+
+	if limits.MaxActive > 0 && activity.Active >= limits.MaxActive {
+		return errors.Wrapf(domain.ErrorPayerVelocityExceeded, "%d active invoices", activity.Active)
+	}
+	if limits.MaxInvoices > 0 && limits.Window > 0 && activity.Created >= limits.MaxInvoices {
+		return errors.Wrapf(domain.ErrorPayerVelocityExceeded, "%d invoices in %s", activity.Created, limits.Window)
+	}
+
+	return nil
+}
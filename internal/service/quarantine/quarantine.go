@@ -0,0 +1,219 @@
+package quarantine
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"mateo/internal/domain"
+	"sync/atomic"
+	"time"
+)
+
+// notificationBatchSize сколько уведомлений отправляется за один проход
+const notificationBatchSize = 100
+
+type Store interface {
+	// SelectRecentOutcomes возвращает статусы последних оплаченных и истекших Invoice сущности,
+	// созданных после окончания ее прошлого карантина, от новых к старым
+	SelectRecentOutcomes(
+		ctx context.Context,
+		entity domain.BlockEntity,
+		entityID string,
+		limit int,
+	) ([]domain.InvoiceStatus, error)
+
+	// SelectOutcomeStats считает оплаченные и истекшие Invoice сущности, созданные после since
+	// и после окончания ее прошлого карантина
+	SelectOutcomeStats(
+		ctx context.Context,
+		entity domain.BlockEntity,
+		entityID string,
+		since time.Time,
+	) (domain.OutcomeStats, error)
+
+	// QuarantineEntity ставит сущность на карантин и в той же транзакции пишет уведомление команде.
+	// Возвращает false, если сущность уже на карантине.
+	QuarantineEntity(ctx context.Context, quarantine domain.Quarantine) (bool, error)
+
+	SelectPendingNotifications(ctx context.Context, limit int) ([]*domain.TeamNotification, error)
+	MarkNotificationSent(ctx context.Context, notificationID string) error
+}
+
+// Notifier доставляет уведомление команде трейдеров
+type Notifier interface {
+	Send(ctx context.Context, notification *domain.TeamNotification) error
+}
+
+// Rules пороги карантина. Нулевое значение порога выключает соответствующее правило.
+type Rules struct {
+	// ConsecutiveExpired сколько Invoice подряд должно истечь
+	ConsecutiveExpired int
+	// MinSuccessRate минимальная доля оплаченных Invoice за Window
+	MinSuccessRate float64
+	Window         time.Duration
+	// MinInvoices с какого числа Invoice в окне проверяется доля оплаченных
+	MinInvoices int
+	// Duration длительность карантина
+	Duration time.Duration
+}
+
+type Service struct {
+	store    Store
+	notifier Notifier
+	clock    domain.Clock
+	rules    atomic.Pointer[Rules]
+	enabled  atomic.Bool
+}
+
+func NewService(store Store, notifier Notifier, clock domain.Clock, rules Rules) *Service {
+	s := &Service{store: store, notifier: notifier, clock: clock}
+	s.rules.Store(&rules)
+	s.enabled.Store(true)
+	return s
+}
+
+// SetRules меняет пороги на ходу
+func (s *Service) SetRules(rules Rules) {
+	s.rules.Store(&rules)
+}
+
+// SetEnabled включает и выключает карантин; уже начатые карантины не снимаются,
+// а накопленные уведомления продолжают отправляться
+func (s *Service) SetEnabled(enabled bool) {
+	s.enabled.Store(enabled)
+}
+
+// InvoiceFinalized проверяет правила для реквизита и терминала истекшего Invoice.
+// Ошибки только логируются: карантин не должен мешать закрытию Invoice.
+func (s *Service) InvoiceFinalized(ctx context.Context, invoice *domain.Invoice) {
+	if invoice.Status != domain.InvoiceStatusExpired || !s.enabled.Load() {
+		return
+	}
+
+	rules := s.rules.Load()
+
+	for _, target := range []struct {
+		entity domain.BlockEntity
+		id     string
+	}{
+		{domain.BlockEntityRequisite, invoice.RequisiteID},
+		{domain.BlockEntityTerminal, invoice.TerminalID},
+	} {
+		if err := s.evaluate(ctx, rules, target.entity, target.id); err != nil {
+			log.Ctx(ctx).Error().Err(err).
+				Str("entity", string(target.entity)).
+				Str("entity_id", target.id).
+				Msg("failed to evaluate quarantine rules")
+		}
+	}
+}
+
+func (s *Service) evaluate(ctx context.Context, rules *Rules, entity domain.BlockEntity, entityID string) error {
+	reason, err := s.violation(ctx, rules, entity, entityID)
+	if err != nil || reason == "" {
+		return err
+	}
+
+	quarantine := domain.Quarantine{
+		Entity:   entity,
+		EntityID: entityID,
+		Reason:   reason,
+		Until:    s.clock.Now().Add(rules.Duration),
+	}
+	quarantined, err := s.store.QuarantineEntity(ctx, quarantine)
+	if err != nil {
+		return errors.Wrap(err, "quarantine entity")
+	}
+	if quarantined {
+		log.Ctx(ctx).Warn().
+			Str("entity", string(entity)).
+			Str("entity_id", entityID).
+			Str("reason", reason).
+			Time("until", quarantine.Until).
+			Msg("entity quarantined")
+	}
+	return nil
+}
+
+// violation возвращает причину карантина или пустую строку, если правила не нарушены
+func (s *Service) violation(
+	ctx context.Context,
+	rules *Rules,
+	entity domain.BlockEntity,
+	entityID string,
+) (string, error) {
+	if n := rules.ConsecutiveExpired; n > 0 {
+		outcomes, err := s.store.SelectRecentOutcomes(ctx, entity, entityID, n)
+		if err != nil {
+			return "", errors.Wrap(err, "select recent outcomes")
+		}
+		if len(outcomes) == n && allExpired(outcomes) {
+			return fmt.Sprintf("%d consecutive invoices expired", n), nil
+		}
+	}
+
+	if rules.MinSuccessRate > 0 && rules.Window > 0 {
+		stats, err := s.store.SelectOutcomeStats(ctx, entity, entityID, s.clock.Now().Add(-rules.Window))
+		if err != nil {
+			return "", errors.Wrap(err, "select outcome stats")
+		}
+		if stats.Total > 0 && stats.Total >= rules.MinInvoices {
+			rate := float64(stats.Success) / float64(stats.Total)
+			if rate < rules.MinSuccessRate {
+				return fmt.Sprintf("success rate %.2f over %d invoices in %s is below %.2f",
+					rate, stats.Total, rules.Window, rules.MinSuccessRate), nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+func allExpired(outcomes []domain.InvoiceStatus) bool {
+	for _, status := range outcomes {
+		if status != domain.InvoiceStatusExpired {
+			return false
+		}
+	}
+	return true
+}
+
+// SendNotifications отправляет накопленные уведомления команд. Неотправленное уведомление
+// остается в outbox до следующего прохода. Возвращает число отправленных уведомлений.
+func (s *Service) SendNotifications(ctx context.Context) (int, error) {
+	notifications, err := s.store.SelectPendingNotifications(ctx, notificationBatchSize)
+	if err != nil {
+		return 0, errors.Wrap(err, "select pending notifications")
+	}
+
+	sent := 0
+	for _, notification := range notifications {
+		if err := s.notifier.Send(ctx, notification); err != nil {
+			return sent, errors.Wrapf(err, "send notification %s", notification.ID)
+		}
+		if err := s.store.MarkNotificationSent(ctx, notification.ID); err != nil {
+			return sent, errors.Wrapf(err, "mark notification %s sent", notification.ID)
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// RunNotifications периодически отправляет уведомления команд, пока не отменен ctx
+func (s *Service) RunNotifications(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.SendNotifications(ctx); err != nil {
+				log.Ctx(ctx).Error().Err(err).Msg("failed to send team notifications")
+			}
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package quarantine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"mateo/internal/domain"
+	"mateo/internal/fake"
+	mock_quarantine "mateo/internal/mock/quarantine"
+	"mateo/internal/service/quarantine"
+)
+
+var now = time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC)
+
+var expired = &domain.Invoice{
+	ID:          "invoice-1",
+	RequisiteID: "requisite-1",
+	TerminalID:  "terminal-1",
+	Status:      domain.InvoiceStatusExpired,
+}
+
+var targets = []struct {
+	entity domain.BlockEntity
+	id     string
+}{
+	{domain.BlockEntityRequisite, "requisite-1"},
+	{domain.BlockEntityTerminal, "terminal-1"},
+}
+
+func newService(t *testing.T, rules quarantine.Rules) (*quarantine.Service, *mock_quarantine.MockStore, *mock_quarantine.MockNotifier) {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+	store := mock_quarantine.NewMockStore(ctrl)
+	notifier := mock_quarantine.NewMockNotifier(ctrl)
+	return quarantine.NewService(store, notifier, fake.NewClock(now), rules), store, notifier
+}
+
+func expectQuarantine(store *mock_quarantine.MockStore, entity domain.BlockEntity, id string, reason string) {
+	store.EXPECT().QuarantineEntity(gomock.Any(), domain.Quarantine{
+		Entity:   entity,
+		EntityID: id,
+		Reason:   reason,
+		Until:    now.Add(30 * time.Minute),
+	}).Return(true, nil)
+}
+
+func TestConsecutiveExpired(t *testing.T) {
+	cases := []struct {
+		name     string
+		outcomes []domain.InvoiceStatus
+		reason   string
+	}{
+		{
+			"all expired",
+			[]domain.InvoiceStatus{domain.InvoiceStatusExpired, domain.InvoiceStatusExpired, domain.InvoiceStatusExpired},
+			"3 consecutive invoices expired",
+		},
+		{
+			"paid in between",
+			[]domain.InvoiceStatus{domain.InvoiceStatusExpired, domain.InvoiceStatusSuccess, domain.InvoiceStatusExpired},
+			"",
+		},
+		// После прошлого карантина закрылось меньше n Invoice
+		{"too few invoices", []domain.InvoiceStatus{domain.InvoiceStatusExpired, domain.InvoiceStatusExpired}, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			service, store, _ := newService(t, quarantine.Rules{ConsecutiveExpired: 3, Duration: 30 * time.Minute})
+			for _, target := range targets {
+				store.EXPECT().SelectRecentOutcomes(gomock.Any(), target.entity, target.id, 3).Return(c.outcomes, nil)
+				if c.reason != "" {
+					expectQuarantine(store, target.entity, target.id, c.reason)
+				}
+			}
+
+			service.InvoiceFinalized(context.Background(), expired)
+		})
+	}
+}
+
+func TestSuccessRate(t *testing.T) {
+	cases := []struct {
+		name        string
+		minInvoices int
+		stats       domain.OutcomeStats
+		reason      string
+	}{
+		{"below rate", 10, domain.OutcomeStats{Total: 10, Success: 2}, "success rate 0.20 over 10 invoices in 1h0m0s is below 0.30"},
+		{"at rate", 10, domain.OutcomeStats{Total: 10, Success: 3}, ""},
+		{"below min invoices", 10, domain.OutcomeStats{Total: 9, Success: 0}, ""},
+		{"no invoices without floor", 0, domain.OutcomeStats{}, ""},
+		{"single invoice without floor", 0, domain.OutcomeStats{Total: 1}, "success rate 0.00 over 1 invoices in 1h0m0s is below 0.30"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			service, store, _ := newService(t, quarantine.Rules{
+				MinSuccessRate: 0.3,
+				Window:         time.Hour,
+				MinInvoices:    c.minInvoices,
+				Duration:       30 * time.Minute,
+			})
+			for _, target := range targets {
+				store.EXPECT().SelectOutcomeStats(gomock.Any(), target.entity, target.id, now.Add(-time.Hour)).Return(c.stats, nil)
+				if c.reason != "" {
+					expectQuarantine(store, target.entity, target.id, c.reason)
+				}
+			}
+
+			service.InvoiceFinalized(context.Background(), expired)
+		})
+	}
+}
+
+func TestRulesDisabled(t *testing.T) {
+	rules := quarantine.Rules{ConsecutiveExpired: 3, MinSuccessRate: 0.3, Window: time.Hour, Duration: 30 * time.Minute}
+
+	// Хранилище без ожиданий: любой вызов провалит тест
+	t.Run("zero thresholds", func(t *testing.T) {
+		service, _, _ := newService(t, quarantine.Rules{Window: time.Hour, Duration: 30 * time.Minute})
+		service.InvoiceFinalized(context.Background(), expired)
+	})
+
+	t.Run("rate without window", func(t *testing.T) {
+		service, _, _ := newService(t, quarantine.Rules{MinSuccessRate: 0.3, Duration: 30 * time.Minute})
+		service.InvoiceFinalized(context.Background(), expired)
+	})
+
+	t.Run("service disabled", func(t *testing.T) {
+		service, _, _ := newService(t, rules)
+		service.SetEnabled(false)
+		service.InvoiceFinalized(context.Background(), expired)
+	})
+
+	t.Run("paid invoice", func(t *testing.T) {
+		service, _, _ := newService(t, rules)
+		service.InvoiceFinalized(context.Background(), &domain.Invoice{
+			RequisiteID: "requisite-1",
+			TerminalID:  "terminal-1",
+			Status:      domain.InvoiceStatusSuccess,
+		})
+	})
+}
+
+func TestRulesEvaluatedPerEntity(t *testing.T) {
+	service, store, _ := newService(t, quarantine.Rules{ConsecutiveExpired: 1, Duration: 30 * time.Minute})
+
+	// Ошибка по реквизиту не мешает проверить терминал
+	store.EXPECT().SelectRecentOutcomes(gomock.Any(), domain.BlockEntityRequisite, "requisite-1", 1).
+		Return(nil, errors.New("connection reset"))
+	store.EXPECT().SelectRecentOutcomes(gomock.Any(), domain.BlockEntityTerminal, "terminal-1", 1).
+		Return([]domain.InvoiceStatus{domain.InvoiceStatusExpired}, nil)
+	store.EXPECT().QuarantineEntity(gomock.Any(), gomock.Any()).Return(false, nil)
+
+	service.InvoiceFinalized(context.Background(), expired)
+}
+
+func TestSendNotifications(t *testing.T) {
+	service, store, notifier := newService(t, quarantine.Rules{})
+
+	notifications := []*domain.TeamNotification{
+		{ID: "notification-1", TeamID: "team-1", Kind: domain.TeamNotificationKindQuarantine},
+		{ID: "notification-2", TeamID: "team-2", Kind: domain.TeamNotificationKindQuarantine},
+		{ID: "notification-3", TeamID: "team-1", Kind: domain.TeamNotificationKindQuarantine},
+	}
+	errWebhook := errors.New("webhook returned 502")
+
+	// Первый проход останавливается на неотправленном уведомлении, оно остается в outbox
+	gomock.InOrder(
+		store.EXPECT().SelectPendingNotifications(gomock.Any(), 100).Return(notifications, nil),
+		notifier.EXPECT().Send(gomock.Any(), notifications[0]).Return(nil),
+		store.EXPECT().MarkNotificationSent(gomock.Any(), "notification-1").Return(nil),
+		notifier.EXPECT().Send(gomock.Any(), notifications[1]).Return(errWebhook),
+	)
+	sent, err := service.SendNotifications(context.Background())
+	assert.Equal(t, 1, sent)
+	require.True(t, errors.Is(err, errWebhook), "got error %v", err)
+	assert.Contains(t, err.Error(), "notification-2")
+
+	gomock.InOrder(
+		store.EXPECT().SelectPendingNotifications(gomock.Any(), 100).Return(notifications[1:], nil),
+		notifier.EXPECT().Send(gomock.Any(), notifications[1]).Return(nil),
+		store.EXPECT().MarkNotificationSent(gomock.Any(), "notification-2").Return(nil),
+		notifier.EXPECT().Send(gomock.Any(), notifications[2]).Return(nil),
+		store.EXPECT().MarkNotificationSent(gomock.Any(), "notification-3").Return(nil),
+	)
+	sent, err = service.SendNotifications(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+}
@@ -5,7 +5,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"mateo/internal/domain"
-	"math/rand"
+	"sync/atomic"
 )
 
 type Store interface {
@@ -14,7 +14,8 @@ type Store interface {
 		merchantID string,
 		amount decimal.Decimal,
 		requisiteType domain.RequisiteType,
-		bankId string,
+		banks domain.BankPreference,
+		payerID string,
 	) ([]*domain.Requisite, error)
 
 	GetBoostedTeamIds(ctx context.Context) ([]string, error)
@@ -26,21 +27,65 @@ type Store interface {
 		flexibleAmountMax decimal.Decimal,
 		flexibleAmountStep decimal.Decimal,
 		requisiteType domain.RequisiteType,
-		bankId string,
+		banks domain.BankPreference,
+		payerID string,
 	) ([]*domain.Requisite, error)
 }
 
-const (
-	flexibleAmountStep   = 5
-	defaultFlexibleRange = 20
-)
+// Flexible параметры гибкой суммы
+type Flexible struct {
+	// Step шаг, с которым перебираются суммы
+	Step int
+	// DefaultRange на сколько сумма может вырасти, если мерчант не передал flexibleRange
+	DefaultRange int
+}
+
+func DefaultFlexible() Flexible {
+	return Flexible{Step: 5, DefaultRange: 20}
+}
 
 type Service struct {
-	store Store
+	store    Store
+	clock    domain.Clock
+	rand     domain.Rand
+	scoring  atomic.Pointer[Scoring]
+	flexible atomic.Pointer[Flexible]
+	metrics  domain.Metrics
+}
+
+func NewService(store Store, clock domain.Clock, rand domain.Rand) *Service {
+	s := &Service{store: store, clock: clock, rand: rand, metrics: domain.NoopMetrics{}}
+	s.SetScoring(DefaultScoring())
+	s.SetFlexible(DefaultFlexible())
+	return s
+}
+
+// WithScoring задает параметры оценки кандидатов вместо DefaultScoring
+func (s *Service) WithScoring(scoring Scoring) *Service {
+	s.SetScoring(scoring)
+	return s
+}
+
+// SetScoring меняет параметры оценки на ходу
+func (s *Service) SetScoring(scoring Scoring) {
+	s.scoring.Store(&scoring)
+}
+
+// WithFlexible задает параметры гибкой суммы вместо DefaultFlexible
+func (s *Service) WithFlexible(flexible Flexible) *Service {
+	s.SetFlexible(flexible)
+	return s
+}
+
+// SetFlexible меняет параметры гибкой суммы на ходу
+func (s *Service) SetFlexible(flexible Flexible) {
+	s.flexible.Store(&flexible)
 }
 
-func NewService(store Store) *Service {
-	return &Service{store: store}
+// WithMetrics подключает учет размеров пула кандидатов
+func (s *Service) WithMetrics(metrics domain.Metrics) *Service {
+	s.metrics = metrics
+	return s
 }
 
 func (s *Service) SelectAvailableRequisite(
@@ -48,42 +93,54 @@ func (s *Service) SelectAvailableRequisite(
 	merchantID string,
 	amount decimal.Decimal,
 	requisiteType domain.RequisiteType,
-	bankID string,
+	banks domain.BankPreference,
+	payerID string,
 	flexibleRange int,
 	allowFlexibleAmount bool,
+	excludedAccounts []string,
 ) (*domain.Requisite, error) {
-	requisites, err := s.store.SelectAvailableRequisites(ctx, merchantID, amount, requisiteType, bankID)
+	requisites, err := s.store.SelectAvailableRequisites(ctx, merchantID, amount, requisiteType, banks, payerID)
 	if err != nil {
 		return nil, errors.Wrap(err, "select available requisites")
 	}
+	s.metrics.ObserveCandidates(requisiteType, false, len(requisites))
+	requisites = excludeAccounts(requisites, excludedAccounts)
 
 	if len(requisites) == 0 {
 		if !allowFlexibleAmount {
 			return nil, domain.ErrorNoAvailableRequisites
 		}
+		flexible := s.flexible.Load()
 		if flexibleRange == 0 {
-			flexibleRange = defaultFlexibleRange
+			flexibleRange = flexible.DefaultRange
 		}
+		step := decimal.NewFromInt(int64(flexible.Step))
 		maxFlexibleAmount := amount.Add(decimal.NewFromInt(int64(flexibleRange)))
-		// округляем до 5
-		minFlexibleAmount := roundUpToFive(roundUpToFive(amount))
+		// округляем вверх до шага
+		minFlexibleAmount := roundUpToStep(amount, step)
 		requisites, err = s.store.SelectAvailableRequisitesFlexible(
 			ctx,
 			merchantID,
 			minFlexibleAmount,
 			maxFlexibleAmount,
-			decimal.NewFromInt(flexibleAmountStep),
+			step,
 			requisiteType,
-			bankID,
+			banks,
+			payerID,
 		)
 		if err != nil {
 			return nil, errors.Wrap(err, "select available flexible requisites")
 		}
+		s.metrics.ObserveCandidates(requisiteType, true, len(requisites))
+		requisites = excludeAccounts(requisites, excludedAccounts)
 		if len(requisites) == 0 {
 			return nil, domain.ErrorNoAvailableRequisites
 		}
 	}
 
+	requisites = avoidUnpaid(requisites)
+	requisites = preferBanks(requisites, banks)
+
 	boostedTeamIds, err := s.store.GetBoostedTeamIds(ctx)
 	if err != nil {
 		return nil, domain.ErrorNoAvailableRequisites
@@ -97,12 +154,61 @@ func (s *Service) SelectAvailableRequisite(
 	}
 
 	if len(boostedRequisites) == 0 {
-		return requisites[rand.Intn(len(requisites))], nil
+		return s.pick(ctx, requisites), nil
+	}
+
+	return s.pick(ctx, boostedRequisites), nil
+}
+
+// excludeAccounts убирает реквизиты аккаунтов, на кошельках которых не хватило баланса
+func excludeAccounts(requisites []*domain.Requisite, excluded []string) []*domain.Requisite {
+	if len(excluded) == 0 {
+		return requisites
+	}
+
+	kept := make([]*domain.Requisite, 0, len(requisites))
+	for _, requisite := range requisites {
+		if !domain.Contains(excluded, requisite.TraiderAccountID) {
+			kept = append(kept, requisite)
+		}
+	}
+	return kept
+}
+
+// avoidUnpaid убирает реквизиты, на которых плательщик уже оставлял Invoice неоплаченными.
+// Если так отмечены все кандидаты, плательщику отдаются все.
+func avoidUnpaid(requisites []*domain.Requisite) []*domain.Requisite {
+	clean := make([]*domain.Requisite, 0, len(requisites))
+	for _, requisite := range requisites {
+		if requisite.Stats.PayerExpired == 0 {
+			clean = append(clean, requisite)
+		}
+	}
+	if len(clean) == 0 {
+		return requisites
+	}
+	return clean
+}
+
+// preferBanks оставляет реквизиты предпочтительных банков, если они есть среди кандидатов.
+// Банк важнее ускоренных команд: плательщику быстрее и дешевле платить внутри своего банка.
+func preferBanks(requisites []*domain.Requisite, banks domain.BankPreference) []*domain.Requisite {
+	if len(banks.Preferred) == 0 {
+		return requisites
 	}
 
-	return boostedRequisites[rand.Intn(len(boostedRequisites))], nil
+	preferred := make([]*domain.Requisite, 0, len(requisites))
+	for _, requisite := range requisites {
+		if banks.Prefers(requisite.BankID) {
+			preferred = append(preferred, requisite)
+		}
+	}
+	if len(preferred) == 0 {
+		return requisites
+	}
+	return preferred
 }
 
-func roundUpToFive(num decimal.Decimal) decimal.Decimal {
-	return num.Div(decimal.NewFromInt(5)).Ceil().Mul(decimal.NewFromInt(5))
+func roundUpToStep(num decimal.Decimal, step decimal.Decimal) decimal.Decimal {
+	return num.Div(step).Ceil().Mul(step)
 }
@@ -0,0 +1,134 @@
+package requisite_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"mateo/internal/domain"
+	"mateo/internal/fake"
+	mock_requisite "mateo/internal/mock/requisite"
+	"mateo/internal/service/requisite"
+)
+
+const selections = 400
+
+var start = time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC)
+
+// candidate реквизит с closed закрытыми Invoice, из которых success оплачены
+func candidate(id string, closed int, success int) *domain.Requisite {
+	return &domain.Requisite{
+		ID:               id,
+		TraiderAccountID: "account-" + id,
+		Type:             domain.RequisiteTypeCard,
+		Stats: domain.RequisiteStats{
+			RequisiteClosed:  closed,
+			RequisiteSuccess: success,
+			TerminalClosed:   closed,
+			TerminalSuccess:  success,
+		},
+	}
+}
+
+// newService сервис, которому хранилище всегда отдает candidates без ускоренных команд
+func newService(t *testing.T, rand *fake.Rand, scoring requisite.Scoring, candidates ...*domain.Requisite) *requisite.Service {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+	store := mock_requisite.NewMockStore(ctrl)
+	store.EXPECT().
+		SelectAvailableRequisites(gomock.Any(), "merchant-1", gomock.Any(), domain.RequisiteTypeCard, gomock.Any(), "").
+		Return(candidates, nil).
+		AnyTimes()
+	store.EXPECT().GetBoostedTeamIds(gomock.Any()).Return(nil, nil).AnyTimes()
+
+	return requisite.NewService(store, fake.NewClock(start), rand).WithScoring(scoring)
+}
+
+// distribution выбирает реквизит selections раз и считает выборы каждого
+func distribution(t *testing.T, service *requisite.Service, excludedAccounts []string) map[string]int {
+	t.Helper()
+
+	counts := make(map[string]int)
+	for range selections {
+		selected, err := service.SelectAvailableRequisite(
+			context.Background(),
+			"merchant-1",
+			decimal.NewFromInt(500),
+			domain.RequisiteTypeCard,
+			domain.BankPreference{},
+			"",
+			0,
+			false,
+			excludedAccounts,
+		)
+		require.NoError(t, err)
+		counts[selected.ID]++
+	}
+	return counts
+}
+
+func withoutExploration() requisite.Scoring {
+	scoring := requisite.DefaultScoring()
+	scoring.Exploration = 0
+	return scoring
+}
+
+func TestSelectSpreadsEqualScores(t *testing.T) {
+	rand := fake.NewRand(0, 1, 2)
+	service := newService(t, rand, withoutExploration(),
+		candidate("a", 10, 5), candidate("b", 10, 5), candidate("c", 10, 5))
+
+	assert.Equal(t, map[string]int{"a": 134, "b": 133, "c": 133}, distribution(t, service, nil))
+	for _, n := range rand.Calls() {
+		assert.Equal(t, 3, n, "every tie is drawn among all three candidates")
+	}
+}
+
+func TestSelectPrefersHigherScore(t *testing.T) {
+	service := newService(t, fake.NewRand(0, 1, 2), withoutExploration(),
+		candidate("poor", 20, 2), candidate("good", 20, 18), candidate("average", 20, 10))
+
+	assert.Equal(t, map[string]int{"good": selections}, distribution(t, service, nil))
+}
+
+func TestSelectExploresFreshRequisites(t *testing.T) {
+	scoring := requisite.DefaultScoring()
+	scoring.Exploration = 0.25
+
+	// Каждое четвертое значение Float64 меньше Exploration. Intn вызывается на каждом выборе,
+	// поэтому разведка через раз получает 0 и 1.
+	rand := fake.NewRand(0, 0, 0, 0, 1, 1, 1, 1).WithFloats(0.1, 0.3, 0.5, 0.7)
+	service := newService(t, rand, scoring,
+		candidate("good", 20, 18), candidate("fresh-1", 0, 0), candidate("fresh-2", 2, 0))
+
+	assert.Equal(t, map[string]int{
+		"good":    selections * 3 / 4,
+		"fresh-1": selections / 8,
+		"fresh-2": selections / 8,
+	}, distribution(t, service, nil))
+}
+
+func TestSelectSkipsExcludedAccounts(t *testing.T) {
+	service := newService(t, fake.NewRand(0, 1), withoutExploration(),
+		candidate("good", 20, 18), candidate("average", 20, 10), candidate("poor", 20, 2))
+
+	assert.Equal(t, map[string]int{"average": selections}, distribution(t, service, []string{"account-good"}))
+
+	_, err := service.SelectAvailableRequisite(
+		context.Background(),
+		"merchant-1",
+		decimal.NewFromInt(500),
+		domain.RequisiteTypeCard,
+		domain.BankPreference{},
+		"",
+		0,
+		false,
+		[]string{"account-good", "account-average", "account-poor"},
+	)
+	assert.ErrorIs(t, err, domain.ErrorNoAvailableRequisites)
+}
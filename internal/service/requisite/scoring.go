@@ -0,0 +1,169 @@
+package requisite
+
+import (
+	"context"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"mateo/internal/domain"
+	"math"
+	"time"
+)
+
+// Scoring параметры оценки кандидатов. Оценка — взвешенная сумма трех составляющих в [0, 1]:
+// сглаженной конверсии, давности последнего Invoice и загрузки реквизита.
+type Scoring struct {
+	// PriorSuccessRate ожидаемая конверсия терминала без истории
+	PriorSuccessRate float64
+	// PriorWeight вес априорной конверсии в псевдо-Invoice. Конверсия терминала сглаживается
+	// к PriorSuccessRate, конверсия реквизита — к конверсии его терминала.
+	PriorWeight float64
+
+	RateWeight    float64
+	RecencyWeight float64
+	LoadWeight    float64
+
+	// RecencyHorizon за сколько простоя реквизит набирает ~63% составляющей давности
+	RecencyHorizon time.Duration
+
+	// Exploration доля выборов, отданных реквизитам с историей меньше MinObservations
+	Exploration     float64
+	MinObservations int
+}
+
+func DefaultScoring() Scoring {
+	return Scoring{
+		PriorSuccessRate: 0.5,
+		PriorWeight:      10,
+		RateWeight:       0.6,
+		RecencyWeight:    0.25,
+		LoadWeight:       0.15,
+		RecencyHorizon:   30 * time.Minute,
+		Exploration:      0.1,
+		MinObservations:  5,
+	}
+}
+
+// Score разбор оценки кандидата
+type Score struct {
+	SuccessRate float64
+	Recency     float64
+	Load        float64
+	Total       float64
+}
+
+func (s Scoring) score(r *domain.Requisite, now time.Time) Score {
+	stats := r.Stats
+
+	terminalRate := smooth(stats.TerminalSuccess, stats.TerminalClosed, s.PriorSuccessRate, s.PriorWeight)
+	rate := smooth(stats.RequisiteSuccess, stats.RequisiteClosed, terminalRate, s.PriorWeight)
+
+	recency := 1.0
+	if stats.LastInvoiceTime != nil && s.RecencyHorizon > 0 {
+		idle := now.Sub(*stats.LastInvoiceTime)
+		recency = 1 - math.Exp(-math.Max(idle.Seconds(), 0)/s.RecencyHorizon.Seconds())
+	}
+
+	load := 1 / float64(1+stats.ActiveInvoices)
+
+	return Score{
+		SuccessRate: rate,
+		Recency:     recency,
+		Load:        load,
+		Total:       s.RateWeight*rate + s.RecencyWeight*recency + s.LoadWeight*load,
+	}
+}
+
+// smooth сглаживает долю успешных к prior с весом weight псевдо-наблюдений
+func smooth(success int, total int, prior float64, weight float64) float64 {
+	if float64(total)+weight <= 0 {
+		return prior
+	}
+	return (float64(success) + prior*weight) / (float64(total) + weight)
+}
+
+// pick выбирает реквизит с наибольшей оценкой, равные оценки разыгрываются случайно.
+// С вероятностью Exploration выбор делается среди реквизитов с короткой историей.
+func (s *Service) pick(ctx context.Context, requisites []*domain.Requisite) *domain.Requisite {
+	now := s.clock.Now()
+	scoring := s.scoring.Load()
+
+	scores := make([]Score, len(requisites))
+	for i, r := range requisites {
+		scores[i] = scoring.score(r, now)
+	}
+
+	var (
+		chosen   int
+		explored bool
+	)
+	candidates := fresh(requisites, scoring.MinObservations)
+	if len(candidates) > 0 && s.rand.Float64() < scoring.Exploration {
+		chosen, explored = candidates[s.rand.Intn(len(candidates))], true
+	} else {
+		chosen = s.best(scores)
+	}
+
+	logScores(ctx, requisites, scores, chosen, explored)
+
+	return requisites[chosen]
+}
+
+// fresh возвращает индексы реквизитов, по которым еще мало закрытых Invoice
+func fresh(requisites []*domain.Requisite, minObservations int) []int {
+	var indexes []int
+	for i, r := range requisites {
+		if r.Stats.RequisiteClosed < minObservations {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// best возвращает индекс наибольшей оценки; при равенстве выбирает случайно
+func (s *Service) best(scores []Score) int {
+	// scoreEpsilon оценки, отличающиеся меньше, считаются равными
+	const scoreEpsilon = 1e-9
+
+	var top []int
+	for i, score := range scores {
+		switch {
+		case len(top) == 0 || score.Total > scores[top[0]].Total+scoreEpsilon:
+			top = []int{i}
+		case score.Total >= scores[top[0]].Total-scoreEpsilon:
+			top = append(top, i)
+		}
+	}
+	return top[s.rand.Intn(len(top))]
+}
+
+// logScores пишет разбор оценки выбранного реквизита, а на уровне debug — всех кандидатов
+func logScores(ctx context.Context, requisites []*domain.Requisite, scores []Score, chosen int, explored bool) {
+	score := scores[chosen]
+	log.Ctx(ctx).Info().
+		Str("requisite_id", requisites[chosen].ID).
+		Int("candidates", len(requisites)).
+		Bool("explored", explored).
+		Float64("score", score.Total).
+		Float64("success_rate", score.SuccessRate).
+		Float64("recency", score.Recency).
+		Float64("load", score.Load).
+		Msg("requisite selected")
+
+	if log.Ctx(ctx).Debug().Enabled() {
+		candidates := zerolog.Arr()
+		for i, r := range requisites {
+			candidates.Dict(zerolog.Dict().
+				Str("requisite_id", r.ID).
+				Float64("score", scores[i].Total).
+				Float64("success_rate", scores[i].SuccessRate).
+				Float64("recency", scores[i].Recency).
+				Float64("load", scores[i].Load).
+				Int("closed", r.Stats.RequisiteClosed).
+				Int("success", r.Stats.RequisiteSuccess))
+		}
+		log.Ctx(ctx).Debug().
+			Str("requisite_id", requisites[chosen].ID).
+			Array("candidates", candidates).
+			Msg("requisite candidate scores")
+	}
+}
@@ -0,0 +1,112 @@
+package sandbox
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"mateo/internal/callback"
+	"mateo/internal/domain"
+	"time"
+)
+
+type Store interface {
+	GetInvoice(ctx context.Context, invoiceID string) (*domain.Invoice, error)
+
+	// FinalizeInvoice переводит активный Invoice в финальный статус
+	FinalizeInvoice(
+		ctx context.Context,
+		invoiceID string,
+		status domain.InvoiceStatus,
+	) (*domain.Invoice, error)
+
+	// AppealInvoice переводит истекший Invoice в SUCCESS_APPEAL
+	AppealInvoice(ctx context.Context, invoiceID string) (*domain.Invoice, error)
+
+	// PruneInvoices удаляет закрытые Invoice, созданные раньше before, и возвращает их число
+	PruneInvoices(ctx context.Context, before time.Time) int
+}
+
+// Notifier отправляет мерчанту callback о статусе Invoice
+type Notifier interface {
+	Send(ctx context.Context, invoice *domain.Invoice) error
+}
+
+type Service struct {
+	store    Store
+	notifier Notifier
+	clock    domain.Clock
+}
+
+func NewService(store Store, notifier Notifier, clock domain.Clock) *Service {
+	return &Service{store: store, notifier: notifier, clock: clock}
+}
+
+// Simulate закрывает Invoice песочницы с исходом outcome и отправляет мерчанту callback.
+// Запрос мерчанта подписывается тем же ключом callbackKey Invoice, что и callback; Invoice
+// без ключа имитировать нельзя. Апелляция возможна и для активного, и для уже истекшего
+// Invoice. Ошибка доставки callback не откатывает статус: она отражается в CallbackDelivered.
+func (s *Service) Simulate(
+	ctx context.Context,
+	merchantID string,
+	invoiceID string,
+	outcome domain.SandboxOutcome,
+	signed domain.SignedBody,
+) (*domain.SandboxSimulation, error) {
+	invoice, err := s.store.GetInvoice(ctx, invoiceID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get invoice")
+	}
+	// Чужие Invoice неотличимы от отсутствующих
+	if invoice.MerchantID != merchantID {
+		return nil, domain.ErrorInvoiceNotFound
+	}
+	if invoice.CallbackKey == "" || !callback.Verify(signed.Body, invoice.CallbackKey, signed.Signature) {
+		return nil, domain.ErrorInvalidSignature
+	}
+
+	switch outcome {
+	case domain.SandboxOutcomeSuccess:
+		invoice, err = s.store.FinalizeInvoice(ctx, invoiceID, domain.InvoiceStatusSuccess)
+	case domain.SandboxOutcomeExpire:
+		invoice, err = s.store.FinalizeInvoice(ctx, invoiceID, domain.InvoiceStatusExpired)
+	case domain.SandboxOutcomeAppeal:
+		if invoice.Status == domain.InvoiceStatusExpired {
+			invoice, err = s.store.AppealInvoice(ctx, invoiceID)
+		} else {
+			invoice, err = s.store.FinalizeInvoice(ctx, invoiceID, domain.InvoiceStatusSuccessAppeal)
+		}
+	default:
+		return nil, domain.ErrorUnknownSandboxOutcome
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "finalize invoice")
+	}
+
+	delivered := true
+	if err := s.notifier.Send(ctx, invoice); err != nil {
+		log.Ctx(ctx).Warn().Err(err).
+			Str("invoice_id", invoiceID).
+			Str("merchant_id", merchantID).
+			Msg("failed to deliver sandbox callback")
+		delivered = false
+	}
+
+	return &domain.SandboxSimulation{Invoice: invoice, CallbackDelivered: delivered}, nil
+}
+
+// RunPruning периодически удаляет закрытые Invoice песочницы старше retention, пока не отменен ctx
+func (s *Service) RunPruning(ctx context.Context, interval time.Duration, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if pruned := s.store.PruneInvoices(ctx, s.clock.Now().Add(-retention)); pruned > 0 {
+				log.Ctx(ctx).Info().Int("count", pruned).Msg("sandbox invoices pruned")
+			}
+		}
+	}
+}
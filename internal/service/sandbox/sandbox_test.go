@@ -0,0 +1,88 @@
+package sandbox_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"mateo/internal/callback"
+	"mateo/internal/domain"
+	"mateo/internal/fake"
+	mock_sandbox "mateo/internal/mock/sandbox"
+	"mateo/internal/service/sandbox"
+)
+
+var body = []byte(`{"merchantID":"merchant-1","outcome":"success"}`)
+
+func newService(t *testing.T) (*sandbox.Service, *mock_sandbox.MockStore, *mock_sandbox.MockNotifier) {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+	store := mock_sandbox.NewMockStore(ctrl)
+	notifier := mock_sandbox.NewMockNotifier(ctrl)
+	clock := fake.NewClock(time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC))
+	return sandbox.NewService(store, notifier, clock), store, notifier
+}
+
+func TestSimulateRequiresSignature(t *testing.T) {
+	invoice := &domain.Invoice{ID: "invoice-1", MerchantID: "merchant-1", CallbackKey: "key", Status: domain.InvoiceStatusCreated}
+
+	cases := []struct {
+		name    string
+		invoice domain.Invoice
+		signed  domain.SignedBody
+	}{
+		{"missing signature", *invoice, domain.SignedBody{Body: body}},
+		{"other key", *invoice, domain.SignedBody{Body: body, Signature: callback.Sign(body, "other")}},
+		{"other body", *invoice, domain.SignedBody{Body: []byte(`{}`), Signature: callback.Sign(body, "key")}},
+		{
+			"invoice without key",
+			domain.Invoice{ID: "invoice-1", MerchantID: "merchant-1", Status: domain.InvoiceStatusCreated},
+			domain.SignedBody{Body: body, Signature: callback.Sign(body, "")},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			service, store, _ := newService(t)
+			stored := c.invoice
+			store.EXPECT().GetInvoice(gomock.Any(), "invoice-1").Return(&stored, nil)
+
+			_, err := service.Simulate(context.Background(), "merchant-1", "invoice-1", domain.SandboxOutcomeSuccess, c.signed)
+			assert.True(t, errors.Is(err, domain.ErrorInvalidSignature), "got error %v", err)
+		})
+	}
+}
+
+func TestSimulateSigned(t *testing.T) {
+	service, store, notifier := newService(t)
+	store.EXPECT().GetInvoice(gomock.Any(), "invoice-1").
+		Return(&domain.Invoice{ID: "invoice-1", MerchantID: "merchant-1", CallbackKey: "key", Status: domain.InvoiceStatusCreated}, nil)
+	paid := &domain.Invoice{ID: "invoice-1", MerchantID: "merchant-1", CallbackKey: "key", Status: domain.InvoiceStatusSuccess}
+	store.EXPECT().FinalizeInvoice(gomock.Any(), "invoice-1", domain.InvoiceStatusSuccess).Return(paid, nil)
+	notifier.EXPECT().Send(gomock.Any(), paid).Return(callback.ErrorForbiddenHost)
+
+	simulation, err := service.Simulate(
+		context.Background(), "merchant-1", "invoice-1", domain.SandboxOutcomeSuccess,
+		domain.SignedBody{Body: body, Signature: callback.Sign(body, "key")},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, paid, simulation.Invoice)
+	assert.False(t, simulation.CallbackDelivered)
+}
+
+func TestSimulateOtherMerchant(t *testing.T) {
+	service, store, _ := newService(t)
+	store.EXPECT().GetInvoice(gomock.Any(), "invoice-1").
+		Return(&domain.Invoice{ID: "invoice-1", MerchantID: "merchant-2", CallbackKey: "key"}, nil)
+
+	_, err := service.Simulate(
+		context.Background(), "merchant-1", "invoice-1", domain.SandboxOutcomeSuccess,
+		domain.SignedBody{Body: body, Signature: callback.Sign(body, "key")},
+	)
+	assert.True(t, errors.Is(err, domain.ErrorInvoiceNotFound), "got error %v", err)
+}
@@ -0,0 +1,22 @@
+package memory
+
+import (
+	"mateo/internal/service/invoice"
+	"mateo/internal/service/merchant"
+	"mateo/internal/service/payer"
+	"mateo/internal/service/quarantine"
+	"mateo/internal/service/requisite"
+	"mateo/internal/service/sandbox"
+)
+
+var (
+	_ merchant.Store   = (*Store)(nil)
+	_ invoice.Store    = (*Store)(nil)
+	_ requisite.Store  = (*Store)(nil)
+	_ quarantine.Store = (*Store)(nil)
+	_ payer.Store      = (*Store)(nil)
+
+	_ invoice.Store   = (*SandboxPool)(nil)
+	_ requisite.Store = (*SandboxPool)(nil)
+	_ sandbox.Store   = (*SandboxPool)(nil)
+)
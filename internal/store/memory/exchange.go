@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+	"time"
+)
+
+func (s *Store) GetExchangeRate(ctx context.Context) (decimal.Decimal, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasExchangeSet {
+		return decimal.Decimal{}, domain.ErrorFailedGetExchangeRate
+	}
+	return s.exchangeRate, nil
+}
+
+func (s *Store) GetExchangeRateUpdatedAt(ctx context.Context) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasExchangeSet {
+		return time.Time{}, domain.ErrorFailedGetExchangeRate
+	}
+	return s.exchangeSetAt, nil
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	return nil
+}
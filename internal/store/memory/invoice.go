@@ -0,0 +1,193 @@
+package memory
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+	"sort"
+	"time"
+)
+
+// CreateInvoice создает Invoice и ставит hold на кошелек трейдера, как pg.Store.CreateInvoice.
+// Обновляет ID и CreatedAt в исходном Invoice.
+func (s *Store) CreateInvoice(ctx context.Context, invoice *domain.Invoice) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.accounts[invoice.TraiderAccountID]
+	if !ok {
+		return "", domain.ErrorFailedCreateInvoice
+	}
+	wallet, ok := s.wallets[account.WalletID]
+	if !ok {
+		return "", domain.ErrorFailedCreateInvoice
+	}
+
+	if wallet.PayInBalance.Sub(s.heldLocked(wallet.ID)).LessThan(invoice.Amount) {
+		return "", domain.ErrorInsufficientBalance
+	}
+
+	invoice.ID = s.ids.NewID()
+	invoice.CreatedAt = s.clock.Now()
+
+	stored := *invoice
+	s.invoices[stored.ID] = &stored
+
+	hold := &domain.WalletHold{
+		ID:        s.ids.NewID(),
+		WalletID:  wallet.ID,
+		InvoiceID: stored.ID,
+		Amount:    stored.Amount,
+		Status:    domain.HoldStatusActive,
+	}
+	s.holds[hold.InvoiceID] = hold
+
+	return invoice.ID, nil
+}
+
+// FinalizeInvoice переводит активный Invoice в финальный статус и закрывает его hold
+func (s *Store) FinalizeInvoice(
+	ctx context.Context,
+	invoiceID string,
+	status domain.InvoiceStatus,
+) (*domain.Invoice, error) {
+	if !status.IsFinal() {
+		return nil, domain.ErrorInvalidStatus
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.invoices[invoiceID]
+	if !ok {
+		return nil, domain.ErrorInvoiceNotFound
+	}
+	if stored.Status != domain.InvoiceStatusCreated {
+		return nil, domain.ErrorInvoiceNotActive
+	}
+	s.setInvoiceStatus(stored, status)
+
+	invoice := *stored
+	return &invoice, nil
+}
+
+// SetInvoiceStatus меняет статус Invoice в обход FinalizeInvoice, как это делает внешняя
+// платежная система. Hold закрывается так же, как триггером "InvoiceIn_close_hold" в Postgres.
+func (s *Store) SetInvoiceStatus(invoiceID string, status domain.InvoiceStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.invoices[invoiceID]; ok {
+		s.setInvoiceStatus(stored, status)
+	}
+}
+
+// setInvoiceStatus меняет статус и при выходе Invoice из CREATED закрывает его hold
+func (s *Store) setInvoiceStatus(stored *domain.Invoice, status domain.InvoiceStatus) {
+	previous := stored.Status
+	stored.Status = status
+	if previous != domain.InvoiceStatusCreated || status == domain.InvoiceStatusCreated {
+		return
+	}
+
+	if hold, ok := s.holds[stored.ID]; ok && hold.Status == domain.HoldStatusActive {
+		if status.IsSuccess() {
+			hold.Status = domain.HoldStatusCaptured
+			if wallet, ok := s.wallets[hold.WalletID]; ok {
+				wallet.PayInBalance = wallet.PayInBalance.Sub(hold.Amount)
+			}
+		} else {
+			hold.Status = domain.HoldStatusReleased
+		}
+	}
+}
+
+// SelectExpiredInvoiceIDs возвращает ID активных Invoice, у которых истекло время оплаты
+func (s *Store) SelectExpiredInvoiceIDs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var expired []*domain.Invoice
+	for _, invoice := range s.invoices {
+		if invoice.Status == domain.InvoiceStatusCreated && !invoice.TimeExpires.After(now) {
+			expired = append(expired, invoice)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].TimeExpires.Before(expired[j].TimeExpires)
+	})
+
+	var invoiceIDs []string
+	for _, invoice := range expired {
+		if len(invoiceIDs) == limit {
+			break
+		}
+		invoiceIDs = append(invoiceIDs, invoice.ID)
+	}
+
+	return invoiceIDs, nil
+}
+
+// heldLocked возвращает сумму активных hold кошелька; вызывается под s.mu
+func (s *Store) heldLocked(walletID string) decimal.Decimal {
+	held := decimal.Zero
+	for _, hold := range s.holds {
+		if hold.WalletID == walletID && hold.Status == domain.HoldStatusActive {
+			held = held.Add(hold.Amount)
+		}
+	}
+	return held
+}
+
+func (s *Store) GetInvoice(ctx context.Context, invoiceID string) (*domain.Invoice, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, ok := s.invoices[invoiceID]
+	if !ok {
+		return nil, domain.ErrorInvoiceNotFound
+	}
+	invoice := *stored
+	return &invoice, nil
+}
+
+// AppealInvoice признает оплаченным по апелляции уже истекший Invoice. Hold истекшего Invoice
+// освобожден, поэтому сумма списывается с кошелька напрямую.
+func (s *Store) AppealInvoice(ctx context.Context, invoiceID string) (*domain.Invoice, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.invoices[invoiceID]
+	if !ok {
+		return nil, domain.ErrorInvoiceNotFound
+	}
+	if stored.Status != domain.InvoiceStatusExpired {
+		return nil, domain.ErrorInvoiceNotActive
+	}
+	stored.Status = domain.InvoiceStatusSuccessAppeal
+
+	if hold, ok := s.holds[invoiceID]; ok {
+		hold.Status = domain.HoldStatusCaptured
+		if wallet, ok := s.wallets[hold.WalletID]; ok {
+			wallet.PayInBalance = wallet.PayInBalance.Sub(hold.Amount)
+		}
+	}
+
+	invoice := *stored
+	return &invoice, nil
+}
+
+// PruneInvoices удаляет закрытые Invoice, созданные раньше before, вместе с их hold
+func (s *Store) PruneInvoices(ctx context.Context, before time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pruned := 0
+	for id, invoice := range s.invoices {
+		if invoice.Status.IsFinal() && invoice.CreatedAt.Before(before) {
+			delete(s.invoices, id)
+			delete(s.holds, id)
+			pruned++
+		}
+	}
+	return pruned
+}
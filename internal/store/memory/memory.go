@@ -0,0 +1,228 @@
+// Package memory реализует хранилища сервисов в памяти процесса с той же логикой отбора,
+// что и SQL в internal/store/pg. Используется в тестах и для песочницы.
+package memory
+
+import (
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+	"sync"
+	"time"
+)
+
+// counterBucket гранулярность скользящих окон, как у корзин счетчиков в pg
+const counterBucket = 15 * time.Minute
+
+type Wallet struct {
+	ID           string
+	PayInBalance decimal.Decimal
+}
+
+type TraderAccount struct {
+	ID        string
+	UserID    string
+	WalletID  string
+	TeamID    string
+	IsCanWork bool
+	IsBlocked bool
+	// BlockedUntil срок блокировки; после него IsBlocked не учитывается
+	BlockedUntil           *time.Time
+	MinInvoiceAmountCard   decimal.Decimal
+	MinInvoiceAmountWallet decimal.Decimal
+	MinInvoiceAmountSBP    decimal.Decimal
+	IsWorkOnCardPayIn      bool
+	IsWorkOnWalletPayIn    bool
+	IsWorkOnSBPPayIn       bool
+	MaxInvoiceAmount       decimal.Decimal
+	MaxActiveInvoices      *int
+}
+
+type Terminal struct {
+	ID               string
+	TraiderAccountID string
+	IsCanWork        bool
+	IsBlocked        bool
+	BlockedUntil     *time.Time
+	// QuarantinedUntil конец автоматического карантина после неоплаченных Invoice
+	QuarantinedUntil              *time.Time
+	QuarantineReason              string
+	MinInvoiceAmount              decimal.Decimal
+	MaxInvoiceAmount              decimal.Decimal
+	DailyLimitMoney               *decimal.Decimal
+	DailyLimitMoneyWindowHours    *int
+	DailyLimitInvoices            *int
+	DailyLimitInvoicesWindowHours *int
+	MaxActiveInvoice              *int
+	// InvoiceInterval минимальный интервал между Invoice в минутах
+	InvoiceInterval int
+}
+
+type Requisite struct {
+	ID                            string
+	TerminalID                    string
+	BankID                        string
+	Type                          domain.RequisiteType
+	Name                          string
+	PhoneNumber                   string
+	CardNumber                    string
+	WalletNumber                  string
+	IsCanWork                     bool
+	IsBlocked                     bool
+	BlockedUntil                  *time.Time
+	QuarantinedUntil              *time.Time
+	QuarantineReason              string
+	MinInvoiceAmount              decimal.Decimal
+	MaxInvoiceAmount              decimal.Decimal
+	DailyLimitInvoices            *int
+	DailyLimitInvoicesWindowHours *int
+	MaxActiveInvoice              *int
+	// InvoiceInterval минимальный интервал между Invoice в минутах
+	InvoiceInterval int
+}
+
+type Store struct {
+	mu sync.RWMutex
+
+	clock            domain.Clock
+	ids              domain.IDGenerator
+	location         *time.Location
+	maxRollingWindow time.Duration
+
+	merchants      map[string]domain.Merchant
+	teams          map[string]domain.Team
+	banks          map[string]domain.Provider
+	wallets        map[string]*Wallet
+	accounts       map[string]TraderAccount
+	terminals      map[string]Terminal
+	requisites     map[string]Requisite
+	merchantLinks  map[string]map[string]bool
+	invoices       map[string]*domain.Invoice
+	holds          map[string]*domain.WalletHold
+	notifications  []*memoryNotification
+	payerBlocks    map[payerBlockKey]*domain.PayerBlock
+	exchangeRate   decimal.Decimal
+	hasExchangeSet bool
+	exchangeSetAt  time.Time
+}
+
+func NewStore(
+	clock domain.Clock,
+	ids domain.IDGenerator,
+	location *time.Location,
+	maxRollingWindow time.Duration,
+) *Store {
+	return &Store{
+		clock:            clock,
+		ids:              ids,
+		location:         location,
+		maxRollingWindow: maxRollingWindow,
+		merchants:        make(map[string]domain.Merchant),
+		teams:            make(map[string]domain.Team),
+		banks:            make(map[string]domain.Provider),
+		wallets:          make(map[string]*Wallet),
+		accounts:         make(map[string]TraderAccount),
+		terminals:        make(map[string]Terminal),
+		requisites:       make(map[string]Requisite),
+		merchantLinks:    make(map[string]map[string]bool),
+		invoices:         make(map[string]*domain.Invoice),
+		holds:            make(map[string]*domain.WalletHold),
+		payerBlocks:      make(map[payerBlockKey]*domain.PayerBlock),
+	}
+}
+
+func (s *Store) PutMerchant(merchant domain.Merchant) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.merchants[merchant.ID] = merchant
+}
+
+func (s *Store) PutTeam(team domain.Team) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.teams[team.ID] = team
+}
+
+// PutBank сохраняет банк; используются только ID и Name
+func (s *Store) PutBank(id string, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.banks[id] = domain.Provider{ID: id, Name: name, IsActive: true}
+}
+
+func (s *Store) PutWallet(wallet Wallet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wallets[wallet.ID] = &wallet
+}
+
+func (s *Store) PutTraderAccount(account TraderAccount) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts[account.ID] = account
+}
+
+func (s *Store) PutTerminal(terminal Terminal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.terminals[terminal.ID] = terminal
+}
+
+func (s *Store) PutRequisite(requisite Requisite) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requisites[requisite.ID] = requisite
+}
+
+// LinkMerchant разрешает аккаунту трейдера принимать Invoice мерчанта
+func (s *Store) LinkMerchant(merchantID string, traiderAccountID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.merchantLinks[merchantID] == nil {
+		s.merchantLinks[merchantID] = make(map[string]bool)
+	}
+	s.merchantLinks[merchantID][traiderAccountID] = true
+}
+
+func (s *Store) SetExchangeRate(rate decimal.Decimal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exchangeRate = rate
+	s.hasExchangeSet = true
+	s.exchangeSetAt = s.clock.Now()
+}
+
+// Wallet возвращает копию кошелька, например для проверки списаний
+func (s *Store) Wallet(id string) (Wallet, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	wallet, ok := s.wallets[id]
+	if !ok {
+		return Wallet{}, false
+	}
+	return *wallet, true
+}
+
+// Invoice возвращает копию Invoice
+func (s *Store) Invoice(id string) (domain.Invoice, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	invoice, ok := s.invoices[id]
+	if !ok {
+		return domain.Invoice{}, false
+	}
+	return *invoice, true
+}
+
+// limitWindow повторяет pg.Store.limitWindow: начало бизнес-дня и нижняя граница окон лимитов
+func (s *Store) limitWindow(now time.Time) (dayStart time.Time, windowStart time.Time) {
+	dayStart = domain.StartOfDay(now, s.location)
+	windowStart = dayStart
+	if rollingStart := floorToBucket(now.Add(-s.maxRollingWindow)); rollingStart.Before(windowStart) {
+		windowStart = rollingStart
+	}
+	return dayStart, windowStart
+}
+
+// floorToBucket округляет момент вниз до начала корзины
+func floorToBucket(t time.Time) time.Time {
+	return t.Truncate(counterBucket)
+}
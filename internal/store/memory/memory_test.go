@@ -0,0 +1,11 @@
+package memory_test
+
+import (
+	"testing"
+
+	"mateo/internal/store/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, storetest.Memory)
+}
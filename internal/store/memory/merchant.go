@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+)
+
+func (s *Store) GetMerchantByMerchantID(ctx context.Context, merchantID string) (*domain.Merchant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	merchant, ok := s.merchants[merchantID]
+	if !ok {
+		return nil, domain.ErrorMerchantNotFound
+	}
+	return &merchant, nil
+}
+
+func (s *Store) GetMerchantDailyTurnover(ctx context.Context, merchantID string) (domain.MerchantTurnover, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dayStart, _ := s.limitWindow(s.clock.Now())
+
+	turnover := domain.MerchantTurnover{Sum: decimal.Zero}
+	for _, invoice := range s.invoices {
+		if invoice.MerchantID != merchantID || floorToBucket(invoice.CreatedAt).Before(dayStart) {
+			continue
+		}
+		if invoice.Status == domain.InvoiceStatusCreated || invoice.Status.IsSuccess() {
+			turnover.Count++
+			turnover.Sum = turnover.Sum.Add(invoice.Amount)
+		}
+	}
+	return turnover, nil
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"mateo/internal/domain"
+	"sort"
+	"time"
+)
+
+// payerBlockKey запись списка блокировки плательщиков
+type payerBlockKey struct {
+	scope   domain.PayerBlockScope
+	scopeID string
+	payerID string
+}
+
+// PutPayerBlock добавляет плательщика в список блокировки мерчанта или команды
+func (s *Store) PutPayerBlock(block domain.PayerBlock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.payerBlocks[payerBlockKey{block.Scope, block.ScopeID, block.PayerID}] = &block
+}
+
+func (s *Store) IsPayerBlocked(ctx context.Context, merchantID string, payerID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.payerBlocks[payerBlockKey{domain.PayerBlockScopeMerchant, merchantID, payerID}] != nil, nil
+}
+
+func (s *Store) GetPayerActivity(
+	ctx context.Context,
+	merchantID string,
+	payerID string,
+	since time.Time,
+) (domain.PayerActivity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var activity domain.PayerActivity
+	for _, invoice := range s.invoices {
+		if invoice.MerchantID != merchantID || invoice.PayerID != payerID || payerID == "" {
+			continue
+		}
+		if !invoice.CreatedAt.Before(since) {
+			activity.Created++
+		}
+		if invoice.Status == domain.InvoiceStatusCreated {
+			activity.Active++
+		}
+	}
+	return activity, nil
+}
+
+func (s *Store) ListPayerBlocks(ctx context.Context, scope domain.PayerBlockScope, scopeID string) ([]*domain.PayerBlock, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	blocks := []*domain.PayerBlock{}
+	for key, block := range s.payerBlocks {
+		if key.scope == scope && key.scopeID == scopeID {
+			b := *block
+			blocks = append(blocks, &b)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		if !blocks[i].CreatedAt.Equal(blocks[j].CreatedAt) {
+			return blocks[i].CreatedAt.After(blocks[j].CreatedAt)
+		}
+		return blocks[i].PayerID < blocks[j].PayerID
+	})
+	return blocks, nil
+}
+
+// payerExpiredLocked считает истекшие Invoice плательщика у мерчанта по реквизитам в окне счетчиков
+func (s *Store) payerExpiredLocked(now time.Time, merchantID string, payerID string) map[string]int {
+	expired := make(map[string]int)
+	if payerID == "" {
+		return expired
+	}
+
+	_, windowStart := s.limitWindow(now)
+	for _, invoice := range s.invoices {
+		if invoice.MerchantID == merchantID && invoice.PayerID == payerID &&
+			invoice.Status == domain.InvoiceStatusExpired && !invoice.CreatedAt.Before(windowStart) {
+			expired[invoice.RequisiteID]++
+		}
+	}
+	return expired
+}
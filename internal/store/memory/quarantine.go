@@ -0,0 +1,182 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"mateo/internal/domain"
+	"sort"
+	"time"
+)
+
+// memoryNotification уведомление outbox вместе с отметкой об отправке
+type memoryNotification struct {
+	notification domain.TeamNotification
+	sent         bool
+}
+
+func (s *Store) SelectRecentOutcomes(
+	ctx context.Context,
+	entity domain.BlockEntity,
+	entityID string,
+	limit int,
+) ([]domain.InvoiceStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invoices, err := s.outcomesLocked(entity, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(invoices, func(i, j int) bool {
+		return invoices[i].CreatedAt.After(invoices[j].CreatedAt)
+	})
+
+	var statuses []domain.InvoiceStatus
+	for _, invoice := range invoices {
+		if len(statuses) == limit {
+			break
+		}
+		statuses = append(statuses, invoice.Status)
+	}
+	return statuses, nil
+}
+
+func (s *Store) SelectOutcomeStats(
+	ctx context.Context,
+	entity domain.BlockEntity,
+	entityID string,
+	since time.Time,
+) (domain.OutcomeStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invoices, err := s.outcomesLocked(entity, entityID)
+	if err != nil {
+		return domain.OutcomeStats{}, err
+	}
+
+	var stats domain.OutcomeStats
+	for _, invoice := range invoices {
+		if invoice.CreatedAt.Before(since) {
+			continue
+		}
+		stats.Total++
+		if invoice.Status.IsSuccess() {
+			stats.Success++
+		}
+	}
+	return stats, nil
+}
+
+// outcomesLocked возвращает оплаченные и истекшие Invoice сущности, созданные после ее прошлого карантина
+func (s *Store) outcomesLocked(entity domain.BlockEntity, entityID string) ([]*domain.Invoice, error) {
+	var (
+		quarantinedUntil *time.Time
+		invoiceEntityID  func(*domain.Invoice) string
+	)
+	switch entity {
+	case domain.BlockEntityRequisite:
+		quarantinedUntil = s.requisites[entityID].QuarantinedUntil
+		invoiceEntityID = func(i *domain.Invoice) string { return i.RequisiteID }
+	case domain.BlockEntityTerminal:
+		quarantinedUntil = s.terminals[entityID].QuarantinedUntil
+		invoiceEntityID = func(i *domain.Invoice) string { return i.TerminalID }
+	default:
+		return nil, domain.ErrorUnknownBlockEntity
+	}
+
+	var invoices []*domain.Invoice
+	for _, invoice := range s.invoices {
+		if invoiceEntityID(invoice) != entityID ||
+			!(invoice.Status.IsSuccess() || invoice.Status == domain.InvoiceStatusExpired) ||
+			(quarantinedUntil != nil && invoice.CreatedAt.Before(*quarantinedUntil)) {
+			continue
+		}
+		invoices = append(invoices, invoice)
+	}
+	return invoices, nil
+}
+
+func (s *Store) QuarantineEntity(ctx context.Context, quarantine domain.Quarantine) (bool, error) {
+	payload, err := json.Marshal(map[string]any{
+		"entity":   quarantine.Entity,
+		"entityId": quarantine.EntityID,
+		"reason":   quarantine.Reason,
+		"until":    quarantine.Until,
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "marshal quarantine payload")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	var terminalID string
+
+	switch quarantine.Entity {
+	case domain.BlockEntityRequisite:
+		requisite, ok := s.requisites[quarantine.EntityID]
+		if !ok || quarantinedAt(requisite.QuarantinedUntil, now) {
+			return false, nil
+		}
+		requisite.QuarantinedUntil, requisite.QuarantineReason = &quarantine.Until, quarantine.Reason
+		s.requisites[requisite.ID] = requisite
+		terminalID = requisite.TerminalID
+	case domain.BlockEntityTerminal:
+		terminal, ok := s.terminals[quarantine.EntityID]
+		if !ok || quarantinedAt(terminal.QuarantinedUntil, now) {
+			return false, nil
+		}
+		terminal.QuarantinedUntil, terminal.QuarantineReason = &quarantine.Until, quarantine.Reason
+		s.terminals[terminal.ID] = terminal
+		terminalID = terminal.ID
+	default:
+		return false, domain.ErrorUnknownBlockEntity
+	}
+
+	s.notifications = append(s.notifications, &memoryNotification{notification: domain.TeamNotification{
+		ID:        s.ids.NewID(),
+		TeamID:    s.accounts[s.terminals[terminalID].TraiderAccountID].TeamID,
+		Kind:      domain.TeamNotificationKindQuarantine,
+		Payload:   payload,
+		CreatedAt: now,
+	}})
+	return true, nil
+}
+
+func (s *Store) SelectPendingNotifications(ctx context.Context, limit int) ([]*domain.TeamNotification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var notifications []*domain.TeamNotification
+	for _, n := range s.notifications {
+		if len(notifications) == limit {
+			break
+		}
+		if !n.sent {
+			notification := n.notification
+			notifications = append(notifications, &notification)
+		}
+	}
+	return notifications, nil
+}
+
+func (s *Store) MarkNotificationSent(ctx context.Context, notificationID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, n := range s.notifications {
+		if n.notification.ID == notificationID {
+			n.sent = true
+		}
+	}
+	return nil
+}
+
+// quarantinedAt сообщает, что карантин действует в момент now
+func quarantinedAt(quarantinedUntil *time.Time, now time.Time) bool {
+	return quarantinedUntil != nil && quarantinedUntil.After(now)
+}
@@ -0,0 +1,350 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+	"sort"
+	"time"
+)
+
+// candidate реквизит вместе со связанными строками, как в соединении запроса кандидатов pg
+type candidate struct {
+	account   TraderAccount
+	wallet    *Wallet
+	terminal  Terminal
+	requisite Requisite
+	bankName  string
+}
+
+// load сводка по Invoice, из которой проверяются лимиты; заменяет счетчики загрузки pg
+type load struct {
+	activeCount     map[string]int
+	lastInvoiceTime map[string]time.Time
+	activeAmounts   map[string][]decimal.Decimal
+	// counted Invoice, которые идут в дневные и скользящие лимиты, по терминалу и реквизиту
+	counted map[string][]*domain.Invoice
+	// closed и success исходы Invoice в окне счетчиков, как closed_count и success_count корзин pg
+	closed  map[string]int
+	success map[string]int
+}
+
+func (s *Store) SelectAvailableRequisites(
+	ctx context.Context,
+	merchantID string,
+	amount decimal.Decimal,
+	requisiteType domain.RequisiteType,
+	banks domain.BankPreference,
+	payerID string,
+) ([]*domain.Requisite, error) {
+	return s.selectCandidates(merchantID, []decimal.Decimal{amount}, requisiteType, banks, payerID, false)
+}
+
+func (s *Store) SelectAvailableRequisitesFlexible(
+	ctx context.Context,
+	merchantID string,
+	flexibleAmountMin decimal.Decimal,
+	flexibleAmountMax decimal.Decimal,
+	flexibleAmountStep decimal.Decimal,
+	requisiteType domain.RequisiteType,
+	banks domain.BankPreference,
+	payerID string,
+) ([]*domain.Requisite, error) {
+	if flexibleAmountStep.LessThanOrEqual(decimal.Zero) {
+		return nil, fmt.Errorf("flexibleAmountStep must be positive")
+	}
+	if flexibleAmountMin.GreaterThan(flexibleAmountMax) {
+		return nil, fmt.Errorf("flexibleAmountMin cannot be greater than flexibleAmountMax")
+	}
+
+	var amounts []decimal.Decimal
+	for amount := flexibleAmountMin; amount.LessThanOrEqual(flexibleAmountMax); amount = amount.Add(flexibleAmountStep) {
+		amounts = append(amounts, amount)
+	}
+
+	return s.selectCandidates(merchantID, amounts, requisiteType, banks, payerID, true)
+}
+
+// selectCandidates возвращает реквизиты, прошедшие все условия отбора хотя бы для одной суммы.
+// Для гибкой суммы выбирается наименьшая подходящая сумма, результат упорядочен по ID реквизита.
+func (s *Store) selectCandidates(
+	merchantID string,
+	amounts []decimal.Decimal,
+	requisiteType domain.RequisiteType,
+	banks domain.BankPreference,
+	payerID string,
+	flexible bool,
+) ([]*domain.Requisite, error) {
+	switch requisiteType {
+	case domain.RequisiteTypeCard, domain.RequisiteTypeWallet, domain.RequisiteTypeSBP:
+	default:
+		return nil, fmt.Errorf("unsupported requisite type: %s", requisiteType)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.clock.Now()
+	l := s.loadLocked(now)
+	payerExpired := s.payerExpiredLocked(now, merchantID, payerID)
+
+	requisiteIDs := make([]string, 0, len(s.requisites))
+	for id := range s.requisites {
+		requisiteIDs = append(requisiteIDs, id)
+	}
+	sort.Strings(requisiteIDs)
+
+	var requisites []*domain.Requisite
+	for _, id := range requisiteIDs {
+		c, ok := s.joinLocked(s.requisites[id])
+		if !ok || !s.matchesStatic(c, l, now, merchantID, requisiteType, banks) {
+			continue
+		}
+		if payerID != "" && s.payerBlocks[payerBlockKey{domain.PayerBlockScopeTeam, c.account.TeamID, payerID}] != nil {
+			continue
+		}
+
+		for _, amount := range amounts {
+			if !s.matchesAmount(c, l, now, amount, requisiteType) {
+				continue
+			}
+
+			r := &domain.Requisite{
+				ID:               c.requisite.ID,
+				Type:             requisiteType,
+				PhoneNumber:      c.requisite.PhoneNumber,
+				CardNumber:       c.requisite.CardNumber,
+				WalletNumber:     c.requisite.WalletNumber,
+				RecipientName:    c.requisite.Name,
+				TerminalID:       c.terminal.ID,
+				UserID:           c.account.UserID,
+				BankID:           c.requisite.BankID,
+				BankName:         c.bankName,
+				TeamID:           c.account.TeamID,
+				TraiderAccountID: c.account.ID,
+				Stats: domain.RequisiteStats{
+					ActiveInvoices:   l.activeCount[c.requisite.ID],
+					TerminalClosed:   l.closed[c.terminal.ID],
+					TerminalSuccess:  l.success[c.terminal.ID],
+					RequisiteClosed:  l.closed[c.requisite.ID],
+					RequisiteSuccess: l.success[c.requisite.ID],
+					PayerExpired:     payerExpired[c.requisite.ID],
+				},
+			}
+			if last, ok := l.lastInvoiceTime[c.requisite.ID]; ok {
+				r.Stats.LastInvoiceTime = &last
+			}
+			if flexible {
+				r.FlexibleSelectedAmount = amount
+			}
+			requisites = append(requisites, r)
+			break
+		}
+	}
+
+	return requisites, nil
+}
+
+// joinLocked находит терминал, аккаунт, кошелек и банк реквизита
+func (s *Store) joinLocked(requisite Requisite) (candidate, bool) {
+	terminal, ok := s.terminals[requisite.TerminalID]
+	if !ok {
+		return candidate{}, false
+	}
+	account, ok := s.accounts[terminal.TraiderAccountID]
+	if !ok {
+		return candidate{}, false
+	}
+	wallet, ok := s.wallets[account.WalletID]
+	if !ok {
+		return candidate{}, false
+	}
+	bank, ok := s.banks[requisite.BankID]
+	if !ok {
+		return candidate{}, false
+	}
+
+	return candidate{
+		account:   account,
+		wallet:    wallet,
+		terminal:  terminal,
+		requisite: requisite,
+		bankName:  bank.Name,
+	}, true
+}
+
+// loadLocked собирает активные Invoice, время последнего Invoice и Invoice в окнах лимитов
+func (s *Store) loadLocked(now time.Time) *load {
+	_, windowStart := s.limitWindow(now)
+
+	l := &load{
+		activeCount:     make(map[string]int),
+		lastInvoiceTime: make(map[string]time.Time),
+		activeAmounts:   make(map[string][]decimal.Decimal),
+		counted:         make(map[string][]*domain.Invoice),
+		closed:          make(map[string]int),
+		success:         make(map[string]int),
+	}
+
+	for _, invoice := range s.invoices {
+		entities := []string{invoice.TraiderAccountID, invoice.TerminalID, invoice.RequisiteID}
+
+		for _, id := range entities {
+			if last, ok := l.lastInvoiceTime[id]; !ok || invoice.CreatedAt.After(last) {
+				l.lastInvoiceTime[id] = invoice.CreatedAt
+			}
+		}
+
+		if invoice.Status == domain.InvoiceStatusCreated {
+			for _, id := range entities {
+				l.activeCount[id]++
+			}
+			l.activeAmounts[invoice.RequisiteID] = append(l.activeAmounts[invoice.RequisiteID], invoice.Amount)
+		}
+
+		if (invoice.Status == domain.InvoiceStatusCreated || invoice.Status.IsSuccess()) &&
+			!floorToBucket(invoice.CreatedAt).Before(windowStart) {
+			l.counted[invoice.TerminalID] = append(l.counted[invoice.TerminalID], invoice)
+			l.counted[invoice.RequisiteID] = append(l.counted[invoice.RequisiteID], invoice)
+		}
+
+		if (invoice.Status == domain.InvoiceStatusExpired || invoice.Status.IsSuccess()) &&
+			!floorToBucket(invoice.CreatedAt).Before(windowStart) {
+			for _, id := range entities {
+				l.closed[id]++
+				if invoice.Status.IsSuccess() {
+					l.success[id]++
+				}
+			}
+		}
+	}
+
+	return l
+}
+
+// window возвращает число и сумму Invoice сущности с начала окна лимита.
+// Без windowHours окно — текущий бизнес-день, иначе скользящее окно из корзин.
+func (s *Store) window(l *load, entityID string, now time.Time, windowHours *int) (int, decimal.Decimal) {
+	start, _ := s.limitWindow(now)
+	if windowHours != nil {
+		start = floorToBucket(now.Add(-time.Duration(*windowHours) * time.Hour))
+	}
+
+	count, sum := 0, decimal.Zero
+	for _, invoice := range l.counted[entityID] {
+		if !floorToBucket(invoice.CreatedAt).Before(start) {
+			count++
+			sum = sum.Add(invoice.Amount)
+		}
+	}
+	return count, sum
+}
+
+// intervalPassed сообщает, что с последнего Invoice прошло не меньше intervalMinutes
+func intervalPassed(l *load, entityID string, now time.Time, intervalMinutes int) bool {
+	last, ok := l.lastInvoiceTime[entityID]
+	return !ok || now.Sub(last) >= time.Duration(intervalMinutes)*time.Minute
+}
+
+// blockedAt сообщает, что блокировка действует в момент now
+func blockedAt(isBlocked bool, blockedUntil *time.Time, now time.Time) bool {
+	return isBlocked && (blockedUntil == nil || blockedUntil.After(now))
+}
+
+// underLimit сообщает, что значение меньше необязательного лимита
+func underLimit(value int, limit *int) bool {
+	return limit == nil || value < *limit
+}
+
+// matchesStatic проверяет условия отбора, не зависящие от суммы
+func (s *Store) matchesStatic(
+	c candidate,
+	l *load,
+	now time.Time,
+	merchantID string,
+	requisiteType domain.RequisiteType,
+	banks domain.BankPreference,
+) bool {
+	account, terminal, requisite := c.account, c.terminal, c.requisite
+
+	var accountIsWork bool
+	switch requisiteType {
+	case domain.RequisiteTypeCard:
+		accountIsWork = account.IsWorkOnCardPayIn
+	case domain.RequisiteTypeWallet:
+		accountIsWork = account.IsWorkOnWalletPayIn
+	case domain.RequisiteTypeSBP:
+		accountIsWork = account.IsWorkOnSBPPayIn
+	}
+
+	terminalDailyCount, _ := s.window(l, terminal.ID, now, terminal.DailyLimitInvoicesWindowHours)
+	requisiteDailyCount, _ := s.window(l, requisite.ID, now, requisite.DailyLimitInvoicesWindowHours)
+
+	return account.IsCanWork &&
+		!blockedAt(account.IsBlocked, account.BlockedUntil, now) &&
+		accountIsWork &&
+		s.merchantLinks[merchantID][account.ID] &&
+		underLimit(l.activeCount[account.ID], account.MaxActiveInvoices) &&
+		terminal.IsCanWork &&
+		!blockedAt(terminal.IsBlocked, terminal.BlockedUntil, now) &&
+		!quarantinedAt(terminal.QuarantinedUntil, now) &&
+		intervalPassed(l, terminal.ID, now, terminal.InvoiceInterval) &&
+		underLimit(l.activeCount[terminal.ID], terminal.MaxActiveInvoice) &&
+		underLimit(terminalDailyCount, terminal.DailyLimitInvoices) &&
+		requisite.Type == requisiteType &&
+		requisite.IsCanWork &&
+		!blockedAt(requisite.IsBlocked, requisite.BlockedUntil, now) &&
+		!quarantinedAt(requisite.QuarantinedUntil, now) &&
+		underLimit(l.activeCount[requisite.ID], requisite.MaxActiveInvoice) &&
+		intervalPassed(l, requisite.ID, now, requisite.InvoiceInterval) &&
+		underLimit(requisiteDailyCount, requisite.DailyLimitInvoices) &&
+		(!banks.Strict || len(banks.Preferred) == 0 || banks.Prefers(requisite.BankID)) &&
+		!domain.Contains(banks.Excluded, requisite.BankID)
+}
+
+// matchesAmount проверяет условия отбора, зависящие от суммы Invoice
+func (s *Store) matchesAmount(
+	c candidate,
+	l *load,
+	now time.Time,
+	amount decimal.Decimal,
+	requisiteType domain.RequisiteType,
+) bool {
+	account, terminal, requisite := c.account, c.terminal, c.requisite
+
+	var accountMin decimal.Decimal
+	switch requisiteType {
+	case domain.RequisiteTypeCard:
+		accountMin = account.MinInvoiceAmountCard
+	case domain.RequisiteTypeWallet:
+		accountMin = account.MinInvoiceAmountWallet
+	case domain.RequisiteTypeSBP:
+		accountMin = account.MinInvoiceAmountSBP
+	}
+
+	if accountMin.GreaterThan(amount) ||
+		account.MaxInvoiceAmount.LessThan(amount) ||
+		c.wallet.PayInBalance.Sub(s.heldLocked(c.wallet.ID)).LessThan(amount) ||
+		terminal.MinInvoiceAmount.GreaterThan(amount) ||
+		terminal.MaxInvoiceAmount.LessThan(amount) ||
+		requisite.MinInvoiceAmount.GreaterThan(amount) ||
+		requisite.MaxInvoiceAmount.LessThan(amount) {
+		return false
+	}
+
+	if terminal.DailyLimitMoney != nil {
+		_, dailySum := s.window(l, terminal.ID, now, terminal.DailyLimitMoneyWindowHours)
+		if dailySum.Add(amount).GreaterThan(*terminal.DailyLimitMoney) {
+			return false
+		}
+	}
+
+	for _, active := range l.activeAmounts[requisite.ID] {
+		if active.Equal(amount) {
+			return false
+		}
+	}
+
+	return true
+}
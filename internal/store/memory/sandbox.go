@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+	"time"
+)
+
+const (
+	// sandboxMerchantID мерчант, к которому привязан фиктивный пул
+	sandboxMerchantID = "sandbox"
+	sandboxBankID     = "sandbox-bank"
+	sandboxAccountID  = "sandbox-account"
+	sandboxWalletID   = "sandbox-wallet"
+	sandboxTerminalID = "sandbox-terminal"
+
+	// sandboxRequisitesPerType сколько реквизитов каждого типа в пуле: активные Invoice
+	// на одинаковую сумму занимают разные реквизиты
+	sandboxRequisitesPerType = 20
+)
+
+// SandboxPool хранилище песочницы с фиктивным пулом реквизитов. Пул общий для всех
+// мерчантов песочницы, поэтому привязка мерчанта к аккаунту при отборе не проверяется.
+// В сервисе пул служит источником реквизитов для pg.SandboxStore, а Invoice хранятся в Postgres.
+type SandboxPool struct {
+	*Store
+}
+
+// NewSandboxPool создает хранилище и заполняет его фиктивными трейдером, банком и реквизитами
+func NewSandboxPool(clock domain.Clock, ids domain.IDGenerator) *SandboxPool {
+	store := NewStore(clock, ids, time.UTC, 24*time.Hour)
+
+	unlimited := decimal.NewFromInt(1_000_000_000)
+
+	store.PutMerchant(domain.Merchant{ID: sandboxMerchantID, IsSandbox: true})
+	store.PutBank(sandboxBankID, "Sandbox Bank")
+	store.PutWallet(Wallet{ID: sandboxWalletID, PayInBalance: unlimited})
+	store.PutTraderAccount(TraderAccount{
+		ID:                  sandboxAccountID,
+		UserID:              "sandbox-user",
+		WalletID:            sandboxWalletID,
+		IsCanWork:           true,
+		IsWorkOnCardPayIn:   true,
+		IsWorkOnWalletPayIn: true,
+		IsWorkOnSBPPayIn:    true,
+		MaxInvoiceAmount:    unlimited,
+	})
+	store.PutTerminal(Terminal{
+		ID:               sandboxTerminalID,
+		TraiderAccountID: sandboxAccountID,
+		IsCanWork:        true,
+		MaxInvoiceAmount: unlimited,
+	})
+	store.LinkMerchant(sandboxMerchantID, sandboxAccountID)
+
+	for _, requisiteType := range []domain.RequisiteType{
+		domain.RequisiteTypeCard,
+		domain.RequisiteTypeWallet,
+		domain.RequisiteTypeSBP,
+	} {
+		for i := 1; i <= sandboxRequisitesPerType; i++ {
+			requisite := Requisite{
+				ID:               fmt.Sprintf("sandbox-%s-%02d", requisiteType, i),
+				TerminalID:       sandboxTerminalID,
+				BankID:           sandboxBankID,
+				Type:             requisiteType,
+				Name:             "Sandbox Recipient",
+				IsCanWork:        true,
+				MaxInvoiceAmount: unlimited,
+			}
+			switch requisiteType {
+			case domain.RequisiteTypeCard:
+				requisite.CardNumber = fmt.Sprintf("0000000000%06d", i)
+			case domain.RequisiteTypeWallet:
+				requisite.WalletNumber = fmt.Sprintf("SANDBOX%06d", i)
+			case domain.RequisiteTypeSBP:
+				requisite.PhoneNumber = fmt.Sprintf("+7000000%04d", i)
+			}
+			store.PutRequisite(requisite)
+		}
+	}
+
+	store.SetExchangeRate(decimal.NewFromInt(100))
+
+	return &SandboxPool{Store: store}
+}
+
+// SelectAvailableRequisites отбирает реквизиты пула; банки и плательщик песочницы не учитываются
+func (p *SandboxPool) SelectAvailableRequisites(
+	ctx context.Context,
+	merchantID string,
+	amount decimal.Decimal,
+	requisiteType domain.RequisiteType,
+	banks domain.BankPreference,
+	payerID string,
+) ([]*domain.Requisite, error) {
+	return p.Store.SelectAvailableRequisites(ctx, sandboxMerchantID, amount, requisiteType, domain.BankPreference{}, "")
+}
+
+func (p *SandboxPool) SelectAvailableRequisitesFlexible(
+	ctx context.Context,
+	merchantID string,
+	flexibleAmountMin decimal.Decimal,
+	flexibleAmountMax decimal.Decimal,
+	flexibleAmountStep decimal.Decimal,
+	requisiteType domain.RequisiteType,
+	banks domain.BankPreference,
+	payerID string,
+) ([]*domain.Requisite, error) {
+	return p.Store.SelectAvailableRequisitesFlexible(
+		ctx, sandboxMerchantID, flexibleAmountMin, flexibleAmountMax, flexibleAmountStep, requisiteType,
+		domain.BankPreference{}, "",
+	)
+}
@@ -0,0 +1,21 @@
+package memory
+
+import (
+	"context"
+	"sort"
+)
+
+func (s *Store) GetBoostedTeamIds(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var teamIds []string
+	for _, team := range s.teams {
+		if team.IsBoosted {
+			teamIds = append(teamIds, team.ID)
+		}
+	}
+	sort.Strings(teamIds)
+
+	return teamIds, nil
+}
@@ -0,0 +1,415 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+)
+
+// Действия и типы сущностей журнала изменений админки
+const (
+	auditActionCreate = "create"
+	auditActionUpdate = "update"
+	auditActionDelete = "delete"
+	auditActionLink   = "link"
+	auditActionUnlink = "unlink"
+
+	auditEntityMerchant              = "merchant"
+	auditEntityMerchantTraderAccount = "merchant_trader_account"
+)
+
+// auditMerchant состояние мерчанта в журнале изменений
+type auditMerchant struct {
+	ID                 string          `json:"id"`
+	Status             string          `json:"status"`
+	InLimitCard        decimal.Decimal `json:"inLimitCard"`
+	InLimitWallet      decimal.Decimal `json:"inLimitWallet"`
+	InLimitSBP         decimal.Decimal `json:"inLimitSbp"`
+	InMaxCard          decimal.Decimal `json:"inMaxCard"`
+	InMaxWallet        decimal.Decimal `json:"inMaxWallet"`
+	InMaxSBP           decimal.Decimal `json:"inMaxSbp"`
+	InDisabledCard     bool            `json:"inDisabledCard"`
+	InDisabledWallet   bool            `json:"inDisabledWallet"`
+	InDisabledSBP      bool            `json:"inDisabledSbp"`
+	DailyTurnoverLimit decimal.Decimal `json:"dailyTurnoverLimit"`
+	DailyInvoiceLimit  int             `json:"dailyInvoiceLimit"`
+	IsSandbox          bool            `json:"isSandbox"`
+}
+
+func newAuditMerchant(m *domain.Merchant) *auditMerchant {
+	return &auditMerchant{
+		ID:                 m.ID,
+		Status:             string(m.Status),
+		InLimitCard:        m.InLimitCard,
+		InLimitWallet:      m.InLimitWallet,
+		InLimitSBP:         m.InLimitSBP,
+		InMaxCard:          m.InMaxCard,
+		InMaxWallet:        m.InMaxWallet,
+		InMaxSBP:           m.InMaxSBP,
+		InDisabledCard:     m.InDisabledCard,
+		InDisabledWallet:   m.InDisabledWallet,
+		InDisabledSBP:      m.InDisabledSBP,
+		DailyTurnoverLimit: m.DailyTurnoverLimit,
+		DailyInvoiceLimit:  m.DailyInvoiceLimit,
+		IsSandbox:          m.IsSandbox,
+	}
+}
+
+// auditLink привязка аккаунта трейдера в журнале изменений
+type auditLink struct {
+	MerchantID       string `json:"merchantId"`
+	TraiderAccountID string `json:"traiderAccountId"`
+}
+
+func (s *Store) ListMerchants(ctx context.Context) ([]*domain.Merchant, error) {
+	rows, err := s.conn.Query(ctx, `SELECT `+merchantColumns+` FROM "Merchant" ORDER BY id`)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to list merchants")
+		return nil, domain.ErrorFailedFindMerchant
+	}
+	defer rows.Close()
+
+	var merchants []*domain.Merchant
+	for rows.Next() {
+		m, err := scanMerchant(rows)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("failed to scan merchant")
+			return nil, domain.ErrorFailedFindMerchant
+		}
+		merchants = append(merchants, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to list merchants")
+		return nil, domain.ErrorFailedFindMerchant
+	}
+
+	return merchants, nil
+}
+
+func (s *Store) CreateMerchant(ctx context.Context, actor string, merchant *domain.Merchant) error {
+	return s.inAdminTx(ctx, func(tx pgx.Tx) error {
+		const query = `
+			INSERT INTO "Merchant" (
+				id, in_limit_card, in_limit_wallet, in_limit_sbp,
+				in_max_card, in_max_wallet, in_max_sbp,
+				in_disabled_card, in_disabled_wallet, in_disabled_sbp,
+				daily_turnover_limit, daily_invoice_limit, is_sandbox, status
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			ON CONFLICT (id) DO NOTHING`
+
+		tag, err := tx.Exec(ctx, query, merchantArgs(merchant)...)
+		if err != nil {
+			return errors.Wrap(err, "insert merchant")
+		}
+		if tag.RowsAffected() == 0 {
+			return domain.ErrorMerchantAlreadyExists
+		}
+
+		return s.writeAudit(ctx, tx, actor, auditActionCreate, auditEntityMerchant, merchant.ID,
+			nil, newAuditMerchant(merchant))
+	})
+}
+
+func (s *Store) UpdateMerchant(
+	ctx context.Context,
+	actor string,
+	merchantID string,
+	patch domain.MerchantPatch,
+) (*domain.Merchant, error) {
+	var updated *domain.Merchant
+	err := s.inAdminTx(ctx, func(tx pgx.Tx) error {
+		before, err := lockMerchant(ctx, tx, merchantID)
+		if err != nil {
+			return err
+		}
+
+		after := patch.Apply(*before)
+		if err := after.ValidateLimits(); err != nil {
+			return err
+		}
+
+		const query = `
+			UPDATE "Merchant"
+			SET in_limit_card = $2, in_limit_wallet = $3, in_limit_sbp = $4,
+				in_max_card = $5, in_max_wallet = $6, in_max_sbp = $7,
+				in_disabled_card = $8, in_disabled_wallet = $9, in_disabled_sbp = $10,
+				daily_turnover_limit = $11, daily_invoice_limit = $12, is_sandbox = $13, status = $14
+			WHERE id = $1`
+
+		_, err = tx.Exec(ctx, query, merchantArgs(&after)...)
+		if err != nil {
+			return errors.Wrap(err, "update merchant")
+		}
+
+		updated = &after
+		return s.writeAudit(ctx, tx, actor, auditActionUpdate, auditEntityMerchant, merchantID,
+			newAuditMerchant(before), newAuditMerchant(&after))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteMerchant удаляет мерчанта и его привязки. Мерчанта с Invoice удалить нельзя:
+// история Invoice ссылается на него.
+func (s *Store) DeleteMerchant(ctx context.Context, actor string, merchantID string) error {
+	return s.inAdminTx(ctx, func(tx pgx.Tx) error {
+		before, err := lockMerchant(ctx, tx, merchantID)
+		if err != nil {
+			return err
+		}
+
+		var hasInvoices bool
+		err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM "InvoiceIn" WHERE merchant_id = $1)`, merchantID).
+			Scan(&hasInvoices)
+		if err != nil {
+			return errors.Wrap(err, "check merchant invoices")
+		}
+		if hasInvoices {
+			return domain.ErrorMerchantHasInvoices
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM "MerchantInvoicesInOnTraiderAccount" WHERE merchant_id = $1`, merchantID); err != nil {
+			return errors.Wrap(err, "delete merchant links")
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM "Merchant" WHERE id = $1`, merchantID); err != nil {
+			return errors.Wrap(err, "delete merchant")
+		}
+
+		return s.writeAudit(ctx, tx, actor, auditActionDelete, auditEntityMerchant, merchantID,
+			newAuditMerchant(before), nil)
+	})
+}
+
+func (s *Store) ListMerchantTraderAccounts(ctx context.Context, merchantID string) ([]string, error) {
+	const query = `
+		SELECT traider_account_id
+		FROM "MerchantInvoicesInOnTraiderAccount"
+		WHERE merchant_id = $1
+		ORDER BY traider_account_id`
+
+	rows, err := s.conn.Query(ctx, query, merchantID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("merchant_id", merchantID).Msg("failed to list merchant trader accounts")
+		return nil, domain.ErrorFailedFindMerchant
+	}
+	defer rows.Close()
+
+	accountIDs := []string{}
+	for rows.Next() {
+		var accountID string
+		if err := rows.Scan(&accountID); err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("merchant_id", merchantID).Msg("failed to scan trader account id")
+			return nil, domain.ErrorFailedFindMerchant
+		}
+		accountIDs = append(accountIDs, accountID)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("merchant_id", merchantID).Msg("failed to list merchant trader accounts")
+		return nil, domain.ErrorFailedFindMerchant
+	}
+
+	return accountIDs, nil
+}
+
+func (s *Store) LinkTraderAccount(ctx context.Context, actor string, merchantID string, traiderAccountID string) error {
+	return s.inAdminTx(ctx, func(tx pgx.Tx) error {
+		if _, err := lockMerchant(ctx, tx, merchantID); err != nil {
+			return err
+		}
+
+		var accountExists bool
+		err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM "TraiderAccount" WHERE id = $1)`, traiderAccountID).
+			Scan(&accountExists)
+		if err != nil {
+			return errors.Wrap(err, "check trader account")
+		}
+		if !accountExists {
+			return domain.ErrorTraderAccountNotFound
+		}
+
+		const query = `
+			INSERT INTO "MerchantInvoicesInOnTraiderAccount" (merchant_id, traider_account_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING`
+
+		tag, err := tx.Exec(ctx, query, merchantID, traiderAccountID)
+		if err != nil {
+			return errors.Wrap(err, "insert merchant link")
+		}
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+
+		return s.writeAudit(ctx, tx, actor, auditActionLink, auditEntityMerchantTraderAccount, merchantID,
+			nil, &auditLink{MerchantID: merchantID, TraiderAccountID: traiderAccountID})
+	})
+}
+
+func (s *Store) UnlinkTraderAccount(ctx context.Context, actor string, merchantID string, traiderAccountID string) error {
+	return s.inAdminTx(ctx, func(tx pgx.Tx) error {
+		if _, err := lockMerchant(ctx, tx, merchantID); err != nil {
+			return err
+		}
+
+		const query = `
+			DELETE FROM "MerchantInvoicesInOnTraiderAccount"
+			WHERE merchant_id = $1 AND traider_account_id = $2`
+
+		tag, err := tx.Exec(ctx, query, merchantID, traiderAccountID)
+		if err != nil {
+			return errors.Wrap(err, "delete merchant link")
+		}
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+
+		return s.writeAudit(ctx, tx, actor, auditActionUnlink, auditEntityMerchantTraderAccount, merchantID,
+			&auditLink{MerchantID: merchantID, TraiderAccountID: traiderAccountID}, nil)
+	})
+}
+
+// ListAuditLog возвращает последние записи журнала; пустые entityType и entityID не фильтруют
+func (s *Store) ListAuditLog(
+	ctx context.Context,
+	entityType string,
+	entityID string,
+	limit int,
+) ([]*domain.AuditEntry, error) {
+	const query = `
+		SELECT id, actor, action, entity_type, entity_id, before, after, created_at
+		FROM "AdminAuditLog"
+		WHERE ($1 = '' OR entity_type = $1) AND ($2 = '' OR entity_id = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3`
+
+	rows, err := s.conn.Query(ctx, query, entityType, entityID, limit)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to list audit log")
+		return nil, domain.ErrorFailedGetAuditLog
+	}
+	defer rows.Close()
+
+	entries := []*domain.AuditEntry{}
+	for rows.Next() {
+		e := &domain.AuditEntry{}
+		err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.EntityType, &e.EntityID, &e.Before, &e.After, &e.CreatedAt)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("failed to scan audit entry")
+			return nil, domain.ErrorFailedGetAuditLog
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to list audit log")
+		return nil, domain.ErrorFailedGetAuditLog
+	}
+
+	return entries, nil
+}
+
+// inAdminTx выполняет изменение админки в транзакции. Ошибки domain возвращаются как есть,
+// остальные логируются и заменяются на domain.ErrorFailedAdminChange.
+func (s *Store) inAdminTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	err := pgx.BeginFunc(ctx, s.conn, fn)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, domain.ErrorMerchantNotFound),
+		errors.Is(err, domain.ErrorMerchantAlreadyExists),
+		errors.Is(err, domain.ErrorMerchantHasInvoices),
+		errors.Is(err, domain.ErrorInvalidLimit),
+		errors.Is(err, domain.ErrorTraderAccountNotFound),
+		errors.Is(err, domain.ErrorBlockEntityNotFound),
+		errors.Is(err, domain.ErrorUnknownPayerBlockScope),
+		errors.Is(err, domain.ErrorPayerBlockScopeNotFound):
+		return err
+	default:
+		log.Ctx(ctx).Error().Err(err).Msg("failed to apply admin change")
+		return domain.ErrorFailedAdminChange
+	}
+}
+
+// merchantArgs параметры $1..$14 вставки и изменения мерчанта в порядке столбцов
+func merchantArgs(m *domain.Merchant) []any {
+	return []any{
+		m.ID,
+		m.InLimitCard,
+		m.InLimitWallet,
+		m.InLimitSBP,
+		m.InMaxCard,
+		m.InMaxWallet,
+		m.InMaxSBP,
+		m.InDisabledCard,
+		m.InDisabledWallet,
+		m.InDisabledSBP,
+		m.DailyTurnoverLimit,
+		m.DailyInvoiceLimit,
+		m.IsSandbox,
+		m.Status,
+	}
+}
+
+// lockMerchant читает мерчанта с блокировкой строки до конца транзакции
+func lockMerchant(ctx context.Context, tx pgx.Tx, merchantID string) (*domain.Merchant, error) {
+	m, err := scanMerchant(tx.QueryRow(ctx, `SELECT `+merchantColumns+` FROM "Merchant" WHERE id = $1 FOR UPDATE`, merchantID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrorMerchantNotFound
+		}
+		return nil, errors.Wrap(err, "lock merchant")
+	}
+	return m, nil
+}
+
+// writeAudit записывает изменение в журнал в транзакции самого изменения
+func (s *Store) writeAudit(
+	ctx context.Context,
+	tx pgx.Tx,
+	actor string,
+	action string,
+	entityType string,
+	entityID string,
+	before any,
+	after any,
+) error {
+	beforeJSON, err := marshalAuditState(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := marshalAuditState(after)
+	if err != nil {
+		return err
+	}
+
+	const query = `
+		INSERT INTO "AdminAuditLog" (id, actor, action, entity_type, entity_id, before, after, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err = tx.Exec(ctx, query, s.ids.NewID(), actor, action, entityType, entityID, beforeJSON, afterJSON, s.clock.Now())
+	if err != nil {
+		return errors.Wrap(err, "write audit log")
+	}
+	return nil
+}
+
+// marshalAuditState кодирует состояние сущности; nil записывается как NULL
+func marshalAuditState(state any) ([]byte, error) {
+	if state == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal audit state")
+	}
+	return data, nil
+}
@@ -0,0 +1,119 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"mateo/internal/domain"
+	"time"
+)
+
+// blockTables таблицы сущностей, которые блокируются из админки
+var blockTables = map[domain.BlockEntity]string{
+	domain.BlockEntityTraderAccount: "TraiderAccount",
+	domain.BlockEntityTerminal:      "Terminal",
+	domain.BlockEntityRequisite:     "Requisite",
+}
+
+const (
+	auditActionBlock   = "block"
+	auditActionUnblock = "unblock"
+)
+
+// auditBlock состояние блокировки в журнале изменений
+type auditBlock struct {
+	IsBlocked    bool       `json:"isBlocked"`
+	Reason       string     `json:"reason,omitempty"`
+	BlockedUntil *time.Time `json:"blockedUntil,omitempty"`
+	BlockedBy    string     `json:"blockedBy,omitempty"`
+}
+
+func newAuditBlock(b *domain.Block) *auditBlock {
+	return &auditBlock{
+		IsBlocked:    b.IsBlocked,
+		Reason:       b.Reason,
+		BlockedUntil: b.BlockedUntil,
+		BlockedBy:    b.BlockedBy,
+	}
+}
+
+func (s *Store) GetBlock(ctx context.Context, entity domain.BlockEntity, entityID string) (*domain.Block, error) {
+	table, ok := blockTables[entity]
+	if !ok {
+		return nil, domain.ErrorUnknownBlockEntity
+	}
+
+	block, err := selectBlock(ctx, s.conn.QueryRow(ctx, blockQuery(table, false), entityID), entity, entityID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrorBlockEntityNotFound) {
+			log.Ctx(ctx).Error().Err(err).
+				Str("entity", string(entity)).
+				Str("entity_id", entityID).
+				Msg("failed to get block")
+			return nil, domain.ErrorFailedGetBlock
+		}
+		return nil, err
+	}
+	return block, nil
+}
+
+// SetBlock сохраняет состояние блокировки. Снятая блокировка очищает причину, срок и автора;
+// кто ее снял, остается в журнале изменений.
+func (s *Store) SetBlock(ctx context.Context, actor string, block *domain.Block) error {
+	table, ok := blockTables[block.Entity]
+	if !ok {
+		return domain.ErrorUnknownBlockEntity
+	}
+
+	return s.inAdminTx(ctx, func(tx pgx.Tx) error {
+		before, err := selectBlock(ctx, tx.QueryRow(ctx, blockQuery(table, true), block.EntityID), block.Entity, block.EntityID)
+		if err != nil {
+			return err
+		}
+
+		var reason, blockedBy *string
+		if block.IsBlocked {
+			reason, blockedBy = &block.Reason, &block.BlockedBy
+		}
+
+		query := `
+			UPDATE ` + pgx.Identifier{table}.Sanitize() + `
+			SET is_blocked = $2, blocked_until = $3, blocked_reason = $4, blocked_by = $5
+			WHERE id = $1`
+
+		if _, err := tx.Exec(ctx, query, block.EntityID, block.IsBlocked, block.BlockedUntil, reason, blockedBy); err != nil {
+			return errors.Wrap(err, "update block")
+		}
+
+		action := auditActionBlock
+		if !block.IsBlocked {
+			action = auditActionUnblock
+		}
+		return s.writeAudit(ctx, tx, actor, action, string(block.Entity), block.EntityID,
+			newAuditBlock(before), newAuditBlock(block))
+	})
+}
+
+// blockQuery читает состояние блокировки; forUpdate блокирует строку до конца транзакции
+func blockQuery(table string, forUpdate bool) string {
+	query := `
+		SELECT is_blocked, blocked_until, COALESCE(blocked_reason, ''), COALESCE(blocked_by, '')
+		FROM ` + pgx.Identifier{table}.Sanitize() + `
+		WHERE id = $1`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	return query
+}
+
+func selectBlock(ctx context.Context, row pgx.Row, entity domain.BlockEntity, entityID string) (*domain.Block, error) {
+	block := &domain.Block{Entity: entity, EntityID: entityID}
+	if err := row.Scan(&block.IsBlocked, &block.BlockedUntil, &block.Reason, &block.BlockedBy); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrorBlockEntityNotFound
+		}
+		return nil, errors.Wrap(err, "select block")
+	}
+	return block, nil
+}
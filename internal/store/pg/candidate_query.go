@@ -0,0 +1,300 @@
+package pg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"mateo/internal/domain"
+)
+
+// Запрос кандидатов-реквизитов собирается из одного шаблона для точной и гибкой суммы.
+// Условия отбора хранятся списком предикатов: запрос конкретного вызова может убрать
+// или добавить условие, не дублируя SQL. Параметры передаются именованными (@name),
+// а в тексте предикатов используются токены:
+//
+//	{amount}            сумма Invoice: @amount для точной суммы, amount_val для гибкой
+//	{account_min}       колонка минимальной суммы аккаунта для типа реквизита
+//	{account_is_work}   колонка признака работы аккаунта с типом реквизита
+
+// column имя колонки, которое подставляется в запрос как экранированный идентификатор
+type column string
+
+func (c column) sql() string {
+	return pgx.Identifier{string(c)}.Sanitize()
+}
+
+// accountColumns колонки "TraiderAccount", зависящие от типа реквизита
+type accountColumns struct {
+	minAmount column
+	isWork    column
+}
+
+var requisiteTypeToAccountColumns = map[domain.RequisiteType]accountColumns{
+	domain.RequisiteTypeSBP:    {minAmount: "min_invoice_amount_sbp", isWork: "is_work_on_sbp_pay_in"},
+	domain.RequisiteTypeCard:   {minAmount: "min_invoice_amount_card", isWork: "is_work_on_card_pay_in"},
+	domain.RequisiteTypeWallet: {minAmount: "min_invoice_amount_wallet", isWork: "is_work_on_wallet_pay_in"},
+}
+
+// predicate именованное условие отбора кандидатов
+type predicate struct {
+	name string
+	sql  string
+}
+
+const (
+	predicateAccountCanWork        = "account_can_work"
+	predicateAccountNotBlocked     = "account_not_blocked"
+	predicateAccountMinAmount      = "account_min_amount"
+	predicateAccountWorksWithType  = "account_works_with_type"
+	predicateAccountMaxAmount      = "account_max_amount"
+	predicateMerchantLinked        = "merchant_linked"
+	predicateWalletFreeBalance     = "wallet_free_balance"
+	predicateTerminalCanWork       = "terminal_can_work"
+	predicateTerminalNotBlocked    = "terminal_not_blocked"
+	predicateTerminalQuarantine    = "terminal_not_quarantined"
+	predicateTerminalMinAmount     = "terminal_min_amount"
+	predicateTerminalMaxAmount     = "terminal_max_amount"
+	predicateTerminalDailyMoney    = "terminal_daily_money"
+	predicateTerminalInterval      = "terminal_interval"
+	predicateTerminalMaxActive     = "terminal_max_active"
+	predicateTerminalDailyInvoices = "terminal_daily_invoices"
+	predicateRequisiteType         = "requisite_type"
+	predicateRequisiteCanWork      = "requisite_can_work"
+	predicateRequisiteNotBlocked   = "requisite_not_blocked"
+	predicateRequisiteQuarantine   = "requisite_not_quarantined"
+	predicateRequisiteMinAmount    = "requisite_min_amount"
+	predicateRequisiteMaxAmount    = "requisite_max_amount"
+	predicateRequisiteMaxActive    = "requisite_max_active"
+	predicateRequisiteSameAmount   = "requisite_same_amount"
+	predicateRequisiteInterval     = "requisite_interval"
+	predicateRequisiteDailyLimit   = "requisite_daily_invoices"
+	predicateBank                  = "bank"
+	predicateExcludedBank          = "excluded_bank"
+	predicatePayerTeamBlock        = "payer_team_block"
+	predicateAccountMaxActive      = "account_max_active"
+)
+
+// candidatePredicates условия отбора, общие для точной и гибкой суммы
+var candidatePredicates = []predicate{
+	{predicateAccountCanWork, `ta.is_can_work = TRUE`},
+	{predicateAccountNotBlocked, `(ta.is_blocked = FALSE OR ta.blocked_until <= @now::timestamptz)`},
+	{predicateAccountMinAmount, `ta.{account_min} <= {amount}`},
+	{predicateAccountWorksWithType, `ta.{account_is_work} = TRUE`},
+	{predicateAccountMaxAmount, `ta.max_invoice_amount_int >= {amount}`},
+	{predicateMerchantLinked, `EXISTS (
+			SELECT 1 FROM "MerchantInvoicesInOnTraiderAccount" mta
+			WHERE mta.traider_account_id = ta.id AND mta.merchant_id = @merchant_id
+		)`},
+	{predicateWalletFreeBalance, `w.pay_in_balance - COALESCE(wh.hold_sum, 0) >= {amount}`},
+	{predicateTerminalCanWork, `t.is_can_work = TRUE`},
+	{predicateTerminalNotBlocked, `(t.is_blocked = FALSE OR t.blocked_until <= @now::timestamptz)`},
+	{predicateTerminalQuarantine, `(t.quarantined_until IS NULL OR t.quarantined_until <= @now::timestamptz)`},
+	{predicateTerminalMinAmount, `t.min_invoice_amount <= {amount}`},
+	{predicateTerminalMaxAmount, `t.max_invoice_amount >= {amount}`},
+	{predicateTerminalDailyMoney, `(t.daily_limit_money IS NULL OR COALESCE(tw.daily_sum, 0) + {amount} <= t.daily_limit_money)`},
+	{predicateTerminalInterval, `(tc.last_invoice_time IS NULL OR @now::timestamptz - tc.last_invoice_time >= (t.invoice_interval * INTERVAL '1 minute'))`},
+	{predicateTerminalMaxActive, `(t.max_active_invoice IS NULL OR COALESCE(tc.active_count, 0) < t.max_active_invoice)`},
+	{predicateTerminalDailyInvoices, `(t.daily_limit_invoices IS NULL OR COALESCE(tw.daily_count, 0) < t.daily_limit_invoices)`},
+	{predicateRequisiteType, `r.type = @requisite_type`},
+	{predicateRequisiteCanWork, `r.is_can_work = TRUE`},
+	{predicateRequisiteNotBlocked, `(r.is_blocked = FALSE OR r.blocked_until <= @now::timestamptz)`},
+	{predicateRequisiteQuarantine, `(r.quarantined_until IS NULL OR r.quarantined_until <= @now::timestamptz)`},
+	{predicateRequisiteMinAmount, `r.min_invoice_amount <= {amount}`},
+	{predicateRequisiteMaxAmount, `r.max_invoice_amount >= {amount}`},
+	{predicateRequisiteMaxActive, `(r.max_active_invoice IS NULL OR COALESCE(rc.active_count, 0) < r.max_active_invoice)`},
+	{predicateRequisiteSameAmount, `NOT EXISTS (
+			SELECT 1 FROM "InvoiceIn" i
+			WHERE i.requisite_id = r.id
+				AND i.status = 'CREATED'
+				AND i.amount = {amount}
+		)`},
+	{predicateRequisiteInterval, `(rc.last_invoice_time IS NULL OR @now::timestamptz - rc.last_invoice_time >= (r.invoice_interval * INTERVAL '1 minute'))`},
+	{predicateRequisiteDailyLimit, `(r.daily_limit_invoices IS NULL OR COALESCE(rw.daily_count, 0) < r.daily_limit_invoices)`},
+	{predicateBank, `r.bank_id = ANY(@bank_ids)`},
+	{predicateExcludedBank, `r.bank_id <> ALL(@excluded_bank_ids)`},
+	{predicateAccountMaxActive, `(ta.max_active_invoices_in IS NULL OR COALESCE(ac.active_count, 0) < ta.max_active_invoices_in)`},
+	{predicatePayerTeamBlock, `NOT EXISTS (
+			SELECT 1 FROM "PayerBlock" pb
+			WHERE pb.scope = 'team' AND pb.scope_id = ta.team_id AND pb.payer_id = @payer_id
+		)`},
+}
+
+const candidateColumns = `
+		ta.user_id,
+		r.id AS requisite_id,
+		COALESCE(r.name, '') AS recipient_name,
+		COALESCE(r.phone_number, '') AS phone_number,
+		COALESCE(r.card_number, '') AS card_number,
+		COALESCE(r.wallet_number, '') AS wallet_number,
+		b.name AS bank_name,
+		r.bank_id,
+		t.id AS terminal_id,
+		ta.id AS traider_account_id,
+		COALESCE(ta.team_id, '') AS team_id,
+		COALESCE(rc.active_count, 0) AS requisite_active_count,
+		rc.last_invoice_time AS requisite_last_invoice_time,
+		COALESCE(tw.closed_count, 0) AS terminal_closed_count,
+		COALESCE(tw.success_count, 0) AS terminal_success_count,
+		COALESCE(rw.closed_count, 0) AS requisite_closed_count,
+		COALESCE(rw.success_count, 0) AS requisite_success_count,`
+
+// payerExpiredCount неоплаченные Invoice плательщика на реквизите за окно статистики
+const payerExpiredCount = `
+		(
+			SELECT COUNT(*) FROM "InvoiceIn" pi
+			WHERE pi.requisite_id = r.id
+				AND pi.merchant_id = @merchant_id
+				AND pi.payer_id = @payer_id
+				AND pi.status = 'EXPIRED'
+				AND pi.created_at >= @window_start::timestamptz
+		) AS payer_expired_count`
+
+// noPayerExpiredCount заменяет подзапрос, когда плательщик не передан
+const noPayerExpiredCount = `
+		0 AS payer_expired_count`
+
+const candidateFrom = `
+	FROM "TraiderAccount" ta
+	JOIN "Wallet" w ON ta.wallet_id = w.id
+	JOIN "Terminal" t ON t.traider_account_id = ta.id
+	JOIN "Requisite" r ON r.terminal_id = t.id
+	JOIN "Bank" b ON r.bank_id = b.id
+	LEFT JOIN wallet_holds wh ON wh.wallet_id = w.id
+	LEFT JOIN "CapacityCounter" ac ON ac.scope = 'ACCOUNT' AND ac.entity_id = ta.id
+	LEFT JOIN "CapacityCounter" tc ON tc.scope = 'TERMINAL' AND tc.entity_id = t.id
+	LEFT JOIN "CapacityCounter" rc ON rc.scope = 'REQUISITE' AND rc.entity_id = r.id
+	LEFT JOIN LATERAL (
+		SELECT
+			SUM(cb.invoice_count) FILTER (WHERE cb.bucket >= COALESCE(date_bin(@bucket::interval, @now::timestamptz - t.daily_limit_invoices_window_hours * INTERVAL '1 hour', TIMESTAMPTZ 'epoch'), @day_start::timestamptz)) AS daily_count,
+			SUM(cb.invoice_sum) FILTER (WHERE cb.bucket >= COALESCE(date_bin(@bucket::interval, @now::timestamptz - t.daily_limit_money_window_hours * INTERVAL '1 hour', TIMESTAMPTZ 'epoch'), @day_start::timestamptz)) AS daily_sum,
+			SUM(cb.closed_count) AS closed_count,
+			SUM(cb.success_count) AS success_count
+		FROM "CapacityCounterBucket" cb
+		WHERE cb.scope = 'TERMINAL' AND cb.entity_id = t.id AND cb.bucket >= @window_start::timestamptz
+	) tw ON TRUE
+	LEFT JOIN LATERAL (
+		SELECT
+			SUM(cb.invoice_count) FILTER (WHERE cb.bucket >= COALESCE(date_bin(@bucket::interval, @now::timestamptz - r.daily_limit_invoices_window_hours * INTERVAL '1 hour', TIMESTAMPTZ 'epoch'), @day_start::timestamptz)) AS daily_count,
+			SUM(cb.closed_count) AS closed_count,
+			SUM(cb.success_count) AS success_count
+		FROM "CapacityCounterBucket" cb
+		WHERE cb.scope = 'REQUISITE' AND cb.entity_id = r.id AND cb.bucket >= @window_start::timestamptz
+	) rw ON TRUE`
+
+// candidateQuery построитель запроса кандидатов-реквизитов
+type candidateQuery struct {
+	columns    accountColumns
+	flexible   bool
+	payer      bool
+	predicates []predicate
+	args       pgx.StrictNamedArgs
+}
+
+// newCandidateQuery создает запрос со всеми условиями отбора по умолчанию.
+// Для гибкой суммы кандидаты перебираются по сетке @amount_min..@amount_max с шагом @amount_step.
+func newCandidateQuery(requisiteType domain.RequisiteType, flexible bool) (*candidateQuery, error) {
+	columns, ok := requisiteTypeToAccountColumns[requisiteType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported requisite type: %s", requisiteType)
+	}
+
+	q := &candidateQuery{
+		columns:    columns,
+		flexible:   flexible,
+		predicates: append([]predicate(nil), candidatePredicates...),
+		args:       pgx.StrictNamedArgs{},
+	}
+	q.bind("requisite_type", requisiteType)
+
+	return q, nil
+}
+
+// bind задает значение именованного параметра
+func (q *candidateQuery) bind(name string, value any) *candidateQuery {
+	q.args[name] = value
+	return q
+}
+
+// forPayer считает неоплаченные Invoice плательщика у каждого кандидата
+func (q *candidateQuery) forPayer(payerID string) *candidateQuery {
+	q.payer = true
+	return q.bind("payer_id", payerID)
+}
+
+// without убирает условие отбора по имени
+func (q *candidateQuery) without(name string) *candidateQuery {
+	predicates := q.predicates[:0]
+	for _, p := range q.predicates {
+		if p.name != name {
+			predicates = append(predicates, p)
+		}
+	}
+	q.predicates = predicates
+	return q
+}
+
+// build возвращает текст запроса и его параметры
+func (q *candidateQuery) build() (string, pgx.StrictNamedArgs) {
+	amount := "@amount"
+	if q.flexible {
+		amount = "amount_val"
+	}
+
+	replacer := strings.NewReplacer(
+		"{amount}", amount,
+		"{account_min}", q.columns.minAmount.sql(),
+		"{account_is_work}", q.columns.isWork.sql(),
+	)
+
+	var sb strings.Builder
+	sb.WriteString(`
+	WITH wallet_holds AS (
+		SELECT
+			wallet_id,
+			SUM(amount) AS hold_sum
+		FROM "WalletHold"
+		WHERE status = 'ACTIVE'
+		GROUP BY wallet_id
+	)`)
+	if q.flexible {
+		sb.WriteString(`,
+	amounts AS (
+		SELECT generate_series(@amount_min::numeric, @amount_max::numeric, @amount_step::numeric) AS amount_val
+	)`)
+	}
+
+	sb.WriteString("\n\tSELECT")
+	if q.flexible {
+		sb.WriteString(" DISTINCT ON (r.id)")
+	}
+	sb.WriteString(candidateColumns)
+	if q.payer {
+		sb.WriteString(payerExpiredCount)
+	} else {
+		sb.WriteString(noPayerExpiredCount)
+	}
+	if q.flexible {
+		sb.WriteString(",\n\t\tamount_val AS selected_amount")
+	}
+
+	sb.WriteString(candidateFrom)
+	if q.flexible {
+		sb.WriteString("\n\tCROSS JOIN amounts")
+	}
+
+	for i, p := range q.predicates {
+		if i == 0 {
+			sb.WriteString("\n\tWHERE\n\t\t")
+		} else {
+			sb.WriteString("\n\t\tAND ")
+		}
+		sb.WriteString(replacer.Replace(p.sql))
+	}
+
+	if q.flexible {
+		sb.WriteString("\n\tORDER BY r.id, amount_val")
+	}
+
+	return sb.String(), q.args
+}
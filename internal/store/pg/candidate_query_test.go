@@ -0,0 +1,144 @@
+package pg
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+	"mateo/internal/fake"
+)
+
+var namedParam = regexp.MustCompile(`@([a-z_]+)`)
+
+func TestCandidateQueryBuild(t *testing.T) {
+	store := NewStore(nil, fake.NewClock(time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC)),
+		fake.NewIDGenerator("id"), time.UTC, 24*time.Hour)
+
+	cases := []struct {
+		name     string
+		flexible bool
+		banks    domain.BankPreference
+		payerID  string
+		// predicates условия, которые должны остаться в запросе, dropped — убранные
+		predicates []string
+		dropped    []string
+	}{
+		{
+			name:    "exact without preferences",
+			dropped: []string{predicateBank, predicateExcludedBank, predicatePayerTeamBlock},
+		},
+		{
+			name:     "flexible without preferences",
+			flexible: true,
+			dropped:  []string{predicateBank, predicateExcludedBank, predicatePayerTeamBlock},
+		},
+		{
+			name:       "strict bank",
+			banks:      domain.BankPreference{Preferred: []string{"bank-1"}, Strict: true},
+			predicates: []string{predicateBank},
+			dropped:    []string{predicateExcludedBank, predicatePayerTeamBlock},
+		},
+		{
+			name:    "preferred bank is ordered by the service, not filtered",
+			banks:   domain.BankPreference{Preferred: []string{"bank-1"}},
+			dropped: []string{predicateBank, predicateExcludedBank, predicatePayerTeamBlock},
+		},
+		{
+			name:       "excluded bank",
+			flexible:   true,
+			banks:      domain.BankPreference{Excluded: []string{"bank-2"}},
+			predicates: []string{predicateExcludedBank},
+			dropped:    []string{predicateBank, predicatePayerTeamBlock},
+		},
+		{
+			name:       "payer",
+			payerID:    "payer-1",
+			predicates: []string{predicatePayerTeamBlock},
+			dropped:    []string{predicateBank, predicateExcludedBank},
+		},
+		{
+			name:       "everything",
+			flexible:   true,
+			banks:      domain.BankPreference{Preferred: []string{"bank-1"}, Excluded: []string{"bank-2"}, Strict: true},
+			payerID:    "payer-1",
+			predicates: []string{predicateBank, predicateExcludedBank, predicatePayerTeamBlock},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			q, err := newCandidateQuery(domain.RequisiteTypeCard, c.flexible)
+			if err != nil {
+				t.Fatalf("newCandidateQuery: %v", err)
+			}
+			if c.flexible {
+				q.bind("amount_min", decimal.NewFromInt(100)).
+					bind("amount_max", decimal.NewFromInt(120)).
+					bind("amount_step", decimal.NewFromInt(5))
+			} else {
+				q.bind("amount", decimal.NewFromInt(100))
+			}
+			store.bindCandidateQuery(q, "merchant-1", c.banks, c.payerID)
+
+			names := make([]string, 0, len(q.predicates))
+			for _, p := range q.predicates {
+				names = append(names, p.name)
+			}
+			for _, name := range c.predicates {
+				if !slices.Contains(names, name) {
+					t.Errorf("predicate %s is missing", name)
+				}
+			}
+			for _, name := range c.dropped {
+				if slices.Contains(names, name) {
+					t.Errorf("predicate %s is not dropped", name)
+				}
+			}
+
+			query, args := q.build()
+
+			// StrictNamedArgs отвергает и лишние, и недостающие параметры
+			referenced := make(map[string]bool)
+			for _, match := range namedParam.FindAllStringSubmatch(query, -1) {
+				referenced[match[1]] = true
+			}
+			for name := range args {
+				if !referenced[name] {
+					t.Errorf("argument @%s is not referenced", name)
+				}
+			}
+			for name := range referenced {
+				if _, ok := args[name]; !ok {
+					t.Errorf("parameter @%s is not bound", name)
+				}
+			}
+
+			if strings.ContainsAny(query, "{}") {
+				t.Errorf("query has unreplaced tokens:\n%s", query)
+			}
+			if !strings.Contains(query, `ta."min_invoice_amount_card"`) || !strings.Contains(query, `ta."is_work_on_card_pay_in"`) {
+				t.Errorf("query does not use the card account columns")
+			}
+			if got := strings.Contains(query, "amount_val"); got != c.flexible {
+				t.Errorf("query uses amount_val: %v, want %v", got, c.flexible)
+			}
+			// Без плательщика его неоплаченные Invoice не ищутся
+			if got := strings.Contains(query, "pi.payer_id"); got != (c.payerID != "") {
+				t.Errorf("query counts payer invoices: %v, want %v", got, c.payerID != "")
+			}
+			if got := strings.Contains(query, "0 AS payer_expired_count"); got != (c.payerID == "") {
+				t.Errorf("query has constant payer_expired_count: %v, want %v", got, c.payerID == "")
+			}
+		})
+	}
+}
+
+func TestCandidateQueryUnknownType(t *testing.T) {
+	if _, err := newCandidateQuery(domain.RequisiteType("CASH"), false); err == nil {
+		t.Fatal("want error for unknown requisite type")
+	}
+}
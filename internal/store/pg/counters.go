@@ -0,0 +1,147 @@
+package pg
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+// Счетчики загрузки ведутся по трем уровням: аккаунт трейдера, терминал и реквизит,
+// и отдельно по мерчанту для его дневных лимитов.
+// "CapacityCounter" хранит текущие активные Invoice и время последнего Invoice,
+// "CapacityCounterBucket" — число и сумму Invoice в 15-минутных корзинах для дневных
+// и скользящих лимитов, а также исходы Invoice для оценки конверсии. Оба ведут триггеры
+// на "InvoiceIn", поэтому учитываются и статусы, которые выставляет внешняя платежная система.
+const (
+	counterScopeAccount   = "ACCOUNT"
+	counterScopeTerminal  = "TERMINAL"
+	counterScopeRequisite = "REQUISITE"
+	counterScopeMerchant  = "MERCHANT"
+
+	// counterBucket размер корзины; передается в SQL как interval для date_bin
+	counterBucket         = 15 * time.Minute
+	counterBucketInterval = "15 minutes"
+)
+
+// RebuildCapacityCounters пересчитывает счетчики загрузки по "InvoiceIn", исправляя расхождения.
+// На время пересчета триггеры создания и закрытия Invoice ждут снятия блокировки счетчиков.
+func (s *Store) RebuildCapacityCounters(ctx context.Context) error {
+	_, windowStart := s.limitWindow(s.clock.Now())
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "begin rebuild transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	statements := []struct {
+		name  string
+		query string
+		args  []any
+	}{
+		{
+			name:  "lock counters",
+			query: `LOCK TABLE "CapacityCounter", "CapacityCounterBucket" IN EXCLUSIVE MODE`,
+		},
+		{
+			name:  "clear counters",
+			query: `DELETE FROM "CapacityCounter"`,
+		},
+		{
+			name:  "clear buckets",
+			query: `DELETE FROM "CapacityCounterBucket"`,
+		},
+		{
+			name: "rebuild counters",
+			query: `
+				INSERT INTO "CapacityCounter" (scope, entity_id, active_count, active_sum, last_invoice_time)
+				SELECT e.scope, e.entity_id,
+					COUNT(*) FILTER (WHERE i.status = 'CREATED'),
+					COALESCE(SUM(i.amount) FILTER (WHERE i.status = 'CREATED'), 0),
+					MAX(i.created_at)
+				FROM "InvoiceIn" i
+				CROSS JOIN LATERAL (VALUES
+					('ACCOUNT', i.traider_account_id),
+					('TERMINAL', i.terminal_id),
+					('REQUISITE', i.requisite_id),
+					('MERCHANT', i.merchant_id)
+				) AS e(scope, entity_id)
+				WHERE i.status = 'CREATED' OR i.created_at >= $1
+				GROUP BY e.scope, e.entity_id`,
+			args: []any{windowStart},
+		},
+		{
+			name: "rebuild buckets",
+			query: `
+				INSERT INTO "CapacityCounterBucket" (
+					scope, entity_id, bucket, invoice_count, invoice_sum, closed_count, success_count
+				)
+				SELECT e.scope, e.entity_id,
+					date_bin($2::interval, i.created_at, TIMESTAMPTZ 'epoch') AS bucket,
+					COUNT(*) FILTER (WHERE i.status <> 'EXPIRED'),
+					COALESCE(SUM(i.amount) FILTER (WHERE i.status <> 'EXPIRED'), 0),
+					COUNT(*) FILTER (WHERE i.status <> 'CREATED'),
+					COUNT(*) FILTER (WHERE i.status IN ('SUCCESS','SUCCESS_HAND','SUCCESS_APPEAL'))
+				FROM "InvoiceIn" i
+				CROSS JOIN LATERAL (VALUES
+					('ACCOUNT', i.traider_account_id),
+					('TERMINAL', i.terminal_id),
+					('REQUISITE', i.requisite_id),
+					('MERCHANT', i.merchant_id)
+				) AS e(scope, entity_id)
+				WHERE i.created_at >= $1
+					AND i.status IN ('CREATED','SUCCESS','SUCCESS_HAND','SUCCESS_APPEAL','EXPIRED')
+				GROUP BY e.scope, e.entity_id, bucket`,
+			args: []any{windowStart, counterBucketInterval},
+		},
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.Exec(ctx, statement.query, statement.args...); err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("step", statement.name).Msg("failed to rebuild capacity counters")
+			return errors.Wrap(err, statement.name)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errors.Wrap(err, "commit rebuild transaction")
+	}
+
+	return nil
+}
+
+// PruneCapacityCounterBuckets удаляет корзины, которые уже не попадают ни в одно окно лимитов
+func (s *Store) PruneCapacityCounterBuckets(ctx context.Context) (int64, error) {
+	_, windowStart := s.limitWindow(s.clock.Now())
+
+	tag, err := s.conn.Exec(ctx, `DELETE FROM "CapacityCounterBucket" WHERE bucket < $1`, windowStart)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to prune capacity counter buckets")
+		return 0, errors.Wrap(err, "prune capacity counter buckets")
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// RunCounterPruning периодически удаляет устаревшие корзины счетчиков до отмены ctx
+func (s *Store) RunCounterPruning(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if deleted, err := s.PruneCapacityCounterBuckets(ctx); err == nil && deleted > 0 {
+				log.Ctx(ctx).Info().Int64("count", deleted).Msg("capacity counter buckets pruned")
+			}
+		}
+	}
+}
+
+// floorToBucket округляет момент вниз до начала корзины счетчиков
+func floorToBucket(t time.Time) time.Time {
+	return t.Truncate(counterBucket)
+}
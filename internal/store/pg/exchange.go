@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/rs/zerolog/log"
 	"mateo/internal/domain"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
@@ -18,9 +19,27 @@ func (s *Store) GetExchangeRate(ctx context.Context) (decimal.Decimal, error) {
 	var rate decimal.Decimal
 	err := s.conn.QueryRow(ctx, query).Scan(&rate)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to get exchange rate")
+		log.Ctx(ctx).Error().Err(err).Msg("failed to get exchange rate")
 		return decimal.Decimal{}, domain.ErrorFailedGetExchangeRate
 	}
 
 	return rate, nil
 }
+
+// GetExchangeRateUpdatedAt время последнего изменения курса, минуя кеш
+func (s *Store) GetExchangeRateUpdatedAt(ctx context.Context) (time.Time, error) {
+	const query = `
+		SELECT updated_at
+		FROM "Settings"
+		LIMIT 1
+	`
+
+	var updatedAt time.Time
+	err := s.conn.QueryRow(ctx, query).Scan(&updatedAt)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to get exchange rate update time")
+		return time.Time{}, domain.ErrorFailedGetExchangeRate
+	}
+
+	return updatedAt, nil
+}
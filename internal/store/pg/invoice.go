@@ -2,15 +2,65 @@ package pg
 
 import (
 	"context"
-	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
 	"mateo/internal/domain"
+	"time"
 )
 
-// CreateInvoice создает Invoice и возвращает его ID. Обратите внимание, что обновляется ID в исходном Invoice
+// CreateInvoice создает Invoice и возвращает его ID. Обратите внимание, что обновляется ID в исходном Invoice.
+// Счетчики загрузки аккаунта, терминала и реквизита обновляет триггер на "InvoiceIn".
+// Вместе с Invoice на кошельке трейдера ставится hold на сумму Invoice. Если свободного баланса
+// (pay_in_balance за вычетом активных hold) не хватает, возвращается domain.ErrorInsufficientBalance.
 func (s *Store) CreateInvoice(ctx context.Context, invoice *domain.Invoice) (string, error) {
-	invoice.ID = uuid.New().String()
-	query := `
+	invoice.ID = s.ids.NewID()
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to begin create invoice transaction")
+		return "", domain.ErrorFailedCreateInvoice
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокируем кошелек, чтобы параллельные Invoice не превысили свободный баланс
+	const lockWalletQuery = `
+		SELECT w.id, w.pay_in_balance
+		FROM "Wallet" w
+		JOIN "TraiderAccount" ta ON ta.wallet_id = w.id
+		WHERE ta.id = $1
+		FOR UPDATE OF w`
+
+	var (
+		walletID string
+		balance  decimal.Decimal
+	)
+	if err := tx.QueryRow(ctx, lockWalletQuery, invoice.TraiderAccountID).Scan(&walletID, &balance); err != nil {
+		log.Ctx(ctx).Error().Err(err).
+			Str("traider_account_id", invoice.TraiderAccountID).
+			Msg("failed to lock wallet")
+		return "", domain.ErrorFailedCreateInvoice
+	}
+
+	const holdsQuery = `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM "WalletHold"
+		WHERE wallet_id = $1 AND status = $2`
+
+	var held decimal.Decimal
+	if err := tx.QueryRow(ctx, holdsQuery, walletID, domain.HoldStatusActive).Scan(&held); err != nil {
+		log.Ctx(ctx).Error().Err(err).
+			Str("wallet_id", walletID).
+			Msg("failed to sum wallet holds")
+		return "", domain.ErrorFailedCreateInvoice
+	}
+
+	if balance.Sub(held).LessThan(invoice.Amount) {
+		return "", domain.ErrorInsufficientBalance
+	}
+
+	const insertInvoiceQuery = `
 		INSERT INTO "InvoiceIn" (
 			id,
 			merchant_id,
@@ -26,12 +76,14 @@ func (s *Store) CreateInvoice(ctx context.Context, invoice *domain.Invoice) (str
 			callback_key,
 			internal_request_id,
 			time_expires,
-			exchange
+			exchange,
+			created_at,
+			payer_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
-		RETURNING id`
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NULLIF($17, ''))
+		RETURNING id, created_at`
 
-	err := s.conn.QueryRow(ctx, query,
+	err = tx.QueryRow(ctx, insertInvoiceQuery,
 		invoice.ID,
 		invoice.MerchantID,
 		invoice.Amount,
@@ -47,13 +99,155 @@ func (s *Store) CreateInvoice(ctx context.Context, invoice *domain.Invoice) (str
 		invoice.InternalRequestID,
 		invoice.TimeExpires,
 		invoice.Exchange,
-	).Scan(&invoice.ID)
+		s.clock.Now(),
+		invoice.PayerID,
+	).Scan(&invoice.ID, &invoice.CreatedAt)
 	if err != nil {
-		log.Error().Err(err).
-			Interface("invoice", invoice).
+		log.Ctx(ctx).Error().Err(err).
+			Str("merchant_id", invoice.MerchantID).
+			Str("requisite_id", invoice.RequisiteID).
+			Str("internal_request_id", invoice.InternalRequestID).
 			Msg("failed to create invoice")
 		return "", domain.ErrorFailedCreateInvoice
 	}
 
+	const insertHoldQuery = `
+		INSERT INTO "WalletHold" (id, wallet_id, invoice_id, amount, status)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err = tx.Exec(ctx, insertHoldQuery,
+		s.ids.NewID(),
+		walletID,
+		invoice.ID,
+		invoice.Amount,
+		domain.HoldStatusActive,
+	)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).
+			Str("invoice_id", invoice.ID).
+			Str("wallet_id", walletID).
+			Msg("failed to create wallet hold")
+		return "", domain.ErrorFailedCreateInvoice
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Ctx(ctx).Error().Err(err).
+			Str("invoice_id", invoice.ID).
+			Msg("failed to commit create invoice transaction")
+		return "", domain.ErrorFailedCreateInvoice
+	}
+
 	return invoice.ID, nil
 }
+
+// FinalizeInvoice переводит активный Invoice в финальный статус. Hold и счетчики загрузки
+// обновляют триггеры на "InvoiceIn": при успешной оплате hold списывается с pay_in_balance,
+// при истечении или отмене — освобождается.
+func (s *Store) FinalizeInvoice(
+	ctx context.Context,
+	invoiceID string,
+	status domain.InvoiceStatus,
+) (*domain.Invoice, error) {
+	if !status.IsFinal() {
+		return nil, domain.ErrorInvalidStatus
+	}
+
+	const updateInvoiceQuery = `
+		UPDATE "InvoiceIn"
+		SET status = $2
+		WHERE id = $1 AND status = $3
+		RETURNING
+			merchant_id,
+			amount,
+			type,
+			terminal_id,
+			user_id,
+			bank_id,
+			traider_account_id,
+			requisite_id,
+			callback_url,
+			callback_key,
+			internal_request_id,
+			time_expires,
+			exchange,
+			created_at,
+			COALESCE(payer_id, '')`
+
+	invoice := &domain.Invoice{ID: invoiceID, Status: status}
+	err := s.conn.QueryRow(ctx, updateInvoiceQuery, invoiceID, status, domain.InvoiceStatusCreated).Scan(
+		&invoice.MerchantID,
+		&invoice.Amount,
+		&invoice.Type,
+		&invoice.TerminalID,
+		&invoice.UserID,
+		&invoice.BankID,
+		&invoice.TraiderAccountID,
+		&invoice.RequisiteID,
+		&invoice.CallbackURL,
+		&invoice.CallbackKey,
+		&invoice.InternalRequestID,
+		&invoice.TimeExpires,
+		&invoice.Exchange,
+		&invoice.CreatedAt,
+		&invoice.PayerID,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, s.invoiceNotActiveError(ctx, invoiceID)
+		}
+		log.Ctx(ctx).Error().Err(err).
+			Str("invoice_id", invoiceID).
+			Msg("failed to update invoice status")
+		return nil, domain.ErrorFailedUpdateInvoice
+	}
+
+	return invoice, nil
+}
+
+// SelectExpiredInvoiceIDs возвращает ID активных Invoice, у которых истекло время оплаты
+func (s *Store) SelectExpiredInvoiceIDs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	const query = `
+		SELECT id
+		FROM "InvoiceIn"
+		WHERE status = $1 AND time_expires <= $2
+		ORDER BY time_expires
+		LIMIT $3`
+
+	rows, err := s.conn.Query(ctx, query, domain.InvoiceStatusCreated, now, limit)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to select expired invoices")
+		return nil, errors.Wrap(err, "failed to select expired invoices")
+	}
+	defer rows.Close()
+
+	var invoiceIDs []string
+	for rows.Next() {
+		var invoiceID string
+		if err := rows.Scan(&invoiceID); err != nil {
+			return nil, errors.Wrap(err, "failed to scan expired invoice id")
+		}
+		invoiceIDs = append(invoiceIDs, invoiceID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to select expired invoices")
+	}
+
+	return invoiceIDs, nil
+}
+
+// invoiceNotActiveError отличает отсутствующий Invoice от уже закрытого
+func (s *Store) invoiceNotActiveError(ctx context.Context, invoiceID string) error {
+	var exists bool
+	err := s.conn.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM "InvoiceIn" WHERE id = $1)`, invoiceID).Scan(&exists)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).
+			Str("invoice_id", invoiceID).
+			Msg("failed to check invoice existence")
+		return domain.ErrorFailedUpdateInvoice
+	}
+	if !exists {
+		return domain.ErrorInvoiceNotFound
+	}
+	return domain.ErrorInvoiceNotActive
+}
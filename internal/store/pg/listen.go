@@ -0,0 +1,77 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+const (
+	// cacheInvalidationChannel канал NOTIFY триггеров "Merchant", "Team" и "Settings"
+	cacheInvalidationChannel = "cache_invalidation"
+	listenReconnectDelay     = time.Second
+)
+
+// CacheInvalidation изменение строки кешируемой таблицы; пустой ID означает всю таблицу
+type CacheInvalidation struct {
+	Table string `json:"table"`
+	ID    string `json:"id"`
+}
+
+// ListenCacheInvalidations слушает изменения кешируемых таблиц до отмены ctx, переподключаясь
+// при обрывах соединения. onConnect вызывается после каждого подключения: уведомления, отправленные
+// без слушателя, потеряны, поэтому кеш нужно сбросить целиком.
+func (s *Store) ListenCacheInvalidations(
+	ctx context.Context,
+	onConnect func(ctx context.Context),
+	onInvalidation func(ctx context.Context, invalidation CacheInvalidation),
+) {
+	for {
+		err := s.listen(ctx, onConnect, onInvalidation)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Ctx(ctx).Error().Err(err).Msg("cache invalidation listener disconnected")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenReconnectDelay):
+		}
+	}
+}
+
+func (s *Store) listen(
+	ctx context.Context,
+	onConnect func(ctx context.Context),
+	onInvalidation func(ctx context.Context, invalidation CacheInvalidation),
+) error {
+	pooled, err := s.conn.Acquire(ctx)
+	if err != nil {
+		return errors.Wrap(err, "acquire connection")
+	}
+	// Соединение в режиме LISTEN не возвращается в пул
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+cacheInvalidationChannel); err != nil {
+		return errors.Wrap(err, "listen")
+	}
+	onConnect(ctx)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return errors.Wrap(err, "wait for notification")
+		}
+
+		var invalidation CacheInvalidation
+		if err := json.Unmarshal([]byte(notification.Payload), &invalidation); err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("payload", notification.Payload).Msg("invalid cache invalidation payload")
+			continue
+		}
+		onInvalidation(ctx, invalidation)
+	}
+}
@@ -8,28 +8,79 @@ import (
 	"mateo/internal/domain"
 )
 
-func (s *Store) GetMerchantByMerchantID(ctx context.Context, merchantID string) (*domain.Merchant, error) {
-	const query = `SELECT id,
-       COALESCE(in_limit_card, 0) as in_limit_card,
-       COALESCE(in_limit_wallet, 0) as in_limit_wallet,
-       COALESCE(in_limit_sbp, 0) as in_limit_sbp
-	   FROM "Merchant" 
-	   WHERE id = $1`
-
-	row := s.conn.QueryRow(ctx, query, merchantID)
+const merchantColumns = `
+	id,
+	COALESCE(in_limit_card, 0),
+	COALESCE(in_limit_wallet, 0),
+	COALESCE(in_limit_sbp, 0),
+	in_max_card,
+	in_max_wallet,
+	in_max_sbp,
+	in_disabled_card,
+	in_disabled_wallet,
+	in_disabled_sbp,
+	daily_turnover_limit,
+	daily_invoice_limit,
+	is_sandbox,
+	status`
 
+func scanMerchant(row pgx.Row) (*domain.Merchant, error) {
 	var m domain.Merchant
-	err := row.Scan(&m.ID, &m.InLimitCard, &m.InLimitWallet, &m.InLimitSBP)
+	err := row.Scan(
+		&m.ID,
+		&m.InLimitCard,
+		&m.InLimitWallet,
+		&m.InLimitSBP,
+		&m.InMaxCard,
+		&m.InMaxWallet,
+		&m.InMaxSBP,
+		&m.InDisabledCard,
+		&m.InDisabledWallet,
+		&m.InDisabledSBP,
+		&m.DailyTurnoverLimit,
+		&m.DailyInvoiceLimit,
+		&m.IsSandbox,
+		&m.Status,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (s *Store) GetMerchantByMerchantID(ctx context.Context, merchantID string) (*domain.Merchant, error) {
+	m, err := scanMerchant(s.conn.QueryRow(ctx, `SELECT `+merchantColumns+` FROM "Merchant" WHERE id = $1`, merchantID))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, domain.ErrorMerchantNotFound
 		}
 
-		log.Error().Err(err).
+		log.Ctx(ctx).Error().Err(err).
 			Str("merchant_id", merchantID).
 			Msg("failed to find merchant")
 		return nil, domain.ErrorFailedFindMerchant
 	}
 
-	return &m, nil
+	return m, nil
+}
+
+// GetMerchantDailyTurnover суммирует корзины счетчиков мерчанта с начала бизнес-дня
+func (s *Store) GetMerchantDailyTurnover(ctx context.Context, merchantID string) (domain.MerchantTurnover, error) {
+	const query = `
+		SELECT COALESCE(SUM(invoice_count), 0), COALESCE(SUM(invoice_sum), 0)
+		FROM "CapacityCounterBucket"
+		WHERE scope = $1 AND entity_id = $2 AND bucket >= $3`
+
+	dayStart, _ := s.limitWindow(s.clock.Now())
+
+	var turnover domain.MerchantTurnover
+	err := s.conn.QueryRow(ctx, query, counterScopeMerchant, merchantID, dayStart).Scan(&turnover.Count, &turnover.Sum)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).
+			Str("merchant_id", merchantID).
+			Msg("failed to get merchant turnover")
+		return domain.MerchantTurnover{}, domain.ErrorFailedGetMerchantTurnover
+	}
+
+	return turnover, nil
 }
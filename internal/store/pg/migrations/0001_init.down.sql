@@ -0,0 +1,2 @@
+-- Базовая схема не откатывается: таблицы содержат рабочие данные.
+-- Migrator.Down отказывается откатывать эту миграцию.
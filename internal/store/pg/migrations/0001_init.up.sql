@@ -0,0 +1,118 @@
+-- Базовая схема таблиц, с которыми работает сервис. Таблицы могут уже существовать
+-- в общей базе, поэтому создание идемпотентно.
+
+CREATE TABLE IF NOT EXISTS "Merchant" (
+    id              TEXT PRIMARY KEY,
+    in_limit_card   NUMERIC,
+    in_limit_wallet NUMERIC,
+    in_limit_sbp    NUMERIC
+);
+
+CREATE TABLE IF NOT EXISTS "Team" (
+    id         TEXT PRIMARY KEY,
+    name       TEXT NOT NULL DEFAULT '',
+    is_boosted BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS "Bank" (
+    id   TEXT PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "Wallet" (
+    id             TEXT PRIMARY KEY,
+    pay_in_balance NUMERIC NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS "TraiderAccount" (
+    id                        TEXT PRIMARY KEY,
+    user_id                   TEXT NOT NULL,
+    wallet_id                 TEXT NOT NULL REFERENCES "Wallet" (id),
+    team_id                   TEXT REFERENCES "Team" (id),
+    is_can_work               BOOLEAN NOT NULL DEFAULT FALSE,
+    is_blocked                BOOLEAN NOT NULL DEFAULT FALSE,
+    min_invoice_amount_card   NUMERIC NOT NULL DEFAULT 0,
+    min_invoice_amount_wallet NUMERIC NOT NULL DEFAULT 0,
+    min_invoice_amount_sbp    NUMERIC NOT NULL DEFAULT 0,
+    is_work_on_card_pay_in    BOOLEAN NOT NULL DEFAULT FALSE,
+    is_work_on_wallet_pay_in  BOOLEAN NOT NULL DEFAULT FALSE,
+    is_work_on_sbp_pay_in     BOOLEAN NOT NULL DEFAULT FALSE,
+    max_invoice_amount_int    NUMERIC NOT NULL DEFAULT 0,
+    max_active_invoices_in    INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS "Terminal" (
+    id                   TEXT PRIMARY KEY,
+    traider_account_id   TEXT NOT NULL REFERENCES "TraiderAccount" (id),
+    is_can_work          BOOLEAN NOT NULL DEFAULT FALSE,
+    is_blocked           BOOLEAN NOT NULL DEFAULT FALSE,
+    min_invoice_amount   NUMERIC NOT NULL DEFAULT 0,
+    max_invoice_amount   NUMERIC NOT NULL DEFAULT 0,
+    daily_limit_money    NUMERIC,
+    daily_limit_invoices INTEGER,
+    max_active_invoice   INTEGER,
+    invoice_interval     INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS "Requisite" (
+    id                   TEXT PRIMARY KEY,
+    terminal_id          TEXT NOT NULL REFERENCES "Terminal" (id),
+    bank_id              TEXT NOT NULL REFERENCES "Bank" (id),
+    type                 TEXT NOT NULL,
+    name                 TEXT,
+    phone_number         TEXT,
+    card_number          TEXT,
+    wallet_number        TEXT,
+    is_can_work          BOOLEAN NOT NULL DEFAULT FALSE,
+    is_blocked           BOOLEAN NOT NULL DEFAULT FALSE,
+    min_invoice_amount   NUMERIC NOT NULL DEFAULT 0,
+    max_invoice_amount   NUMERIC NOT NULL DEFAULT 0,
+    daily_limit_invoices INTEGER,
+    max_active_invoice   INTEGER,
+    invoice_interval     INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS "MerchantInvoicesInOnTraiderAccount" (
+    merchant_id        TEXT NOT NULL REFERENCES "Merchant" (id),
+    traider_account_id TEXT NOT NULL REFERENCES "TraiderAccount" (id),
+    PRIMARY KEY (merchant_id, traider_account_id)
+);
+
+CREATE TABLE IF NOT EXISTS "Settings" (
+    id            SERIAL PRIMARY KEY,
+    exchange_rate NUMERIC NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "InvoiceIn" (
+    id                  TEXT PRIMARY KEY,
+    merchant_id         TEXT NOT NULL,
+    amount              NUMERIC NOT NULL,
+    status              TEXT NOT NULL,
+    type                TEXT NOT NULL,
+    terminal_id         TEXT NOT NULL,
+    user_id             TEXT NOT NULL,
+    bank_id             TEXT NOT NULL,
+    traider_account_id  TEXT NOT NULL,
+    requisite_id        TEXT NOT NULL,
+    callback_url        TEXT NOT NULL,
+    callback_key        TEXT NOT NULL DEFAULT '',
+    internal_request_id TEXT NOT NULL DEFAULT '',
+    time_expires        TIMESTAMPTZ NOT NULL,
+    exchange            NUMERIC NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Индексы, на которые опирается выборка кандидатов-реквизитов
+CREATE INDEX IF NOT EXISTS "Terminal_traider_account_id_idx" ON "Terminal" (traider_account_id);
+CREATE INDEX IF NOT EXISTS "Requisite_terminal_id_idx" ON "Requisite" (terminal_id);
+CREATE INDEX IF NOT EXISTS "Requisite_type_working_idx" ON "Requisite" (type)
+    WHERE is_can_work = TRUE AND is_blocked = FALSE;
+CREATE INDEX IF NOT EXISTS "MerchantInvoicesInOnTraiderAccount_traider_account_id_idx"
+    ON "MerchantInvoicesInOnTraiderAccount" (traider_account_id);
+CREATE INDEX IF NOT EXISTS "InvoiceIn_created_at_idx" ON "InvoiceIn" (created_at);
+-- Исключение реквизитов с активным Invoice на ту же сумму
+CREATE INDEX IF NOT EXISTS "InvoiceIn_active_requisite_amount_idx" ON "InvoiceIn" (requisite_id, amount)
+    WHERE status = 'CREATED';
+-- Поиск просроченных Invoice
+CREATE INDEX IF NOT EXISTS "InvoiceIn_active_time_expires_idx" ON "InvoiceIn" (time_expires)
+    WHERE status = 'CREATED';
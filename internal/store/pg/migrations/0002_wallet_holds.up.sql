@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS "WalletHold" (
+    id         TEXT PRIMARY KEY,
+    wallet_id  TEXT NOT NULL REFERENCES "Wallet" (id),
+    invoice_id TEXT NOT NULL REFERENCES "InvoiceIn" (id),
+    amount     NUMERIC NOT NULL CHECK (amount > 0),
+    status     TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "WalletHold_invoice_id_key" ON "WalletHold" (invoice_id);
+-- Сумма активных hold по кошельку в выборке кандидатов и при создании Invoice
+CREATE INDEX IF NOT EXISTS "WalletHold_active_wallet_id_idx" ON "WalletHold" (wallet_id)
+    INCLUDE (amount)
+    WHERE status = 'ACTIVE';
+
+-- Ставим hold под Invoice, созданные до появления механизма
+INSERT INTO "WalletHold" (id, wallet_id, invoice_id, amount, status)
+SELECT gen_random_uuid()::text, ta.wallet_id, i.id, i.amount, 'ACTIVE'
+FROM "InvoiceIn" i
+JOIN "TraiderAccount" ta ON ta.id = i.traider_account_id
+WHERE i.status = 'CREATED'
+ON CONFLICT DO NOTHING;
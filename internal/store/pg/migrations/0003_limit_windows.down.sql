@@ -0,0 +1,5 @@
+ALTER TABLE "Requisite" DROP COLUMN IF EXISTS daily_limit_invoices_window_hours;
+
+ALTER TABLE "Terminal"
+    DROP COLUMN IF EXISTS daily_limit_invoices_window_hours,
+    DROP COLUMN IF EXISTS daily_limit_money_window_hours;
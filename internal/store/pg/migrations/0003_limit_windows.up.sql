@@ -0,0 +1,11 @@
+-- NULL: дневной лимит считается за календарный день в часовом поясе бизнеса,
+-- N: за скользящие N часов
+ALTER TABLE "Terminal"
+    ADD COLUMN IF NOT EXISTS daily_limit_money_window_hours INTEGER
+        CHECK (daily_limit_money_window_hours > 0),
+    ADD COLUMN IF NOT EXISTS daily_limit_invoices_window_hours INTEGER
+        CHECK (daily_limit_invoices_window_hours > 0);
+
+ALTER TABLE "Requisite"
+    ADD COLUMN IF NOT EXISTS daily_limit_invoices_window_hours INTEGER
+        CHECK (daily_limit_invoices_window_hours > 0);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "CapacityCounterBucket";
+DROP TABLE IF EXISTS "CapacityCounter";
@@ -0,0 +1,51 @@
+CREATE TABLE IF NOT EXISTS "CapacityCounter" (
+    scope             TEXT NOT NULL,
+    entity_id         TEXT NOT NULL,
+    active_count      INTEGER NOT NULL DEFAULT 0,
+    active_sum        NUMERIC NOT NULL DEFAULT 0,
+    last_invoice_time TIMESTAMPTZ,
+    PRIMARY KEY (scope, entity_id)
+);
+
+CREATE TABLE IF NOT EXISTS "CapacityCounterBucket" (
+    scope         TEXT NOT NULL,
+    entity_id     TEXT NOT NULL,
+    bucket        TIMESTAMPTZ NOT NULL,
+    invoice_count INTEGER NOT NULL DEFAULT 0,
+    invoice_sum   NUMERIC NOT NULL DEFAULT 0,
+    PRIMARY KEY (scope, entity_id, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS "CapacityCounterBucket_bucket_idx" ON "CapacityCounterBucket" (bucket);
+
+-- Начальное заполнение; дальше счетчики поддерживает сервис, расхождения чинит cmd/counters rebuild
+INSERT INTO "CapacityCounter" (scope, entity_id, active_count, active_sum, last_invoice_time)
+SELECT e.scope, e.entity_id,
+    COUNT(*) FILTER (WHERE i.status = 'CREATED'),
+    COALESCE(SUM(i.amount) FILTER (WHERE i.status = 'CREATED'), 0),
+    MAX(i.created_at)
+FROM "InvoiceIn" i
+CROSS JOIN LATERAL (VALUES
+    ('ACCOUNT', i.traider_account_id),
+    ('TERMINAL', i.terminal_id),
+    ('REQUISITE', i.requisite_id)
+) AS e(scope, entity_id)
+WHERE i.status = 'CREATED' OR i.created_at >= NOW() - INTERVAL '2 days'
+GROUP BY e.scope, e.entity_id
+ON CONFLICT DO NOTHING;
+
+INSERT INTO "CapacityCounterBucket" (scope, entity_id, bucket, invoice_count, invoice_sum)
+SELECT e.scope, e.entity_id,
+    date_bin('15 minutes', i.created_at, TIMESTAMPTZ 'epoch') AS bucket,
+    COUNT(*),
+    SUM(i.amount)
+FROM "InvoiceIn" i
+CROSS JOIN LATERAL (VALUES
+    ('ACCOUNT', i.traider_account_id),
+    ('TERMINAL', i.terminal_id),
+    ('REQUISITE', i.requisite_id)
+) AS e(scope, entity_id)
+WHERE i.created_at >= NOW() - INTERVAL '2 days'
+    AND i.status IN ('CREATED','SUCCESS','SUCCESS_HAND','SUCCESS_APPEAL')
+GROUP BY e.scope, e.entity_id, bucket
+ON CONFLICT DO NOTHING;
@@ -0,0 +1 @@
+ALTER TABLE "Merchant" DROP COLUMN IF EXISTS is_sandbox;
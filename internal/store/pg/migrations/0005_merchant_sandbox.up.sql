@@ -0,0 +1,3 @@
+-- Мерчанты песочницы получают реквизиты фиктивного пула и не затрагивают реальных трейдеров
+ALTER TABLE "Merchant"
+    ADD COLUMN IF NOT EXISTS is_sandbox BOOLEAN NOT NULL DEFAULT FALSE;
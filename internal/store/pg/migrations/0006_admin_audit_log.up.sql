@@ -0,0 +1,15 @@
+-- Журнал изменений, сделанных через админку; пишется в транзакции самого изменения
+CREATE TABLE IF NOT EXISTS "AdminAuditLog" (
+    id          TEXT PRIMARY KEY,
+    actor       TEXT NOT NULL,
+    action      TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id   TEXT NOT NULL,
+    before      JSONB,
+    after       JSONB,
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "AdminAuditLog_entity_idx"
+    ON "AdminAuditLog" (entity_type, entity_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS "AdminAuditLog_created_at_idx" ON "AdminAuditLog" (created_at DESC);
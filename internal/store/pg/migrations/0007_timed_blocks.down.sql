@@ -0,0 +1,18 @@
+DROP INDEX IF EXISTS "Requisite_type_can_work_idx";
+CREATE INDEX IF NOT EXISTS "Requisite_type_working_idx" ON "Requisite" (type)
+    WHERE is_can_work = TRUE AND is_blocked = FALSE;
+
+ALTER TABLE "Requisite"
+    DROP COLUMN IF EXISTS blocked_by,
+    DROP COLUMN IF EXISTS blocked_reason,
+    DROP COLUMN IF EXISTS blocked_until;
+
+ALTER TABLE "Terminal"
+    DROP COLUMN IF EXISTS blocked_by,
+    DROP COLUMN IF EXISTS blocked_reason,
+    DROP COLUMN IF EXISTS blocked_until;
+
+ALTER TABLE "TraiderAccount"
+    DROP COLUMN IF EXISTS blocked_by,
+    DROP COLUMN IF EXISTS blocked_reason,
+    DROP COLUMN IF EXISTS blocked_until;
@@ -0,0 +1,21 @@
+-- Блокировки из админки: причина, автор и необязательный срок. Блокировка с истекшим
+-- blocked_until при выборе реквизитов не учитывается, хотя is_blocked остается TRUE.
+ALTER TABLE "TraiderAccount"
+    ADD COLUMN IF NOT EXISTS blocked_until  TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS blocked_reason TEXT,
+    ADD COLUMN IF NOT EXISTS blocked_by     TEXT;
+
+ALTER TABLE "Terminal"
+    ADD COLUMN IF NOT EXISTS blocked_until  TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS blocked_reason TEXT,
+    ADD COLUMN IF NOT EXISTS blocked_by     TEXT;
+
+ALTER TABLE "Requisite"
+    ADD COLUMN IF NOT EXISTS blocked_until  TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS blocked_reason TEXT,
+    ADD COLUMN IF NOT EXISTS blocked_by     TEXT;
+
+-- Индекс рабочих реквизитов больше не может опираться на is_blocked
+DROP INDEX IF EXISTS "Requisite_type_working_idx";
+CREATE INDEX IF NOT EXISTS "Requisite_type_can_work_idx" ON "Requisite" (type)
+    WHERE is_can_work = TRUE;
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS "TeamNotification";
+
+DROP INDEX IF EXISTS "InvoiceIn_terminal_created_at_idx";
+DROP INDEX IF EXISTS "InvoiceIn_requisite_created_at_idx";
+
+ALTER TABLE "Terminal"
+    DROP COLUMN IF EXISTS quarantine_reason,
+    DROP COLUMN IF EXISTS quarantined_until;
+
+ALTER TABLE "Requisite"
+    DROP COLUMN IF EXISTS quarantine_reason,
+    DROP COLUMN IF EXISTS quarantined_until;
@@ -0,0 +1,25 @@
+-- Автоматический карантин реквизитов и терминалов, у которых Invoice истекают неоплаченными
+ALTER TABLE "Requisite"
+    ADD COLUMN IF NOT EXISTS quarantined_until TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS quarantine_reason TEXT;
+
+ALTER TABLE "Terminal"
+    ADD COLUMN IF NOT EXISTS quarantined_until TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS quarantine_reason TEXT;
+
+-- Исходы последних Invoice реквизита и терминала
+CREATE INDEX IF NOT EXISTS "InvoiceIn_requisite_created_at_idx" ON "InvoiceIn" (requisite_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS "InvoiceIn_terminal_created_at_idx" ON "InvoiceIn" (terminal_id, created_at DESC);
+
+-- Outbox уведомлений команд трейдеров; пишется в транзакции события
+CREATE TABLE IF NOT EXISTS "TeamNotification" (
+    id         TEXT PRIMARY KEY,
+    team_id    TEXT,
+    kind       TEXT NOT NULL,
+    payload    JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    sent_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS "TeamNotification_pending_idx" ON "TeamNotification" (created_at)
+    WHERE sent_at IS NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE "CapacityCounterBucket"
+    DROP COLUMN IF EXISTS success_count,
+    DROP COLUMN IF EXISTS closed_count;
@@ -0,0 +1,24 @@
+-- Исходы Invoice в корзинах счетчиков для оценки конверсии терминалов и реквизитов.
+-- closed_count — оплаченные и истекшие Invoice корзины создания, success_count — из них оплаченные;
+-- отмененные мерчантом не учитываются.
+ALTER TABLE "CapacityCounterBucket"
+    ADD COLUMN IF NOT EXISTS closed_count  INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS success_count INTEGER NOT NULL DEFAULT 0;
+
+INSERT INTO "CapacityCounterBucket" (scope, entity_id, bucket, closed_count, success_count)
+SELECT e.scope, e.entity_id,
+    date_bin('15 minutes', i.created_at, TIMESTAMPTZ 'epoch') AS bucket,
+    COUNT(*),
+    COUNT(*) FILTER (WHERE i.status <> 'EXPIRED')
+FROM "InvoiceIn" i
+CROSS JOIN LATERAL (VALUES
+    ('ACCOUNT', i.traider_account_id),
+    ('TERMINAL', i.terminal_id),
+    ('REQUISITE', i.requisite_id)
+) AS e(scope, entity_id)
+WHERE i.created_at >= NOW() - INTERVAL '2 days'
+    AND i.status IN ('SUCCESS','SUCCESS_HAND','SUCCESS_APPEAL','EXPIRED')
+GROUP BY e.scope, e.entity_id, bucket
+ON CONFLICT (scope, entity_id, bucket) DO UPDATE SET
+    closed_count = EXCLUDED.closed_count,
+    success_count = EXCLUDED.success_count;
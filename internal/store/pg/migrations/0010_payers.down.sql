@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "PayerBlock";
+
+DROP INDEX IF EXISTS "InvoiceIn_payer_idx";
+
+ALTER TABLE "InvoiceIn" DROP COLUMN IF EXISTS payer_id;
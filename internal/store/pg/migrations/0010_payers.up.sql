@@ -0,0 +1,19 @@
+-- Идентификатор плательщика на стороне мерчанта; NULL, если мерчант его не передал
+ALTER TABLE "InvoiceIn" ADD COLUMN IF NOT EXISTS payer_id TEXT;
+
+-- Лимиты плательщика и реквизиты, на которых он оставлял Invoice неоплаченными
+CREATE INDEX IF NOT EXISTS "InvoiceIn_payer_idx" ON "InvoiceIn" (merchant_id, payer_id, created_at DESC)
+    WHERE payer_id IS NOT NULL;
+
+-- Списки блокировки плательщиков мерчантов и команд трейдеров
+CREATE TABLE IF NOT EXISTS "PayerBlock" (
+    scope      TEXT NOT NULL CHECK (scope IN ('merchant', 'team')),
+    scope_id   TEXT NOT NULL,
+    payer_id   TEXT NOT NULL,
+    reason     TEXT NOT NULL,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (scope, scope_id, payer_id)
+);
+
+CREATE INDEX IF NOT EXISTS "PayerBlock_payer_idx" ON "PayerBlock" (payer_id);
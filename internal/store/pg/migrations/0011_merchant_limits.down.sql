@@ -0,0 +1,13 @@
+DELETE FROM "CapacityCounterBucket" WHERE scope = 'MERCHANT';
+DELETE FROM "CapacityCounter" WHERE scope = 'MERCHANT';
+
+ALTER TABLE "Merchant"
+    DROP COLUMN IF EXISTS daily_invoice_limit,
+    DROP COLUMN IF EXISTS daily_turnover_limit,
+    DROP COLUMN IF EXISTS in_disabled_sbp,
+    DROP COLUMN IF EXISTS in_disabled_wallet,
+    DROP COLUMN IF EXISTS in_disabled_card,
+    DROP COLUMN IF EXISTS in_max_sbp,
+    DROP COLUMN IF EXISTS in_max_wallet,
+    DROP COLUMN IF EXISTS in_max_card,
+    DROP COLUMN IF EXISTS status;
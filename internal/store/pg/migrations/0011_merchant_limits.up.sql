@@ -0,0 +1,39 @@
+-- Статус мерчанта, максимальные суммы и выключение приема по типам реквизитов, дневные лимиты.
+-- Нулевые лимиты не ограничивают.
+ALTER TABLE "Merchant"
+    ADD COLUMN IF NOT EXISTS status               TEXT    NOT NULL DEFAULT 'ACTIVE'
+        CHECK (status IN ('ACTIVE', 'SUSPENDED')),
+    ADD COLUMN IF NOT EXISTS in_max_card          NUMERIC NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS in_max_wallet        NUMERIC NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS in_max_sbp           NUMERIC NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS in_disabled_card     BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS in_disabled_wallet   BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS in_disabled_sbp      BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS daily_turnover_limit NUMERIC NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS daily_invoice_limit  INTEGER NOT NULL DEFAULT 0;
+
+-- Оборот мерчанта ведется в счетчиках загрузки со scope MERCHANT; переносим последние Invoice
+INSERT INTO "CapacityCounter" (scope, entity_id, active_count, active_sum, last_invoice_time)
+SELECT 'MERCHANT', i.merchant_id,
+    COUNT(*) FILTER (WHERE i.status = 'CREATED'),
+    COALESCE(SUM(i.amount) FILTER (WHERE i.status = 'CREATED'), 0),
+    MAX(i.created_at)
+FROM "InvoiceIn" i
+WHERE i.status = 'CREATED' OR i.created_at >= NOW() - INTERVAL '2 days'
+GROUP BY i.merchant_id
+ON CONFLICT (scope, entity_id) DO NOTHING;
+
+INSERT INTO "CapacityCounterBucket" (
+    scope, entity_id, bucket, invoice_count, invoice_sum, closed_count, success_count
+)
+SELECT 'MERCHANT', i.merchant_id,
+    date_bin('15 minutes', i.created_at, TIMESTAMPTZ 'epoch') AS bucket,
+    COUNT(*) FILTER (WHERE i.status <> 'EXPIRED'),
+    COALESCE(SUM(i.amount) FILTER (WHERE i.status <> 'EXPIRED'), 0),
+    COUNT(*) FILTER (WHERE i.status <> 'CREATED'),
+    COUNT(*) FILTER (WHERE i.status IN ('SUCCESS','SUCCESS_HAND','SUCCESS_APPEAL'))
+FROM "InvoiceIn" i
+WHERE i.created_at >= NOW() - INTERVAL '2 days'
+    AND i.status IN ('CREATED','SUCCESS','SUCCESS_HAND','SUCCESS_APPEAL','EXPIRED')
+GROUP BY i.merchant_id, bucket
+ON CONFLICT (scope, entity_id, bucket) DO NOTHING;
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS "Settings_cache_invalidation" ON "Settings";
+DROP TRIGGER IF EXISTS "Team_cache_invalidation" ON "Team";
+DROP TRIGGER IF EXISTS "Merchant_cache_truncate" ON "Merchant";
+DROP TRIGGER IF EXISTS "Merchant_cache_invalidation" ON "Merchant";
+DROP FUNCTION IF EXISTS notify_cache_invalidation();
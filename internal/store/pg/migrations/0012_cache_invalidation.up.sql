@@ -0,0 +1,35 @@
+-- Изменения кешируемых таблиц публикуются в канал cache_invalidation после коммита.
+-- Payload — JSON {"table": ..., "id": ...}; пустой id сбрасывает все записи таблицы.
+CREATE OR REPLACE FUNCTION notify_cache_invalidation() RETURNS trigger AS $$
+BEGIN
+    IF TG_LEVEL = 'ROW' THEN
+        PERFORM pg_notify('cache_invalidation', json_build_object(
+            'table', TG_TABLE_NAME,
+            'id', CASE WHEN TG_OP = 'DELETE' THEN OLD.id ELSE NEW.id END
+        )::text);
+    ELSE
+        PERFORM pg_notify('cache_invalidation', json_build_object('table', TG_TABLE_NAME, 'id', '')::text);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS "Merchant_cache_invalidation" ON "Merchant";
+CREATE TRIGGER "Merchant_cache_invalidation"
+    AFTER INSERT OR UPDATE OR DELETE ON "Merchant"
+    FOR EACH ROW EXECUTE FUNCTION notify_cache_invalidation();
+
+DROP TRIGGER IF EXISTS "Merchant_cache_truncate" ON "Merchant";
+CREATE TRIGGER "Merchant_cache_truncate"
+    AFTER TRUNCATE ON "Merchant"
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_cache_invalidation();
+
+DROP TRIGGER IF EXISTS "Team_cache_invalidation" ON "Team";
+CREATE TRIGGER "Team_cache_invalidation"
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON "Team"
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_cache_invalidation();
+
+DROP TRIGGER IF EXISTS "Settings_cache_invalidation" ON "Settings";
+CREATE TRIGGER "Settings_cache_invalidation"
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON "Settings"
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_cache_invalidation();
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS "Settings_updated_at" ON "Settings";
+DROP FUNCTION IF EXISTS touch_settings_updated_at();
+
+ALTER TABLE "Settings"
+    DROP COLUMN IF EXISTS updated_at;
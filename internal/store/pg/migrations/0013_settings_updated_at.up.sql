@@ -0,0 +1,15 @@
+-- Время последнего изменения курса для проверки готовности
+ALTER TABLE "Settings"
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE OR REPLACE FUNCTION touch_settings_updated_at() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at := now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS "Settings_updated_at" ON "Settings";
+CREATE TRIGGER "Settings_updated_at"
+    BEFORE UPDATE ON "Settings"
+    FOR EACH ROW EXECUTE FUNCTION touch_settings_updated_at();
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS "InvoiceIn_close_hold" ON "InvoiceIn";
+DROP FUNCTION IF EXISTS close_invoice_hold();
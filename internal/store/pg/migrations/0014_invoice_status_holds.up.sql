@@ -0,0 +1,55 @@
+-- Hold закрывается при выходе Invoice из CREATED независимо от того, кто меняет статус:
+-- FinalizeInvoice или внешняя платежная система. Успешная оплата списывает hold
+-- с pay_in_balance, истечение и отмена освобождают его.
+CREATE OR REPLACE FUNCTION close_invoice_hold() RETURNS trigger AS $$
+DECLARE
+    hold_status TEXT;
+BEGIN
+    hold_status := CASE
+        WHEN NEW.status IN ('SUCCESS', 'SUCCESS_HAND', 'SUCCESS_APPEAL') THEN 'CAPTURED'
+        ELSE 'RELEASED'
+    END;
+
+    WITH closed AS (
+        UPDATE "WalletHold"
+        SET status = hold_status, updated_at = NOW()
+        WHERE invoice_id = NEW.id AND status = 'ACTIVE'
+        RETURNING wallet_id, amount
+    )
+    UPDATE "Wallet" w
+    SET pay_in_balance = w.pay_in_balance - closed.amount
+    FROM closed
+    WHERE w.id = closed.wallet_id AND hold_status = 'CAPTURED';
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS "InvoiceIn_close_hold" ON "InvoiceIn";
+CREATE TRIGGER "InvoiceIn_close_hold"
+    AFTER UPDATE OF status ON "InvoiceIn"
+    FOR EACH ROW
+    WHEN (OLD.status = 'CREATED' AND NEW.status <> 'CREATED')
+    EXECUTE FUNCTION close_invoice_hold();
+
+-- Закрываем hold, оставшиеся активными у Invoice, оплаченных во внешней системе
+WITH closed AS (
+    UPDATE "WalletHold" h
+    SET status = CASE
+            WHEN i.status IN ('SUCCESS', 'SUCCESS_HAND', 'SUCCESS_APPEAL') THEN 'CAPTURED'
+            ELSE 'RELEASED'
+        END,
+        updated_at = NOW()
+    FROM "InvoiceIn" i
+    WHERE i.id = h.invoice_id AND h.status = 'ACTIVE' AND i.status <> 'CREATED'
+    RETURNING h.wallet_id, h.amount, h.status
+), captured AS (
+    SELECT wallet_id, SUM(amount) AS amount
+    FROM closed
+    WHERE status = 'CAPTURED'
+    GROUP BY wallet_id
+)
+UPDATE "Wallet" w
+SET pay_in_balance = w.pay_in_balance - captured.amount
+FROM captured
+WHERE w.id = captured.wallet_id;
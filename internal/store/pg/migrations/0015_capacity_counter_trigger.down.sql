@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS "InvoiceIn_capacity_counters_close" ON "InvoiceIn";
+DROP TRIGGER IF EXISTS "InvoiceIn_capacity_counters_insert" ON "InvoiceIn";
+DROP FUNCTION IF EXISTS update_capacity_counters();
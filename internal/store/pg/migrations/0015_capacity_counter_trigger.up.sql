@@ -0,0 +1,90 @@
+-- Счетчики загрузки ведет триггер на "InvoiceIn", поэтому они учитывают и статусы,
+-- которые выставляет внешняя платежная система. Новый Invoice попадает в активные
+-- счетчики и в корзину создания; выход из CREATED снимает его с активных, а истекшие
+-- и отмененные Invoice убираются из корзин, поскольку в дневные лимиты идут только
+-- созданные и оплаченные.
+CREATE OR REPLACE FUNCTION update_capacity_counters() RETURNS trigger AS $$
+DECLARE
+    invoice_bucket TIMESTAMPTZ := date_bin('15 minutes', NEW.created_at, TIMESTAMPTZ 'epoch');
+    is_active      BOOLEAN := NEW.status = 'CREATED';
+    is_success     BOOLEAN := NEW.status IN ('SUCCESS', 'SUCCESS_HAND', 'SUCCESS_APPEAL');
+    entity         RECORD;
+BEGIN
+    FOR entity IN
+        SELECT * FROM (VALUES
+            ('ACCOUNT', NEW.traider_account_id),
+            ('TERMINAL', NEW.terminal_id),
+            ('REQUISITE', NEW.requisite_id),
+            ('MERCHANT', NEW.merchant_id)
+        ) AS e(entity_scope, entity_key)
+    LOOP
+        IF TG_OP = 'INSERT' THEN
+            INSERT INTO "CapacityCounter" (scope, entity_id, active_count, active_sum, last_invoice_time)
+            VALUES (
+                entity.entity_scope, entity.entity_key,
+                CASE WHEN is_active THEN 1 ELSE 0 END,
+                CASE WHEN is_active THEN NEW.amount ELSE 0 END,
+                NEW.created_at
+            )
+            ON CONFLICT (scope, entity_id) DO UPDATE SET
+                active_count = "CapacityCounter".active_count + EXCLUDED.active_count,
+                active_sum = "CapacityCounter".active_sum + EXCLUDED.active_sum,
+                last_invoice_time = GREATEST("CapacityCounter".last_invoice_time, EXCLUDED.last_invoice_time);
+
+            IF is_active OR is_success THEN
+                INSERT INTO "CapacityCounterBucket" (scope, entity_id, bucket, invoice_count, invoice_sum)
+                VALUES (entity.entity_scope, entity.entity_key, invoice_bucket, 1, NEW.amount)
+                ON CONFLICT (scope, entity_id, bucket) DO UPDATE SET
+                    invoice_count = "CapacityCounterBucket".invoice_count + 1,
+                    invoice_sum = "CapacityCounterBucket".invoice_sum + EXCLUDED.invoice_sum;
+            END IF;
+        ELSE
+            UPDATE "CapacityCounter"
+            SET active_count = GREATEST(active_count - 1, 0),
+                active_sum = GREATEST(active_sum - NEW.amount, 0)
+            WHERE scope = entity.entity_scope AND entity_id = entity.entity_key;
+
+            IF NOT is_success THEN
+                UPDATE "CapacityCounterBucket"
+                SET invoice_count = GREATEST(invoice_count - 1, 0),
+                    invoice_sum = GREATEST(invoice_sum - NEW.amount, 0)
+                WHERE scope = entity.entity_scope AND entity_id = entity.entity_key AND bucket = invoice_bucket;
+            END IF;
+        END IF;
+    END LOOP;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS "InvoiceIn_capacity_counters_insert" ON "InvoiceIn";
+CREATE TRIGGER "InvoiceIn_capacity_counters_insert"
+    AFTER INSERT ON "InvoiceIn"
+    FOR EACH ROW EXECUTE FUNCTION update_capacity_counters();
+
+DROP TRIGGER IF EXISTS "InvoiceIn_capacity_counters_close" ON "InvoiceIn";
+CREATE TRIGGER "InvoiceIn_capacity_counters_close"
+    AFTER UPDATE OF status ON "InvoiceIn"
+    FOR EACH ROW
+    WHEN (OLD.status = 'CREATED' AND NEW.status <> 'CREATED')
+    EXECUTE FUNCTION update_capacity_counters();
+
+-- Активные счетчики, завышенные Invoice, закрытыми во внешней системе, пересчитываем
+-- по "InvoiceIn"; корзины чинит cmd/counters rebuild
+UPDATE "CapacityCounter" c
+SET active_count = COALESCE(a.active_count, 0),
+    active_sum = COALESCE(a.active_sum, 0)
+FROM "CapacityCounter" k
+LEFT JOIN (
+    SELECT e.scope, e.entity_id, COUNT(*) AS active_count, SUM(i.amount) AS active_sum
+    FROM "InvoiceIn" i
+    CROSS JOIN LATERAL (VALUES
+        ('ACCOUNT', i.traider_account_id),
+        ('TERMINAL', i.terminal_id),
+        ('REQUISITE', i.requisite_id),
+        ('MERCHANT', i.merchant_id)
+    ) AS e(scope, entity_id)
+    WHERE i.status = 'CREATED'
+    GROUP BY e.scope, e.entity_id
+) a ON a.scope = k.scope AND a.entity_id = k.entity_id
+WHERE k.scope = c.scope AND k.entity_id = c.entity_id;
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS "InvoiceIn_capacity_outcomes" ON "InvoiceIn";
+DROP FUNCTION IF EXISTS record_invoice_outcome();
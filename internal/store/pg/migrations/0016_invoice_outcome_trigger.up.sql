@@ -0,0 +1,50 @@
+-- Исходы Invoice для оценки конверсии учитываются триггером, как и остальные счетчики:
+-- оплата и истечение попадают в closed_count корзины создания Invoice, оплата — еще и в success_count.
+CREATE OR REPLACE FUNCTION record_invoice_outcome() RETURNS trigger AS $$
+DECLARE
+    invoice_bucket TIMESTAMPTZ := date_bin('15 minutes', NEW.created_at, TIMESTAMPTZ 'epoch');
+    success        INTEGER := CASE WHEN NEW.status IN ('SUCCESS', 'SUCCESS_HAND', 'SUCCESS_APPEAL') THEN 1 ELSE 0 END;
+BEGIN
+    UPDATE "CapacityCounterBucket" b
+    SET closed_count = b.closed_count + 1,
+        success_count = b.success_count + success
+    FROM (VALUES
+        ('ACCOUNT', NEW.traider_account_id),
+        ('TERMINAL', NEW.terminal_id),
+        ('REQUISITE', NEW.requisite_id),
+        ('MERCHANT', NEW.merchant_id)
+    ) AS e(entity_scope, entity_key)
+    WHERE b.scope = e.entity_scope AND b.entity_id = e.entity_key AND b.bucket = invoice_bucket;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS "InvoiceIn_capacity_outcomes" ON "InvoiceIn";
+CREATE TRIGGER "InvoiceIn_capacity_outcomes"
+    AFTER UPDATE OF status ON "InvoiceIn"
+    FOR EACH ROW
+    WHEN (OLD.status = 'CREATED' AND NEW.status IN ('SUCCESS', 'SUCCESS_HAND', 'SUCCESS_APPEAL', 'EXPIRED'))
+    EXECUTE FUNCTION record_invoice_outcome();
+
+-- Исходы Invoice, закрытых во внешней системе, пересчитываем по "InvoiceIn"
+UPDATE "CapacityCounterBucket" b
+SET closed_count = o.closed_count,
+    success_count = o.success_count
+FROM (
+    SELECT e.scope, e.entity_id,
+        date_bin('15 minutes', i.created_at, TIMESTAMPTZ 'epoch') AS bucket,
+        COUNT(*) FILTER (WHERE i.status <> 'CANCELED') AS closed_count,
+        COUNT(*) FILTER (WHERE i.status <> 'EXPIRED' AND i.status <> 'CANCELED') AS success_count
+    FROM "InvoiceIn" i
+    CROSS JOIN LATERAL (VALUES
+        ('ACCOUNT', i.traider_account_id),
+        ('TERMINAL', i.terminal_id),
+        ('REQUISITE', i.requisite_id),
+        ('MERCHANT', i.merchant_id)
+    ) AS e(scope, entity_id)
+    WHERE i.status <> 'CREATED'
+        AND i.created_at >= (SELECT MIN(bucket) FROM "CapacityCounterBucket")
+    GROUP BY e.scope, e.entity_id, 3
+) o
+WHERE o.scope = b.scope AND o.entity_id = b.entity_id AND o.bucket = b.bucket;
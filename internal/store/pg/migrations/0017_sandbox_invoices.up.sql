@@ -0,0 +1,28 @@
+-- Invoice мерчантов песочницы. Хранятся отдельно от "InvoiceIn", чтобы не попадать
+-- во внешнюю платежную систему и в счетчики реальных трейдеров, но в общей базе,
+-- чтобы имитация оплаты работала на любой реплике.
+CREATE TABLE IF NOT EXISTS "SandboxInvoice" (
+    id                  TEXT PRIMARY KEY,
+    merchant_id         TEXT NOT NULL,
+    amount              NUMERIC NOT NULL,
+    status              TEXT NOT NULL,
+    type                TEXT NOT NULL,
+    terminal_id         TEXT NOT NULL,
+    user_id             TEXT NOT NULL,
+    bank_id             TEXT NOT NULL,
+    traider_account_id  TEXT NOT NULL,
+    requisite_id        TEXT NOT NULL,
+    callback_url        TEXT NOT NULL,
+    callback_key        TEXT NOT NULL DEFAULT '',
+    internal_request_id TEXT NOT NULL DEFAULT '',
+    time_expires        TIMESTAMPTZ NOT NULL,
+    exchange            NUMERIC NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL,
+    payer_id            TEXT
+);
+
+-- Активные Invoice исключают реквизит с той же суммой и ищутся планировщиком истечения
+CREATE INDEX IF NOT EXISTS "SandboxInvoice_active_idx" ON "SandboxInvoice" (time_expires)
+    INCLUDE (requisite_id, amount)
+    WHERE status = 'CREATED';
+CREATE INDEX IF NOT EXISTS "SandboxInvoice_created_at_idx" ON "SandboxInvoice" (created_at);
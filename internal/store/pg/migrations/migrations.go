@@ -0,0 +1,379 @@
+// Package migrations содержит версионированные SQL-миграции схемы, встроенные в бинарник,
+// и применяет их к базе.
+//
+// Каждая миграция — пара файлов NNNN_name.up.sql и NNNN_name.down.sql. Примененные версии
+// хранятся в таблице schema_migrations; каждая миграция выполняется в своей транзакции.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+var (
+	ErrorSchemaBehind   = errors.New("database schema is behind the service")
+	ErrorNoMigrations   = errors.New("no migrations to roll back")
+	ErrorInvalidName    = errors.New("invalid migration name")
+	ErrorMissingDownSQL = errors.New("migration has no down script")
+	ErrorBaseline       = errors.New("baseline migration cannot be rolled back")
+)
+
+// baselineVersion начальная схема с рабочими таблицами; ее откат удалил бы данные
+const baselineVersion = 1
+
+// advisoryLockID сериализует миграции, запущенные с нескольких реплик одновременно
+const advisoryLockID = 7_311_482_005
+
+var (
+	fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	namePattern     = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus состояние миграции в базе
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	conn       *pgxpool.Pool
+	migrations []Migration
+}
+
+func New(conn *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{conn: conn, migrations: migrations}, nil
+}
+
+// Load читает встроенные миграции, упорядоченные по версии
+func Load() ([]Migration, error) {
+	return load(files)
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, errors.Wrap(err, "read migrations")
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse version of %s", entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, errors.Wrapf(err, "read %s", entry.Name())
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, errors.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, errors.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Latest возвращает версию последней встроенной миграции
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version возвращает последнюю примененную к базе версию схемы.
+// Не меняет базу: без таблицы schema_migrations версия считается нулевой.
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int64
+	for v := range applied {
+		version = max(version, v)
+	}
+	return version, nil
+}
+
+// CheckVersion возвращает ErrorSchemaBehind, если к базе применены не все встроенные миграции.
+// Более новая схема допускается: ее могла применить следующая версия сервиса при выкатке.
+func (m *Migrator) CheckVersion(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			missing = append(missing, fmt.Sprintf("%d_%s", migration.Version, migration.Name))
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Wrapf(ErrorSchemaBehind, "not applied: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Status возвращает все встроенные миграции с отметкой о применении
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Up применяет все непримененные миграции и возвращает их
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		if err := m.ensureTable(ctx); err != nil {
+			return err
+		}
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx,
+					`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+					migration.Version, migration.Name,
+				)
+				return err
+			})
+			if err != nil {
+				return errors.Wrapf(err, "apply migration %d_%s", migration.Version, migration.Name)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down откатывает steps последних примененных миграций и возвращает их
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			return ErrorNoMigrations
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if migration.Version <= baselineVersion {
+				return errors.Wrapf(ErrorBaseline, "%d_%s", migration.Version, migration.Name)
+			}
+			if migration.Down == "" {
+				return errors.Wrapf(ErrorMissingDownSQL, "%d_%s", migration.Version, migration.Name)
+			}
+
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+				return err
+			})
+			if err != nil {
+				return errors.Wrapf(err, "roll back migration %d_%s", migration.Version, migration.Name)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Create создает в dir заготовки up/down файлов миграции со следующим номером версии
+func Create(dir string, name string) (upPath string, downPath string, err error) {
+	if !namePattern.MatchString(name) {
+		return "", "", errors.Wrapf(ErrorInvalidName, "%q: use lowercase letters, digits and underscores", name)
+	}
+
+	migrations, err := load(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+
+	var version int64 = 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	base := fmt.Sprintf("%04d_%s", version, name)
+	upPath = filepath.Join(dir, base+".up.sql")
+	downPath = filepath.Join(dir, base+".down.sql")
+
+	for path, header := range map[string]string{
+		upPath:   fmt.Sprintf("-- %s: apply\n", base),
+		downPath: fmt.Sprintf("-- %s: roll back\n", base),
+	} {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "create %s", path)
+		}
+		if _, err := file.WriteString(header); err != nil {
+			file.Close()
+			return "", "", errors.Wrapf(err, "write %s", path)
+		}
+		if err := file.Close(); err != nil {
+			return "", "", errors.Wrapf(err, "close %s", path)
+		}
+	}
+
+	return upPath, downPath, nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return errors.Wrap(err, "create schema_migrations")
+	}
+	return nil
+}
+
+// applied возвращает примененные версии; отсутствующая таблица schema_migrations
+// означает, что миграции еще не применялись
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	var exists bool
+	err := m.conn.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		return nil, errors.Wrap(err, "check schema_migrations")
+	}
+	if !exists {
+		return map[int64]time.Time{}, nil
+	}
+
+	rows, err := m.conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, errors.Wrap(err, "select applied migrations")
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, errors.Wrap(err, "scan applied migration")
+		}
+		applied[version] = appliedAt
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "select applied migrations")
+	}
+	return applied, nil
+}
+
+// withLock выполняет fn на выделенном соединении под advisory lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.conn.Acquire(ctx)
+	if err != nil {
+		return errors.Wrap(err, "acquire connection")
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockID); err != nil {
+		return errors.Wrap(err, "acquire migration lock")
+	}
+	defer func() {
+		// Используем отдельный контекст: исходный может быть уже отменен
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, _ = conn.Exec(unlockCtx, `SELECT pg_advisory_unlock($1)`, advisoryLockID)
+	}()
+
+	return fn(conn)
+}
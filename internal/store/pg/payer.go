@@ -0,0 +1,203 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"mateo/internal/domain"
+	"time"
+)
+
+const (
+	auditActionPayerBlock   = "payer_block"
+	auditActionPayerUnblock = "payer_unblock"
+)
+
+// payerBlockScopeTables таблицы владельцев списков блокировки плательщиков
+var payerBlockScopeTables = map[domain.PayerBlockScope]string{
+	domain.PayerBlockScopeMerchant: "Merchant",
+	domain.PayerBlockScopeTeam:     "Team",
+}
+
+// auditPayerBlock плательщик в списке блокировки в журнале изменений
+type auditPayerBlock struct {
+	PayerID string `json:"payerId"`
+	Reason  string `json:"reason"`
+}
+
+// IsPayerBlocked сообщает, что плательщик в списке блокировки мерчанта
+func (s *Store) IsPayerBlocked(ctx context.Context, merchantID string, payerID string) (bool, error) {
+	const query = `
+		SELECT EXISTS (
+			SELECT 1 FROM "PayerBlock"
+			WHERE scope = 'merchant' AND scope_id = $1 AND payer_id = $2
+		)`
+
+	var blocked bool
+	if err := s.conn.QueryRow(ctx, query, merchantID, payerID).Scan(&blocked); err != nil {
+		log.Ctx(ctx).Error().Err(err).
+			Str("merchant_id", merchantID).
+			Msg("failed to check payer block")
+		return false, domain.ErrorFailedCheckPayer
+	}
+	return blocked, nil
+}
+
+// GetPayerActivity считает Invoice плательщика у мерчанта, созданные после since, и активные сейчас
+func (s *Store) GetPayerActivity(
+	ctx context.Context,
+	merchantID string,
+	payerID string,
+	since time.Time,
+) (domain.PayerActivity, error) {
+	const query = `
+		SELECT
+			COUNT(*) FILTER (WHERE created_at >= $3),
+			COUNT(*) FILTER (WHERE status = 'CREATED')
+		FROM "InvoiceIn"
+		WHERE merchant_id = $1 AND payer_id = $2 AND (created_at >= $3 OR status = 'CREATED')`
+
+	var activity domain.PayerActivity
+	if err := s.conn.QueryRow(ctx, query, merchantID, payerID, since).Scan(&activity.Created, &activity.Active); err != nil {
+		log.Ctx(ctx).Error().Err(err).
+			Str("merchant_id", merchantID).
+			Msg("failed to get payer activity")
+		return domain.PayerActivity{}, domain.ErrorFailedCheckPayer
+	}
+	return activity, nil
+}
+
+func (s *Store) ListPayerBlocks(ctx context.Context, scope domain.PayerBlockScope, scopeID string) ([]*domain.PayerBlock, error) {
+	const query = `
+		SELECT payer_id, reason, created_by, created_at
+		FROM "PayerBlock"
+		WHERE scope = $1 AND scope_id = $2
+		ORDER BY created_at DESC, payer_id`
+
+	rows, err := s.conn.Query(ctx, query, scope, scopeID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to list payer blocks")
+		return nil, domain.ErrorFailedGetBlock
+	}
+	defer rows.Close()
+
+	blocks := []*domain.PayerBlock{}
+	for rows.Next() {
+		b := &domain.PayerBlock{Scope: scope, ScopeID: scopeID}
+		if err := rows.Scan(&b.PayerID, &b.Reason, &b.CreatedBy, &b.CreatedAt); err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("failed to scan payer block")
+			return nil, domain.ErrorFailedGetBlock
+		}
+		blocks = append(blocks, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to list payer blocks")
+		return nil, domain.ErrorFailedGetBlock
+	}
+	return blocks, nil
+}
+
+// SetPayerBlock добавляет плательщика в список блокировки или меняет причину
+func (s *Store) SetPayerBlock(ctx context.Context, actor string, block *domain.PayerBlock) error {
+	return s.inAdminTx(ctx, func(tx pgx.Tx) error {
+		if err := checkPayerBlockScope(ctx, tx, block.Scope, block.ScopeID); err != nil {
+			return err
+		}
+
+		before, err := selectPayerBlock(ctx, tx, block.Scope, block.ScopeID, block.PayerID)
+		if err != nil {
+			return err
+		}
+
+		const query = `
+			INSERT INTO "PayerBlock" (scope, scope_id, payer_id, reason, created_by, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (scope, scope_id, payer_id) DO UPDATE SET
+				reason = EXCLUDED.reason,
+				created_by = EXCLUDED.created_by,
+				created_at = EXCLUDED.created_at`
+
+		_, err = tx.Exec(ctx, query, block.Scope, block.ScopeID, block.PayerID, block.Reason, block.CreatedBy, block.CreatedAt)
+		if err != nil {
+			return errors.Wrap(err, "upsert payer block")
+		}
+
+		// Новая запись пишется в журнал без прежнего состояния, а не с JSON null
+		var beforeState any
+		if before != nil {
+			beforeState = before
+		}
+		return s.writeAudit(ctx, tx, actor, auditActionPayerBlock, string(block.Scope), block.ScopeID,
+			beforeState, &auditPayerBlock{PayerID: block.PayerID, Reason: block.Reason})
+	})
+}
+
+// DeletePayerBlock убирает плательщика из списка блокировки; отсутствие в списке не ошибка
+func (s *Store) DeletePayerBlock(
+	ctx context.Context,
+	actor string,
+	scope domain.PayerBlockScope,
+	scopeID string,
+	payerID string,
+) error {
+	return s.inAdminTx(ctx, func(tx pgx.Tx) error {
+		if err := checkPayerBlockScope(ctx, tx, scope, scopeID); err != nil {
+			return err
+		}
+
+		before, err := selectPayerBlock(ctx, tx, scope, scopeID, payerID)
+		if err != nil || before == nil {
+			return err
+		}
+
+		const query = `DELETE FROM "PayerBlock" WHERE scope = $1 AND scope_id = $2 AND payer_id = $3`
+		if _, err := tx.Exec(ctx, query, scope, scopeID, payerID); err != nil {
+			return errors.Wrap(err, "delete payer block")
+		}
+
+		return s.writeAudit(ctx, tx, actor, auditActionPayerUnblock, string(scope), scopeID, before, nil)
+	})
+}
+
+// checkPayerBlockScope проверяет, что мерчант или команда списка существует
+func checkPayerBlockScope(ctx context.Context, tx pgx.Tx, scope domain.PayerBlockScope, scopeID string) error {
+	table, ok := payerBlockScopeTables[scope]
+	if !ok {
+		return domain.ErrorUnknownPayerBlockScope
+	}
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM ` + pgx.Identifier{table}.Sanitize() + ` WHERE id = $1)`
+	if err := tx.QueryRow(ctx, query, scopeID).Scan(&exists); err != nil {
+		return errors.Wrap(err, "check payer block scope")
+	}
+	if !exists {
+		return domain.ErrorPayerBlockScopeNotFound
+	}
+	return nil
+}
+
+// selectPayerBlock читает запись списка с блокировкой строки; nil, если плательщика в списке нет
+func selectPayerBlock(
+	ctx context.Context,
+	tx pgx.Tx,
+	scope domain.PayerBlockScope,
+	scopeID string,
+	payerID string,
+) (*auditPayerBlock, error) {
+	const query = `
+		SELECT reason FROM "PayerBlock"
+		WHERE scope = $1 AND scope_id = $2 AND payer_id = $3
+		FOR UPDATE`
+
+	block := &auditPayerBlock{PayerID: payerID}
+	if err := tx.QueryRow(ctx, query, scope, scopeID, payerID).Scan(&block.Reason); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "select payer block")
+	}
+	return block, nil
+}
@@ -1,13 +1,40 @@
 package pg
 
 import (
+	"context"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"mateo/internal/domain"
+	"time"
 )
 
 type Store struct {
-	conn *pgxpool.Pool
+	conn  *pgxpool.Pool
+	clock domain.Clock
+	ids   domain.IDGenerator
+
+	// location часовой пояс бизнес-дня, по которому сбрасываются дневные лимиты
+	location *time.Location
+	// maxRollingWindow самое длинное скользящее окно лимитов, которое учитывается при выборке
+	maxRollingWindow time.Duration
+}
+
+func NewStore(
+	conn *pgxpool.Pool,
+	clock domain.Clock,
+	ids domain.IDGenerator,
+	location *time.Location,
+	maxRollingWindow time.Duration,
+) *Store {
+	return &Store{
+		conn:             conn,
+		clock:            clock,
+		ids:              ids,
+		location:         location,
+		maxRollingWindow: maxRollingWindow,
+	}
 }
 
-func NewStore(conn *pgxpool.Pool) *Store {
-	return &Store{conn: conn}
+// Ping проверяет, что пул может выполнить запрос
+func (s *Store) Ping(ctx context.Context) error {
+	return s.conn.Ping(ctx)
 }
@@ -0,0 +1,154 @@
+package pg_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+	"mateo/internal/fake"
+	"mateo/internal/store/memory"
+	"mateo/internal/store/storetest"
+)
+
+// TestStore запускается против базы из TEST_DATABASE_URL
+func TestStore(t *testing.T) {
+	storetest.Run(t, storetest.PG(storetest.PGConn(t)))
+}
+
+// TestCandidateQueryMatchesMemory сверяет кандидатов из SQL с отбором memory.Store на одном пуле
+func TestCandidateQueryMatchesMemory(t *testing.T) {
+	newPG := storetest.PG(storetest.PGConn(t))
+	pgHarness := newPG(t, fake.NewClock(storetest.Start))
+	memoryHarness := storetest.Memory(t, fake.NewClock(storetest.Start))
+	seedCandidates(memoryHarness.Seed)
+	seedCandidates(pgHarness.Seed)
+
+	cases := []struct {
+		name     string
+		amount   int64
+		flexible bool
+		banks    domain.BankPreference
+		payerID  string
+	}{
+		{name: "exact", amount: 200},
+		{name: "exact above a requisite minimum", amount: 400},
+		{name: "flexible", amount: 203, flexible: true},
+		{name: "strict bank", amount: 400, banks: domain.BankPreference{Preferred: []string{"bank-1"}, Strict: true}},
+		{name: "preferred bank", amount: 400, banks: domain.BankPreference{Preferred: []string{"bank-1"}}},
+		{name: "excluded bank", amount: 400, banks: domain.BankPreference{Excluded: []string{"bank-1"}}},
+		{name: "flexible excluded bank", amount: 203, flexible: true, banks: domain.BankPreference{Excluded: []string{"bank-2"}}},
+		{name: "payer blocked by team", amount: 400, payerID: "payer-1"},
+		{name: "other payer", amount: 400, payerID: "payer-2"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := selectCandidates(t, pgHarness.Store, c.amount, c.flexible, c.banks, c.payerID)
+			want := selectCandidates(t, memoryHarness.Store, c.amount, c.flexible, c.banks, c.payerID)
+			if !slices.Equal(got, want) {
+				t.Fatalf("postgres returned %v, memory %v", got, want)
+			}
+		})
+	}
+}
+
+// seedCandidates два аккаунта разных команд и банков; команда team-2 заблокировала payer-1
+func seedCandidates(seed storetest.Seeder) {
+	seed.PutMerchant(domain.Merchant{ID: "merchant-1"})
+	seed.PutBank("bank-1", "Bank 1")
+	seed.PutBank("bank-2", "Bank 2")
+	seed.SetExchangeRate(decimal.NewFromInt(90))
+
+	requisites := []struct {
+		id, account, team, bank string
+		minAmount               int64
+	}{
+		{"requisite-1", "account-1", "team-1", "bank-1", 100},
+		{"requisite-2", "account-2", "team-2", "bank-2", 100},
+		{"requisite-3", "account-2", "team-2", "bank-1", 300},
+	}
+	for _, r := range requisites {
+		seed.PutTeam(domain.Team{ID: r.team, Name: r.team})
+		if r.id != "requisite-3" {
+			seed.PutWallet(memory.Wallet{ID: "wallet-" + r.account, PayInBalance: decimal.NewFromInt(100000)})
+			seed.PutTraderAccount(memory.TraderAccount{
+				ID:                   r.account,
+				UserID:               "user-" + r.account,
+				WalletID:             "wallet-" + r.account,
+				TeamID:               r.team,
+				IsCanWork:            true,
+				MinInvoiceAmountCard: decimal.NewFromInt(100),
+				IsWorkOnCardPayIn:    true,
+				MaxInvoiceAmount:     decimal.NewFromInt(10000),
+			})
+			seed.LinkMerchant("merchant-1", r.account)
+		}
+		seed.PutTerminal(memory.Terminal{
+			ID:               "terminal-" + r.id,
+			TraiderAccountID: r.account,
+			IsCanWork:        true,
+			MinInvoiceAmount: decimal.NewFromInt(100),
+			MaxInvoiceAmount: decimal.NewFromInt(10000),
+		})
+		seed.PutRequisite(memory.Requisite{
+			ID:               r.id,
+			TerminalID:       "terminal-" + r.id,
+			BankID:           r.bank,
+			Type:             domain.RequisiteTypeCard,
+			CardNumber:       "2200000000000001",
+			IsCanWork:        true,
+			MinInvoiceAmount: decimal.NewFromInt(r.minAmount),
+			MaxInvoiceAmount: decimal.NewFromInt(10000),
+		})
+	}
+
+	seed.PutPayerBlock(domain.PayerBlock{
+		Scope:     domain.PayerBlockScopeTeam,
+		ScopeID:   "team-2",
+		PayerID:   "payer-1",
+		Reason:    "fraud",
+		CreatedBy: "test",
+		CreatedAt: storetest.Start,
+	})
+}
+
+// selectCandidates возвращает отсортированные «ID:сумма» кандидатов
+func selectCandidates(
+	t *testing.T,
+	store storetest.Store,
+	amount int64,
+	flexible bool,
+	banks domain.BankPreference,
+	payerID string,
+) []string {
+	t.Helper()
+
+	var (
+		requisites []*domain.Requisite
+		err        error
+	)
+	if flexible {
+		requisites, err = store.SelectAvailableRequisitesFlexible(
+			context.Background(), "merchant-1",
+			decimal.NewFromInt(amount), decimal.NewFromInt(amount+20), decimal.NewFromInt(5),
+			domain.RequisiteTypeCard, banks, payerID,
+		)
+	} else {
+		requisites, err = store.SelectAvailableRequisites(
+			context.Background(), "merchant-1", decimal.NewFromInt(amount), domain.RequisiteTypeCard, banks, payerID,
+		)
+	}
+	if err != nil {
+		t.Fatalf("select candidates: %v", err)
+	}
+
+	got := make([]string, 0, len(requisites))
+	for _, r := range requisites {
+		got = append(got, fmt.Sprintf("%s:%s", r.ID, r.FlexibleSelectedAmount))
+	}
+	slices.Sort(got)
+	return got
+}
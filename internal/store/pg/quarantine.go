@@ -0,0 +1,223 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"mateo/internal/domain"
+	"time"
+)
+
+// quarantineTarget таблица сущности на карантине, колонка Invoice со ссылкой на нее
+// и запрос команды-владельца
+type quarantineTarget struct {
+	table     string
+	column    string
+	teamQuery string
+}
+
+var quarantineTargets = map[domain.BlockEntity]quarantineTarget{
+	domain.BlockEntityRequisite: {
+		table:  "Requisite",
+		column: "requisite_id",
+		teamQuery: `
+			SELECT COALESCE(ta.team_id, '')
+			FROM "Requisite" r
+			JOIN "Terminal" t ON r.terminal_id = t.id
+			JOIN "TraiderAccount" ta ON t.traider_account_id = ta.id
+			WHERE r.id = $1`,
+	},
+	domain.BlockEntityTerminal: {
+		table:  "Terminal",
+		column: "terminal_id",
+		teamQuery: `
+			SELECT COALESCE(ta.team_id, '')
+			FROM "Terminal" t
+			JOIN "TraiderAccount" ta ON t.traider_account_id = ta.id
+			WHERE t.id = $1`,
+	},
+}
+
+// closedOutcomes Invoice, по которым считаются исходы: отмененные мерчантом не учитываются
+const closedOutcomes = `i.status IN ('SUCCESS', 'SUCCESS_HAND', 'SUCCESS_APPEAL', 'EXPIRED')`
+
+// quarantinePayload тело уведомления о карантине
+type quarantinePayload struct {
+	Entity   domain.BlockEntity `json:"entity"`
+	EntityID string             `json:"entityId"`
+	Reason   string             `json:"reason"`
+	Until    time.Time          `json:"until"`
+}
+
+// SelectRecentOutcomes возвращает статусы последних закрытых Invoice сущности от новых к старым.
+// Invoice, созданные до окончания прошлого карантина, не учитываются: иначе после карантина
+// сущность сразу попала бы на него снова.
+func (s *Store) SelectRecentOutcomes(
+	ctx context.Context,
+	entity domain.BlockEntity,
+	entityID string,
+	limit int,
+) ([]domain.InvoiceStatus, error) {
+	target, ok := quarantineTargets[entity]
+	if !ok {
+		return nil, domain.ErrorUnknownBlockEntity
+	}
+
+	query := `
+		SELECT i.status
+		FROM "InvoiceIn" i
+		JOIN ` + pgx.Identifier{target.table}.Sanitize() + ` x ON x.id = i.` + column(target.column).sql() + `
+		WHERE x.id = $1
+			AND ` + closedOutcomes + `
+			AND (x.quarantined_until IS NULL OR i.created_at >= x.quarantined_until)
+		ORDER BY i.created_at DESC
+		LIMIT $2`
+
+	rows, err := s.conn.Query(ctx, query, entityID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select recent outcomes")
+	}
+	defer rows.Close()
+
+	var statuses []domain.InvoiceStatus
+	for rows.Next() {
+		var status domain.InvoiceStatus
+		if err := rows.Scan(&status); err != nil {
+			return nil, errors.Wrap(err, "failed to scan outcome")
+		}
+		statuses = append(statuses, status)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to select recent outcomes")
+	}
+
+	return statuses, nil
+}
+
+// SelectOutcomeStats считает закрытые и оплаченные Invoice сущности, созданные после since
+func (s *Store) SelectOutcomeStats(
+	ctx context.Context,
+	entity domain.BlockEntity,
+	entityID string,
+	since time.Time,
+) (domain.OutcomeStats, error) {
+	target, ok := quarantineTargets[entity]
+	if !ok {
+		return domain.OutcomeStats{}, domain.ErrorUnknownBlockEntity
+	}
+
+	query := `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE i.status <> 'EXPIRED')
+		FROM "InvoiceIn" i
+		JOIN ` + pgx.Identifier{target.table}.Sanitize() + ` x ON x.id = i.` + column(target.column).sql() + `
+		WHERE x.id = $1
+			AND ` + closedOutcomes + `
+			AND i.created_at >= $2
+			AND (x.quarantined_until IS NULL OR i.created_at >= x.quarantined_until)`
+
+	var stats domain.OutcomeStats
+	if err := s.conn.QueryRow(ctx, query, entityID, since).Scan(&stats.Total, &stats.Success); err != nil {
+		return domain.OutcomeStats{}, errors.Wrap(err, "failed to select outcome stats")
+	}
+	return stats, nil
+}
+
+// QuarantineEntity ставит сущность на карантин, если она еще не на нем, и в той же транзакции
+// кладет уведомление команде в outbox "TeamNotification"
+func (s *Store) QuarantineEntity(ctx context.Context, quarantine domain.Quarantine) (bool, error) {
+	target, ok := quarantineTargets[quarantine.Entity]
+	if !ok {
+		return false, domain.ErrorUnknownBlockEntity
+	}
+
+	payload, err := json.Marshal(quarantinePayload{
+		Entity:   quarantine.Entity,
+		EntityID: quarantine.EntityID,
+		Reason:   quarantine.Reason,
+		Until:    quarantine.Until,
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "marshal quarantine payload")
+	}
+
+	quarantined := false
+	err = pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		now := s.clock.Now()
+
+		query := `
+			UPDATE ` + pgx.Identifier{target.table}.Sanitize() + `
+			SET quarantined_until = $2, quarantine_reason = $3
+			WHERE id = $1 AND (quarantined_until IS NULL OR quarantined_until <= $4)`
+
+		tag, err := tx.Exec(ctx, query, quarantine.EntityID, quarantine.Until, quarantine.Reason, now)
+		if err != nil {
+			return errors.Wrap(err, "update quarantine")
+		}
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+
+		var teamID string
+		if err := tx.QueryRow(ctx, target.teamQuery, quarantine.EntityID).Scan(&teamID); err != nil {
+			return errors.Wrap(err, "select team")
+		}
+
+		const insertNotificationQuery = `
+			INSERT INTO "TeamNotification" (id, team_id, kind, payload, created_at)
+			VALUES ($1, NULLIF($2, ''), $3, $4, $5)`
+
+		_, err = tx.Exec(ctx, insertNotificationQuery, s.ids.NewID(), teamID, domain.TeamNotificationKindQuarantine, payload, now)
+		if err != nil {
+			return errors.Wrap(err, "insert team notification")
+		}
+
+		quarantined = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return quarantined, nil
+}
+
+// SelectPendingNotifications возвращает неотправленные уведомления команд в порядке создания
+func (s *Store) SelectPendingNotifications(ctx context.Context, limit int) ([]*domain.TeamNotification, error) {
+	const query = `
+		SELECT id, COALESCE(team_id, ''), kind, payload, created_at
+		FROM "TeamNotification"
+		WHERE sent_at IS NULL
+		ORDER BY created_at
+		LIMIT $1`
+
+	rows, err := s.conn.Query(ctx, query, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select pending notifications")
+	}
+	defer rows.Close()
+
+	var notifications []*domain.TeamNotification
+	for rows.Next() {
+		n := &domain.TeamNotification{}
+		if err := rows.Scan(&n.ID, &n.TeamID, &n.Kind, &n.Payload, &n.CreatedAt); err != nil {
+			return nil, errors.Wrap(err, "failed to scan notification")
+		}
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to select pending notifications")
+	}
+
+	return notifications, nil
+}
+
+func (s *Store) MarkNotificationSent(ctx context.Context, notificationID string) error {
+	const query = `UPDATE "TeamNotification" SET sent_at = $2 WHERE id = $1`
+
+	if _, err := s.conn.Exec(ctx, query, notificationID, s.clock.Now()); err != nil {
+		return errors.Wrap(err, "failed to mark notification sent")
+	}
+	return nil
+}
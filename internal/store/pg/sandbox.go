@@ -0,0 +1,369 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+	"time"
+)
+
+// SandboxPool фиктивный пул реквизитов песочницы. Пул одинаков на всех репликах,
+// поэтому не хранится в базе; занятость реквизитов определяется по "SandboxInvoice".
+type SandboxPool interface {
+	SelectAvailableRequisites(
+		ctx context.Context,
+		merchantID string,
+		amount decimal.Decimal,
+		requisiteType domain.RequisiteType,
+		banks domain.BankPreference,
+		payerID string,
+	) ([]*domain.Requisite, error)
+
+	SelectAvailableRequisitesFlexible(
+		ctx context.Context,
+		merchantID string,
+		flexibleAmountMin decimal.Decimal,
+		flexibleAmountMax decimal.Decimal,
+		flexibleAmountStep decimal.Decimal,
+		requisiteType domain.RequisiteType,
+		banks domain.BankPreference,
+		payerID string,
+	) ([]*domain.Requisite, error)
+
+	GetBoostedTeamIds(ctx context.Context) ([]string, error)
+	GetExchangeRate(ctx context.Context) (decimal.Decimal, error)
+}
+
+// SandboxStore хранилище песочницы: реквизиты берутся из фиктивного пула, Invoice
+// сохраняются в "SandboxInvoice", поэтому имитация оплаты доступна на любой реплике.
+// Hold и счетчики загрузки для Invoice песочницы не ведутся.
+type SandboxStore struct {
+	pool  SandboxPool
+	conn  *pgxpool.Pool
+	clock domain.Clock
+	ids   domain.IDGenerator
+}
+
+func NewSandboxStore(conn *pgxpool.Pool, pool SandboxPool, clock domain.Clock, ids domain.IDGenerator) *SandboxStore {
+	return &SandboxStore{
+		pool:  pool,
+		conn:  conn,
+		clock: clock,
+		ids:   ids,
+	}
+}
+
+// sandboxInvoiceColumns колонки "SandboxInvoice" в порядке scanSandboxInvoice
+const sandboxInvoiceColumns = `
+	id,
+	merchant_id,
+	amount,
+	status,
+	type,
+	terminal_id,
+	user_id,
+	bank_id,
+	traider_account_id,
+	requisite_id,
+	callback_url,
+	callback_key,
+	internal_request_id,
+	time_expires,
+	exchange,
+	created_at,
+	COALESCE(payer_id, '')`
+
+func scanSandboxInvoice(row pgx.Row) (*domain.Invoice, error) {
+	invoice := &domain.Invoice{}
+	err := row.Scan(
+		&invoice.ID,
+		&invoice.MerchantID,
+		&invoice.Amount,
+		&invoice.Status,
+		&invoice.Type,
+		&invoice.TerminalID,
+		&invoice.UserID,
+		&invoice.BankID,
+		&invoice.TraiderAccountID,
+		&invoice.RequisiteID,
+		&invoice.CallbackURL,
+		&invoice.CallbackKey,
+		&invoice.InternalRequestID,
+		&invoice.TimeExpires,
+		&invoice.Exchange,
+		&invoice.CreatedAt,
+		&invoice.PayerID,
+	)
+	return invoice, err
+}
+
+// SelectAvailableRequisites отбирает реквизиты пула без активного Invoice на ту же сумму
+func (s *SandboxStore) SelectAvailableRequisites(
+	ctx context.Context,
+	merchantID string,
+	amount decimal.Decimal,
+	requisiteType domain.RequisiteType,
+	banks domain.BankPreference,
+	payerID string,
+) ([]*domain.Requisite, error) {
+	requisites, err := s.pool.SelectAvailableRequisites(ctx, merchantID, amount, requisiteType, banks, payerID)
+	if err != nil {
+		return nil, err
+	}
+	return s.excludeBusy(ctx, requisites, func(*domain.Requisite) decimal.Decimal { return amount })
+}
+
+// SelectAvailableRequisitesFlexible отбирает реквизиты пула без активного Invoice на выбранную сумму
+func (s *SandboxStore) SelectAvailableRequisitesFlexible(
+	ctx context.Context,
+	merchantID string,
+	flexibleAmountMin decimal.Decimal,
+	flexibleAmountMax decimal.Decimal,
+	flexibleAmountStep decimal.Decimal,
+	requisiteType domain.RequisiteType,
+	banks domain.BankPreference,
+	payerID string,
+) ([]*domain.Requisite, error) {
+	requisites, err := s.pool.SelectAvailableRequisitesFlexible(
+		ctx, merchantID, flexibleAmountMin, flexibleAmountMax, flexibleAmountStep, requisiteType, banks, payerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return s.excludeBusy(ctx, requisites, func(r *domain.Requisite) decimal.Decimal { return r.FlexibleSelectedAmount })
+}
+
+// excludeBusy убирает реквизиты, на которых уже есть активный Invoice песочницы на ту же сумму
+func (s *SandboxStore) excludeBusy(
+	ctx context.Context,
+	requisites []*domain.Requisite,
+	amountOf func(*domain.Requisite) decimal.Decimal,
+) ([]*domain.Requisite, error) {
+	if len(requisites) == 0 {
+		return requisites, nil
+	}
+
+	const query = `
+		SELECT requisite_id, amount
+		FROM "SandboxInvoice"
+		WHERE status = $1`
+
+	rows, err := s.conn.Query(ctx, query, domain.InvoiceStatusCreated)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to select active sandbox invoices")
+		return nil, errors.Wrap(err, "failed to select active sandbox invoices")
+	}
+	defer rows.Close()
+
+	busy := make(map[string][]decimal.Decimal)
+	for rows.Next() {
+		var (
+			requisiteID string
+			amount      decimal.Decimal
+		)
+		if err := rows.Scan(&requisiteID, &amount); err != nil {
+			return nil, errors.Wrap(err, "failed to scan active sandbox invoice")
+		}
+		busy[requisiteID] = append(busy[requisiteID], amount)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to select active sandbox invoices")
+	}
+
+	available := requisites[:0]
+	for _, requisite := range requisites {
+		free := true
+		for _, amount := range busy[requisite.ID] {
+			if amount.Equal(amountOf(requisite)) {
+				free = false
+				break
+			}
+		}
+		if free {
+			available = append(available, requisite)
+		}
+	}
+	return available, nil
+}
+
+func (s *SandboxStore) GetBoostedTeamIds(ctx context.Context) ([]string, error) {
+	return s.pool.GetBoostedTeamIds(ctx)
+}
+
+func (s *SandboxStore) GetExchangeRate(ctx context.Context) (decimal.Decimal, error) {
+	return s.pool.GetExchangeRate(ctx)
+}
+
+// CreateInvoice сохраняет Invoice песочницы и возвращает его ID
+func (s *SandboxStore) CreateInvoice(ctx context.Context, invoice *domain.Invoice) (string, error) {
+	invoice.ID = s.ids.NewID()
+	invoice.CreatedAt = s.clock.Now()
+
+	const query = `
+		INSERT INTO "SandboxInvoice" (
+			id,
+			merchant_id,
+			amount,
+			status,
+			type,
+			terminal_id,
+			user_id,
+			bank_id,
+			traider_account_id,
+			requisite_id,
+			callback_url,
+			callback_key,
+			internal_request_id,
+			time_expires,
+			exchange,
+			created_at,
+			payer_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NULLIF($17, ''))`
+
+	_, err := s.conn.Exec(ctx, query,
+		invoice.ID,
+		invoice.MerchantID,
+		invoice.Amount,
+		invoice.Status,
+		invoice.Type,
+		invoice.TerminalID,
+		invoice.UserID,
+		invoice.BankID,
+		invoice.TraiderAccountID,
+		invoice.RequisiteID,
+		invoice.CallbackURL,
+		invoice.CallbackKey,
+		invoice.InternalRequestID,
+		invoice.TimeExpires,
+		invoice.Exchange,
+		invoice.CreatedAt,
+		invoice.PayerID,
+	)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).
+			Str("merchant_id", invoice.MerchantID).
+			Str("internal_request_id", invoice.InternalRequestID).
+			Msg("failed to create sandbox invoice")
+		return "", domain.ErrorFailedCreateInvoice
+	}
+
+	return invoice.ID, nil
+}
+
+// GetInvoice возвращает Invoice песочницы
+func (s *SandboxStore) GetInvoice(ctx context.Context, invoiceID string) (*domain.Invoice, error) {
+	query := `SELECT ` + sandboxInvoiceColumns + ` FROM "SandboxInvoice" WHERE id = $1`
+
+	invoice, err := scanSandboxInvoice(s.conn.QueryRow(ctx, query, invoiceID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrorInvoiceNotFound
+		}
+		log.Ctx(ctx).Error().Err(err).
+			Str("invoice_id", invoiceID).
+			Msg("failed to get sandbox invoice")
+		return nil, errors.Wrap(err, "failed to get sandbox invoice")
+	}
+
+	return invoice, nil
+}
+
+// FinalizeInvoice переводит активный Invoice песочницы в финальный статус
+func (s *SandboxStore) FinalizeInvoice(
+	ctx context.Context,
+	invoiceID string,
+	status domain.InvoiceStatus,
+) (*domain.Invoice, error) {
+	if !status.IsFinal() {
+		return nil, domain.ErrorInvalidStatus
+	}
+	return s.updateStatus(ctx, invoiceID, domain.InvoiceStatusCreated, status)
+}
+
+// AppealInvoice признает оплаченным по апелляции уже истекший Invoice песочницы
+func (s *SandboxStore) AppealInvoice(ctx context.Context, invoiceID string) (*domain.Invoice, error) {
+	return s.updateStatus(ctx, invoiceID, domain.InvoiceStatusExpired, domain.InvoiceStatusSuccessAppeal)
+}
+
+// updateStatus меняет статус Invoice, только если он все еще равен from
+func (s *SandboxStore) updateStatus(
+	ctx context.Context,
+	invoiceID string,
+	from domain.InvoiceStatus,
+	to domain.InvoiceStatus,
+) (*domain.Invoice, error) {
+	query := `
+		UPDATE "SandboxInvoice"
+		SET status = $3
+		WHERE id = $1 AND status = $2
+		RETURNING ` + sandboxInvoiceColumns
+
+	invoice, err := scanSandboxInvoice(s.conn.QueryRow(ctx, query, invoiceID, from, to))
+	if err == nil {
+		return invoice, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		log.Ctx(ctx).Error().Err(err).
+			Str("invoice_id", invoiceID).
+			Msg("failed to update sandbox invoice status")
+		return nil, domain.ErrorFailedUpdateInvoice
+	}
+
+	if _, err := s.GetInvoice(ctx, invoiceID); err != nil {
+		if errors.Is(err, domain.ErrorInvoiceNotFound) {
+			return nil, err
+		}
+		return nil, domain.ErrorFailedUpdateInvoice
+	}
+	return nil, domain.ErrorInvoiceNotActive
+}
+
+// SelectExpiredInvoiceIDs возвращает ID активных Invoice песочницы, у которых истекло время оплаты
+func (s *SandboxStore) SelectExpiredInvoiceIDs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	const query = `
+		SELECT id
+		FROM "SandboxInvoice"
+		WHERE status = $1 AND time_expires <= $2
+		ORDER BY time_expires
+		LIMIT $3`
+
+	rows, err := s.conn.Query(ctx, query, domain.InvoiceStatusCreated, now, limit)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to select expired sandbox invoices")
+		return nil, errors.Wrap(err, "failed to select expired sandbox invoices")
+	}
+	defer rows.Close()
+
+	var invoiceIDs []string
+	for rows.Next() {
+		var invoiceID string
+		if err := rows.Scan(&invoiceID); err != nil {
+			return nil, errors.Wrap(err, "failed to scan expired sandbox invoice id")
+		}
+		invoiceIDs = append(invoiceIDs, invoiceID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to select expired sandbox invoices")
+	}
+
+	return invoiceIDs, nil
+}
+
+// PruneInvoices удаляет закрытые Invoice песочницы, созданные раньше before, и возвращает их число
+func (s *SandboxStore) PruneInvoices(ctx context.Context, before time.Time) int {
+	tag, err := s.conn.Exec(ctx,
+		`DELETE FROM "SandboxInvoice" WHERE status <> $1 AND created_at < $2`,
+		domain.InvoiceStatusCreated, before,
+	)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to prune sandbox invoices")
+		return 0
+	}
+	return int(tag.RowsAffected())
+}
@@ -0,0 +1,111 @@
+package pg_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+	"mateo/internal/fake"
+	"mateo/internal/store/memory"
+	"mateo/internal/store/pg"
+	"mateo/internal/store/storetest"
+)
+
+// TestSandboxStore две реплики с общей базой: Invoice, созданный одной, закрывается другой
+func TestSandboxStore(t *testing.T) {
+	conn := storetest.PGConn(t)
+	ctx := context.Background()
+	if _, err := conn.Exec(ctx, `TRUNCATE "SandboxInvoice"`); err != nil {
+		t.Fatalf("truncate sandbox invoices: %v", err)
+	}
+
+	clock := fake.NewClock(storetest.Start)
+	newReplica := func(name string) *pg.SandboxStore {
+		ids := fake.NewIDGenerator(name + "-")
+		return pg.NewSandboxStore(conn, memory.NewSandboxPool(clock, ids), clock, ids)
+	}
+	first, second := newReplica("first"), newReplica("second")
+
+	amount := decimal.NewFromInt(500)
+	requisites, err := first.SelectAvailableRequisites(ctx, "merchant-1", amount, domain.RequisiteTypeCard, domain.BankPreference{}, "")
+	if err != nil {
+		t.Fatalf("SelectAvailableRequisites: %v", err)
+	}
+	if len(requisites) == 0 {
+		t.Fatal("sandbox pool has no card requisites")
+	}
+	total := len(requisites)
+
+	invoice := &domain.Invoice{
+		MerchantID:       "merchant-1",
+		Amount:           amount,
+		Status:           domain.InvoiceStatusCreated,
+		Type:             domain.RequisiteTypeCard,
+		TerminalID:       requisites[0].TerminalID,
+		UserID:           requisites[0].UserID,
+		BankID:           requisites[0].BankID,
+		TraiderAccountID: requisites[0].TraiderAccountID,
+		RequisiteID:      requisites[0].ID,
+		CallbackURL:      "https://example.com/callback",
+		CallbackKey:      "key",
+		TimeExpires:      clock.Now().Add(15 * time.Minute),
+		Exchange:         decimal.NewFromInt(100),
+	}
+	if _, err := first.CreateInvoice(ctx, invoice); err != nil {
+		t.Fatalf("CreateInvoice: %v", err)
+	}
+
+	// Реквизит с активным Invoice на ту же сумму занят и для другой реплики
+	requisites, err = second.SelectAvailableRequisites(ctx, "merchant-1", amount, domain.RequisiteTypeCard, domain.BankPreference{}, "")
+	if err != nil {
+		t.Fatalf("SelectAvailableRequisites: %v", err)
+	}
+	if len(requisites) != total-1 {
+		t.Fatalf("got %d requisites, want %d", len(requisites), total-1)
+	}
+
+	got, err := second.GetInvoice(ctx, invoice.ID)
+	if err != nil {
+		t.Fatalf("GetInvoice: %v", err)
+	}
+	if got.CallbackKey != "key" || !got.Amount.Equal(amount) || got.RequisiteID != invoice.RequisiteID {
+		t.Fatalf("unexpected invoice %+v", got)
+	}
+
+	clock.Advance(20 * time.Minute)
+	ids, err := second.SelectExpiredInvoiceIDs(ctx, clock.Now(), 10)
+	if err != nil {
+		t.Fatalf("SelectExpiredInvoiceIDs: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != invoice.ID {
+		t.Fatalf("got expired %v, want [%s]", ids, invoice.ID)
+	}
+
+	if _, err := second.FinalizeInvoice(ctx, invoice.ID, domain.InvoiceStatusExpired); err != nil {
+		t.Fatalf("FinalizeInvoice: %v", err)
+	}
+	if _, err := first.FinalizeInvoice(ctx, invoice.ID, domain.InvoiceStatusSuccess); !errors.Is(err, domain.ErrorInvoiceNotActive) {
+		t.Fatalf("got error %v, want %v", err, domain.ErrorInvoiceNotActive)
+	}
+
+	appealed, err := first.AppealInvoice(ctx, invoice.ID)
+	if err != nil {
+		t.Fatalf("AppealInvoice: %v", err)
+	}
+	if appealed.Status != domain.InvoiceStatusSuccessAppeal {
+		t.Fatalf("got status %s, want %s", appealed.Status, domain.InvoiceStatusSuccessAppeal)
+	}
+	if _, err := first.AppealInvoice(ctx, "missing"); !errors.Is(err, domain.ErrorInvoiceNotFound) {
+		t.Fatalf("got error %v, want %v", err, domain.ErrorInvoiceNotFound)
+	}
+
+	if pruned := second.PruneInvoices(ctx, clock.Now()); pruned != 1 {
+		t.Fatalf("pruned %d invoices, want 1", pruned)
+	}
+	if _, err := first.GetInvoice(ctx, invoice.ID); !errors.Is(err, domain.ErrorInvoiceNotFound) {
+		t.Fatalf("got error %v, want %v", err, domain.ErrorInvoiceNotFound)
+	}
+}
@@ -14,7 +14,7 @@ func (s *Store) GetBoostedTeamIds(ctx context.Context) ([]string, error) {
 	`
 	rows, err := s.conn.Query(ctx, query)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to get boosted team ids")
+		log.Ctx(ctx).Error().Err(err).Msg("failed to get boosted team ids")
 		return nil, errors.Wrap(err, "failed to get boosted team ids")
 	}
 	defer rows.Close()
@@ -23,7 +23,7 @@ func (s *Store) GetBoostedTeamIds(ctx context.Context) ([]string, error) {
 	for rows.Next() {
 		var teamId string
 		if err := rows.Scan(&teamId); err != nil {
-			log.Error().Err(err).Msg("failed to get boosted team ids")
+			log.Ctx(ctx).Error().Err(err).Msg("failed to get boosted team ids")
 			return nil, errors.Wrap(err, "failed to get boosted team ids")
 		}
 		teamIds = append(teamIds, teamId)
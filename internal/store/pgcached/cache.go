@@ -2,104 +2,72 @@ package pgcached
 
 import (
 	"context"
-	"encoding/json"
-	"github.com/shopspring/decimal"
-	"time"
 
-	"github.com/go-redis/redis/v8"
-	"github.com/pkg/errors"
-	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+	"mateo/internal/cache"
+	"mateo/internal/domain"
 	"mateo/internal/store/pg"
 )
 
-type exchangeRateCache struct {
-	Rate      float64   `json:"rate"`
-	UpdatedAt time.Time `json:"updatedAt"`
-}
-
+// Имена кешей, они же префиксы ключей в Redis. Записи сбрасываются по уведомлениям Postgres
+// (см. RunInvalidation); TTL ограничивает устаревание, если уведомление потеряно.
 const (
-	boostedTeamCacheKey  = "boosted_team_ids"
-	exchangeRateCacheKey = "exchangeRate"
-	boostedTeamCacheTTL  = time.Minute * 5
-	exchangeRateCacheTTL = time.Minute * 5
+	boostedTeamCacheName  = "cache:boosted_team_ids"
+	exchangeRateCacheName = "cache:exchange_rate"
+	merchantCacheName     = "cache:merchant"
 )
 
 type CachedStore struct {
 	*pg.Store
-	redisClient *redis.Client
+	boostedTeams  *cache.Cache[[]string]
+	exchangeRates *cache.Cache[decimal.Decimal]
+	merchants     *cache.Cache[*domain.Merchant]
 }
 
-func NewCachedStore(store *pg.Store, redisClient *redis.Client) *CachedStore {
+func NewCachedStore(
+	store *pg.Store,
+	remote cache.Remote,
+	clock domain.Clock,
+	rand domain.Rand,
+	options cache.Options,
+) *CachedStore {
 	return &CachedStore{
-		Store:       store,
-		redisClient: redisClient,
+		Store:         store,
+		boostedTeams:  cache.New[[]string](boostedTeamCacheName, remote, clock, rand, options),
+		exchangeRates: cache.New[decimal.Decimal](exchangeRateCacheName, remote, clock, rand, options),
+		merchants: cache.New[*domain.Merchant](merchantCacheName, remote, clock, rand, options).
+			WithEvictOn(domain.ErrorMerchantNotFound),
 	}
 }
 
 func (c *CachedStore) GetBoostedTeamIds(ctx context.Context) ([]string, error) {
-	// Try to get from cache
-	cached, err := c.redisClient.Get(ctx, boostedTeamCacheKey).Result()
-	if err == nil {
-		var teamIds []string
-		if err := json.Unmarshal([]byte(cached), &teamIds); err == nil {
-			return teamIds, nil
-		}
-		log.Error().Err(err).Msg("failed to unmarshal team ids")
-	}
-
-	// If not in cache or error, get from database
-	teamIds, err := c.Store.GetBoostedTeamIds(ctx)
-	if err != nil {
-		return nil, errors.Wrap(err, "get from storage")
-	}
-
-	// Update cache
-	teamIdsJSON, err := json.Marshal(teamIds)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to marshal team ids")
-		return teamIds, nil
-	}
-
-	if err := c.redisClient.Set(ctx, boostedTeamCacheKey, teamIdsJSON, boostedTeamCacheTTL).Err(); err != nil {
-		log.Error().Err(err).Msg("failed to set cache in redis")
-	}
-
-	return teamIds, nil
+	return c.boostedTeams.Get(ctx, "", c.Store.GetBoostedTeamIds)
 }
 
 func (c *CachedStore) GetExchangeRate(ctx context.Context) (decimal.Decimal, error) {
-	// Try to get from cache
-	cached, err := c.redisClient.Get(ctx, exchangeRateCacheKey).Result()
-	if err == nil {
-		var cachedRate exchangeRateCache
-		if err := json.Unmarshal([]byte(cached), &cachedRate); err == nil {
-			return decimal.NewFromFloat(cachedRate.Rate), nil
-		}
-		log.Error().Err(err).Msg("failed to unmarshal exchange rate from cache")
-	}
-
-	// If not in cache or error, get from database
-	rate, err := c.Store.GetExchangeRate(ctx)
-	if err != nil {
-		return decimal.Zero, errors.Wrap(err, "get exchange rate from storage")
-	}
-
-	// Update cache
-	rateFloat, _ := rate.Float64()
-	cacheData := exchangeRateCache{
-		Rate:      rateFloat,
-		UpdatedAt: time.Now(),
-	}
+	return c.exchangeRates.Get(ctx, "", c.Store.GetExchangeRate)
+}
 
-	cacheJSON, err := json.Marshal(cacheData)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to marshal exchange rate for cache")
-		return rate, nil
-	}
+// GetMerchantByMerchantID кеширует найденных мерчантов; удаленный из базы мерчант
+// вытесняется из кеша, а не отдается устаревшим
+func (c *CachedStore) GetMerchantByMerchantID(ctx context.Context, merchantID string) (*domain.Merchant, error) {
+	return c.merchants.Get(ctx, merchantID, func(ctx context.Context) (*domain.Merchant, error) {
+		return c.Store.GetMerchantByMerchantID(ctx, merchantID)
+	})
+}
 
-	if err := c.redisClient.Set(ctx, exchangeRateCacheKey, cacheJSON, exchangeRateCacheTTL).Err(); err != nil {
-		log.Error().Err(err).Msg("failed to set exchange rate cache in redis")
+// CacheStats счетчики обращений к кешам по именам без префикса
+func (c *CachedStore) CacheStats() map[string]cache.Stats {
+	return map[string]cache.Stats{
+		"boosted_team_ids": c.boostedTeams.Stats(),
+		"exchange_rate":    c.exchangeRates.Stats(),
+		"merchant":         c.merchants.Stats(),
 	}
+}
 
-	return rate, nil
+// SetCacheOptions меняет сроки всех кешей на ходу
+func (c *CachedStore) SetCacheOptions(options cache.Options) {
+	c.boostedTeams.SetOptions(options)
+	c.exchangeRates.SetOptions(options)
+	c.merchants.SetOptions(options)
 }
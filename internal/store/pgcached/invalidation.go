@@ -0,0 +1,36 @@
+package pgcached
+
+import (
+	"context"
+	"mateo/internal/store/pg"
+)
+
+// RunInvalidation сбрасывает записи кеша по уведомлениям Postgres об изменении "Merchant", "Team"
+// и "Settings" до отмены ctx. Уведомление получает каждая реплика и сбрасывает свой LRU;
+// повторное удаление общего ключа в Redis безвредно.
+func (c *CachedStore) RunInvalidation(ctx context.Context) {
+	c.Store.ListenCacheInvalidations(ctx, c.Flush, c.invalidate)
+}
+
+func (c *CachedStore) invalidate(ctx context.Context, invalidation pg.CacheInvalidation) {
+	switch invalidation.Table {
+	case "Merchant":
+		if invalidation.ID == "" {
+			c.merchants.InvalidateAll(ctx)
+			return
+		}
+		c.merchants.Invalidate(ctx, invalidation.ID)
+	case "Team":
+		c.boostedTeams.InvalidateAll(ctx)
+	case "Settings":
+		c.exchangeRates.InvalidateAll(ctx)
+	}
+}
+
+// Flush сбрасывает весь кеш: уведомления, пропущенные до подключения слушателя, и удаления,
+// не дошедшие до недоступного Redis, не восстановить
+func (c *CachedStore) Flush(ctx context.Context) {
+	c.boostedTeams.InvalidateAll(ctx)
+	c.exchangeRates.InvalidateAll(ctx)
+	c.merchants.InvalidateAll(ctx)
+}
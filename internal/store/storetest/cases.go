@@ -0,0 +1,645 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+	"mateo/internal/store/memory"
+)
+
+func testMerchant(t *testing.T, h Harness) {
+	seedPool(h, nil)
+	ctx := context.Background()
+
+	m, err := h.Store.GetMerchantByMerchantID(ctx, merchantID)
+	if err != nil {
+		t.Fatalf("GetMerchantByMerchantID: %v", err)
+	}
+	if m.ID != merchantID {
+		t.Fatalf("got merchant %q, want %q", m.ID, merchantID)
+	}
+
+	h.Seed.PutMerchant(domain.Merchant{
+		ID:                 merchantID,
+		Status:             domain.MerchantStatusSuspended,
+		InMaxCard:          decimal.NewFromInt(5000),
+		InDisabledSBP:      true,
+		DailyTurnoverLimit: decimal.NewFromInt(100000),
+		DailyInvoiceLimit:  50,
+	})
+	m, err = h.Store.GetMerchantByMerchantID(ctx, merchantID)
+	if err != nil {
+		t.Fatalf("GetMerchantByMerchantID: %v", err)
+	}
+	if !m.IsSuspended() || !m.InMaxCard.Equal(decimal.NewFromInt(5000)) || !m.InDisabledSBP || m.InDisabledCard ||
+		!m.DailyTurnoverLimit.Equal(decimal.NewFromInt(100000)) || m.DailyInvoiceLimit != 50 {
+		t.Fatalf("unexpected merchant %+v", m)
+	}
+
+	if _, err := h.Store.GetMerchantByMerchantID(ctx, "missing"); !errors.Is(err, domain.ErrorMerchantNotFound) {
+		t.Fatalf("got error %v, want %v", err, domain.ErrorMerchantNotFound)
+	}
+}
+
+func testMerchantTurnover(t *testing.T, h Harness) {
+	ctx := context.Background()
+	seedPool(h, nil)
+
+	// Вчерашний Invoice не входит в оборот текущего дня
+	createInvoice(t, h, 100)
+	h.Clock.Advance(24 * time.Hour)
+
+	for _, step := range []struct {
+		amount int64
+		status domain.InvoiceStatus
+	}{
+		{200, domain.InvoiceStatusCreated},
+		{300, domain.InvoiceStatusSuccess},
+		{400, domain.InvoiceStatusExpired},
+		{500, domain.InvoiceStatusCanceled},
+	} {
+		inv := createInvoice(t, h, step.amount)
+		if step.status != domain.InvoiceStatusCreated {
+			if _, err := h.Store.FinalizeInvoice(ctx, inv.ID, step.status); err != nil {
+				t.Fatalf("FinalizeInvoice: %v", err)
+			}
+		}
+		h.Clock.Advance(time.Minute)
+	}
+
+	turnover, err := h.Store.GetMerchantDailyTurnover(ctx, merchantID)
+	if err != nil {
+		t.Fatalf("GetMerchantDailyTurnover: %v", err)
+	}
+	if turnover.Count != 2 || !turnover.Sum.Equal(decimal.NewFromInt(500)) {
+		t.Fatalf("got turnover %d/%s, want 2/500", turnover.Count, turnover.Sum)
+	}
+
+	turnover, err = h.Store.GetMerchantDailyTurnover(ctx, "missing")
+	if err != nil {
+		t.Fatalf("GetMerchantDailyTurnover: %v", err)
+	}
+	if turnover.Count != 0 || !turnover.Sum.IsZero() {
+		t.Fatalf("got turnover %d/%s for unknown merchant, want 0/0", turnover.Count, turnover.Sum)
+	}
+}
+
+func testExchangeRate(t *testing.T, h Harness) {
+	seedPool(h, nil)
+
+	rate, err := h.Store.GetExchangeRate(context.Background())
+	if err != nil {
+		t.Fatalf("GetExchangeRate: %v", err)
+	}
+	if !rate.Equal(decimal.NewFromInt(90)) {
+		t.Fatalf("got rate %s, want 90", rate)
+	}
+
+	// Postgres ставит время изменения своими часами, поэтому сверяется только его наличие
+	updatedAt, err := h.Store.GetExchangeRateUpdatedAt(context.Background())
+	if err != nil {
+		t.Fatalf("GetExchangeRateUpdatedAt: %v", err)
+	}
+	if updatedAt.IsZero() {
+		t.Fatal("exchange rate update time is not set")
+	}
+}
+
+func testBoostedTeams(t *testing.T, h Harness) {
+	seedPool(h, nil)
+	h.Seed.PutTeam(domain.Team{ID: "team-boosted", Name: "Boosted", IsBoosted: true})
+
+	teamIds, err := h.Store.GetBoostedTeamIds(context.Background())
+	if err != nil {
+		t.Fatalf("GetBoostedTeamIds: %v", err)
+	}
+	expectIDs(t, teamIds, "team-boosted")
+}
+
+func testSelectExact(t *testing.T, h Harness) {
+	seedPool(h, nil)
+
+	requisites, err := h.Store.SelectAvailableRequisites(
+		context.Background(), merchantID, decimal.NewFromInt(500), domain.RequisiteTypeCard, domain.BankPreference{}, "",
+	)
+	if err != nil {
+		t.Fatalf("SelectAvailableRequisites: %v", err)
+	}
+	if len(requisites) != 1 {
+		t.Fatalf("got %d requisites, want 1", len(requisites))
+	}
+
+	r := requisites[0]
+	if r.ID != requisiteID || r.TerminalID != terminalID || r.TraiderAccountID != accountID ||
+		r.TeamID != teamID || r.UserID != "user-1" || r.BankName != "Bank" || r.RecipientName != "Ivan I." {
+		t.Fatalf("unexpected requisite %+v", r)
+	}
+
+	expectIDs(t, selectIDs(t, h, 50, ""))
+	expectIDs(t, selectIDs(t, h, 20000, ""))
+
+	requisites, err = h.Store.SelectAvailableRequisites(
+		context.Background(), merchantID, decimal.NewFromInt(500), domain.RequisiteTypeSBP, domain.BankPreference{}, "",
+	)
+	if err != nil {
+		t.Fatalf("SelectAvailableRequisites: %v", err)
+	}
+	if len(requisites) != 0 {
+		t.Fatalf("got %d SBP requisites, want 0", len(requisites))
+	}
+}
+
+func testSelectBank(t *testing.T, h Harness) {
+	seedPool(h, nil)
+
+	expectIDs(t, selectIDs(t, h, 500, bankID), requisiteID)
+	expectIDs(t, selectIDs(t, h, 500, "bank-other"))
+
+	// Без строгого режима предпочтение не фильтрует кандидатов
+	expectIDs(t, selectBankIDs(t, h, domain.BankPreference{Preferred: []string{"bank-other"}}), requisiteID)
+	expectIDs(t, selectBankIDs(t, h, domain.BankPreference{Excluded: []string{"bank-other"}}), requisiteID)
+	expectIDs(t, selectBankIDs(t, h, domain.BankPreference{Excluded: []string{bankID}}))
+	expectIDs(t, selectBankIDs(t, h, domain.BankPreference{
+		Preferred: []string{bankID},
+		Excluded:  []string{bankID},
+		Strict:    true,
+	}))
+}
+
+func testSameAmountExcluded(t *testing.T, h Harness) {
+	seedPool(h, nil)
+	createInvoice(t, h, 500)
+
+	expectIDs(t, selectIDs(t, h, 500, ""))
+	expectIDs(t, selectIDs(t, h, 600, ""), requisiteID)
+}
+
+func testRequisiteInterval(t *testing.T, h Harness) {
+	seedPool(h, func(_ *memory.TraderAccount, _ *memory.Terminal, r *memory.Requisite) {
+		r.InvoiceInterval = 5
+	})
+	createInvoice(t, h, 500)
+
+	h.Clock.Advance(4 * time.Minute)
+	expectIDs(t, selectIDs(t, h, 600, ""))
+
+	h.Clock.Advance(time.Minute)
+	expectIDs(t, selectIDs(t, h, 600, ""), requisiteID)
+}
+
+func testRequisiteDailyLimit(t *testing.T, h Harness) {
+	seedPool(h, func(_ *memory.TraderAccount, _ *memory.Terminal, r *memory.Requisite) {
+		r.DailyLimitInvoices = intPtr(2)
+	})
+	createInvoice(t, h, 500)
+	createInvoice(t, h, 600)
+
+	expectIDs(t, selectIDs(t, h, 700, ""))
+
+	// Лимит сбрасывается с началом следующего бизнес-дня
+	h.Clock.Set(Start.Truncate(24 * time.Hour).Add(24 * time.Hour))
+	expectIDs(t, selectIDs(t, h, 700, ""), requisiteID)
+}
+
+func testTerminalRollingMoneyLimit(t *testing.T, h Harness) {
+	seedPool(h, func(_ *memory.TraderAccount, terminal *memory.Terminal, _ *memory.Requisite) {
+		terminal.DailyLimitMoney = decimalPtr(1000)
+		terminal.DailyLimitMoneyWindowHours = intPtr(1)
+	})
+	createInvoice(t, h, 700)
+
+	expectIDs(t, selectIDs(t, h, 300, ""), requisiteID)
+	expectIDs(t, selectIDs(t, h, 400, ""))
+
+	h.Clock.Advance(75 * time.Minute)
+	expectIDs(t, selectIDs(t, h, 400, ""), requisiteID)
+}
+
+func testTimedBlocks(t *testing.T, h Harness) {
+	accountUntil := Start.Add(10 * time.Minute)
+	requisiteUntil := Start.Add(20 * time.Minute)
+	seedPool(h, func(account *memory.TraderAccount, terminal *memory.Terminal, r *memory.Requisite) {
+		account.IsBlocked = true
+		account.BlockedUntil = &accountUntil
+		terminal.IsBlocked = true
+		terminal.BlockedUntil = &accountUntil
+		r.IsBlocked = true
+		r.BlockedUntil = &requisiteUntil
+	})
+
+	expectIDs(t, selectIDs(t, h, 500, ""))
+
+	h.Clock.Set(accountUntil)
+	expectIDs(t, selectIDs(t, h, 500, ""))
+
+	// Блокировка снимается ровно в момент blocked_until
+	h.Clock.Set(requisiteUntil)
+	expectIDs(t, selectIDs(t, h, 500, ""), requisiteID)
+
+	// Блокировка без срока действует всегда
+	h.Seed.PutRequisite(memory.Requisite{
+		ID:               "requisite-2",
+		TerminalID:       terminalID,
+		BankID:           bankID,
+		Type:             domain.RequisiteTypeCard,
+		IsCanWork:        true,
+		IsBlocked:        true,
+		MaxInvoiceAmount: decimal.NewFromInt(10000),
+	})
+	h.Clock.Advance(365 * 24 * time.Hour)
+	expectIDs(t, selectIDs(t, h, 500, ""), requisiteID)
+}
+
+func testQuarantine(t *testing.T, h Harness) {
+	ctx := context.Background()
+	seedPool(h, nil)
+
+	until := Start.Add(30 * time.Minute)
+	q := domain.Quarantine{Entity: domain.BlockEntityRequisite, EntityID: requisiteID, Reason: "3 consecutive invoices expired", Until: until}
+
+	quarantined, err := h.Store.QuarantineEntity(ctx, q)
+	if err != nil || !quarantined {
+		t.Fatalf("QuarantineEntity: got %v, %v, want true", quarantined, err)
+	}
+	expectIDs(t, selectIDs(t, h, 500, ""))
+
+	// Повторный карантин не продлевает текущий и не шлет второе уведомление
+	if quarantined, err := h.Store.QuarantineEntity(ctx, q); err != nil || quarantined {
+		t.Fatalf("repeated QuarantineEntity: got %v, %v, want false", quarantined, err)
+	}
+
+	notifications, err := h.Store.SelectPendingNotifications(ctx, 10)
+	if err != nil {
+		t.Fatalf("SelectPendingNotifications: %v", err)
+	}
+	if len(notifications) != 1 || notifications[0].TeamID != teamID || notifications[0].Kind != domain.TeamNotificationKindQuarantine {
+		t.Fatalf("got notifications %+v, want one quarantine notification for %s", notifications, teamID)
+	}
+	if err := h.Store.MarkNotificationSent(ctx, notifications[0].ID); err != nil {
+		t.Fatalf("MarkNotificationSent: %v", err)
+	}
+	if notifications, _ := h.Store.SelectPendingNotifications(ctx, 10); len(notifications) != 0 {
+		t.Fatalf("got %d pending notifications after send, want 0", len(notifications))
+	}
+
+	h.Clock.Set(until)
+	expectIDs(t, selectIDs(t, h, 500, ""), requisiteID)
+
+	// Карантин терминала исключает все его реквизиты
+	q = domain.Quarantine{Entity: domain.BlockEntityTerminal, EntityID: terminalID, Reason: "low success rate", Until: until.Add(time.Hour)}
+	if quarantined, err := h.Store.QuarantineEntity(ctx, q); err != nil || !quarantined {
+		t.Fatalf("QuarantineEntity terminal: got %v, %v, want true", quarantined, err)
+	}
+	expectIDs(t, selectIDs(t, h, 500, ""))
+}
+
+func testQuarantineOutcomes(t *testing.T, h Harness) {
+	ctx := context.Background()
+	seedPool(h, nil)
+
+	for _, step := range []struct {
+		amount int64
+		status domain.InvoiceStatus
+	}{
+		{100, domain.InvoiceStatusExpired},
+		{200, domain.InvoiceStatusSuccess},
+		{300, domain.InvoiceStatusCanceled},
+		{400, domain.InvoiceStatusExpired},
+	} {
+		inv := createInvoice(t, h, step.amount)
+		if _, err := h.Store.FinalizeInvoice(ctx, inv.ID, step.status); err != nil {
+			t.Fatalf("FinalizeInvoice: %v", err)
+		}
+		h.Clock.Advance(time.Minute)
+	}
+
+	// Отмененные Invoice не считаются исходом
+	outcomes, err := h.Store.SelectRecentOutcomes(ctx, domain.BlockEntityRequisite, requisiteID, 2)
+	if err != nil {
+		t.Fatalf("SelectRecentOutcomes: %v", err)
+	}
+	want := []domain.InvoiceStatus{domain.InvoiceStatusExpired, domain.InvoiceStatusSuccess}
+	if len(outcomes) != len(want) || outcomes[0] != want[0] || outcomes[1] != want[1] {
+		t.Fatalf("got outcomes %v, want %v", outcomes, want)
+	}
+
+	stats, err := h.Store.SelectOutcomeStats(ctx, domain.BlockEntityTerminal, terminalID, Start)
+	if err != nil {
+		t.Fatalf("SelectOutcomeStats: %v", err)
+	}
+	if stats != (domain.OutcomeStats{Total: 3, Success: 1}) {
+		t.Fatalf("got stats %+v, want 3 total, 1 success", stats)
+	}
+
+	stats, _ = h.Store.SelectOutcomeStats(ctx, domain.BlockEntityTerminal, terminalID, Start.Add(2*time.Minute))
+	if stats != (domain.OutcomeStats{Total: 1}) {
+		t.Fatalf("got stats since window start %+v, want 1 total", stats)
+	}
+
+	// После карантина прежние исходы не учитываются
+	until := h.Clock.Now()
+	q := domain.Quarantine{Entity: domain.BlockEntityRequisite, EntityID: requisiteID, Reason: "expired", Until: until}
+	if _, err := h.Store.QuarantineEntity(ctx, q); err != nil {
+		t.Fatalf("QuarantineEntity: %v", err)
+	}
+	outcomes, _ = h.Store.SelectRecentOutcomes(ctx, domain.BlockEntityRequisite, requisiteID, 2)
+	if len(outcomes) != 0 {
+		t.Fatalf("got outcomes %v after quarantine, want none", outcomes)
+	}
+}
+
+func testSelectionStats(t *testing.T, h Harness) {
+	ctx := context.Background()
+	seedPool(h, nil)
+
+	// external: статус выставляет внешняя платежная система, минуя FinalizeInvoice
+	for _, step := range []struct {
+		amount   int64
+		status   domain.InvoiceStatus
+		external bool
+	}{
+		{100, domain.InvoiceStatusSuccess, false},
+		{200, domain.InvoiceStatusExpired, false},
+		{300, domain.InvoiceStatusCanceled, false},
+		{150, domain.InvoiceStatusSuccessHand, true},
+		{250, domain.InvoiceStatusExpired, true},
+		{400, domain.InvoiceStatusCreated, false},
+	} {
+		inv := createInvoice(t, h, step.amount)
+		switch {
+		case step.status == domain.InvoiceStatusCreated:
+		case step.external:
+			h.Seed.SetInvoiceStatus(inv.ID, step.status)
+		default:
+			if _, err := h.Store.FinalizeInvoice(ctx, inv.ID, step.status); err != nil {
+				t.Fatalf("FinalizeInvoice: %v", err)
+			}
+		}
+		h.Clock.Advance(time.Minute)
+	}
+
+	requisites, err := h.Store.SelectAvailableRequisites(ctx, merchantID, decimal.NewFromInt(500), domain.RequisiteTypeCard, domain.BankPreference{}, "")
+	if err != nil {
+		t.Fatalf("SelectAvailableRequisites: %v", err)
+	}
+	if len(requisites) != 1 {
+		t.Fatalf("got %d requisites, want 1", len(requisites))
+	}
+
+	stats := requisites[0].Stats
+	lastInvoiceTime := Start.Add(5 * time.Minute)
+	if stats.LastInvoiceTime == nil || !stats.LastInvoiceTime.Equal(lastInvoiceTime) {
+		t.Fatalf("got last invoice time %v, want %v", stats.LastInvoiceTime, lastInvoiceTime)
+	}
+	stats.LastInvoiceTime = nil
+
+	want := domain.RequisiteStats{
+		ActiveInvoices:   1,
+		TerminalClosed:   4,
+		TerminalSuccess:  2,
+		RequisiteClosed:  4,
+		RequisiteSuccess: 2,
+	}
+	if stats != want {
+		t.Fatalf("got stats %+v, want %+v", stats, want)
+	}
+}
+
+func testPayers(t *testing.T, h Harness) {
+	ctx := context.Background()
+	seedPool(h, nil)
+
+	expired := createPayerInvoice(t, h, 100, "payer-1")
+	if _, err := h.Store.FinalizeInvoice(ctx, expired.ID, domain.InvoiceStatusExpired); err != nil {
+		t.Fatalf("FinalizeInvoice: %v", err)
+	}
+	h.Clock.Advance(time.Minute)
+	createPayerInvoice(t, h, 200, "payer-1")
+	createPayerInvoice(t, h, 300, "payer-2")
+	h.Clock.Advance(time.Minute)
+
+	activity, err := h.Store.GetPayerActivity(ctx, merchantID, "payer-1", Start.Add(30*time.Second))
+	if err != nil {
+		t.Fatalf("GetPayerActivity: %v", err)
+	}
+	if want := (domain.PayerActivity{Created: 1, Active: 1}); activity != want {
+		t.Fatalf("got activity %+v, want %+v", activity, want)
+	}
+
+	for payerID, want := range map[string]int{"payer-1": 1, "payer-2": 0, "": 0} {
+		requisites, err := h.Store.SelectAvailableRequisites(
+			ctx, merchantID, decimal.NewFromInt(500), domain.RequisiteTypeCard, domain.BankPreference{}, payerID,
+		)
+		if err != nil {
+			t.Fatalf("SelectAvailableRequisites: %v", err)
+		}
+		if len(requisites) != 1 {
+			t.Fatalf("payer %q: got %d requisites, want 1", payerID, len(requisites))
+		}
+		if got := requisites[0].Stats.PayerExpired; got != want {
+			t.Fatalf("payer %q: got %d expired invoices, want %d", payerID, got, want)
+		}
+	}
+
+	h.Seed.PutPayerBlock(domain.PayerBlock{
+		Scope: domain.PayerBlockScopeTeam, ScopeID: teamID, PayerID: "payer-1",
+		Reason: "chargeback", CreatedBy: "ops", CreatedAt: Start,
+	})
+	h.Seed.PutPayerBlock(domain.PayerBlock{
+		Scope: domain.PayerBlockScopeMerchant, ScopeID: merchantID, PayerID: "payer-2",
+		Reason: "fraud", CreatedBy: "ops", CreatedAt: Start,
+	})
+
+	requisites, err := h.Store.SelectAvailableRequisites(
+		ctx, merchantID, decimal.NewFromInt(500), domain.RequisiteTypeCard, domain.BankPreference{}, "payer-1",
+	)
+	if err != nil {
+		t.Fatalf("SelectAvailableRequisites: %v", err)
+	}
+	if len(requisites) != 0 {
+		t.Fatalf("got %d requisites for payer blocked by team, want 0", len(requisites))
+	}
+
+	for payerID, want := range map[string]bool{"payer-1": false, "payer-2": true} {
+		blocked, err := h.Store.IsPayerBlocked(ctx, merchantID, payerID)
+		if err != nil {
+			t.Fatalf("IsPayerBlocked: %v", err)
+		}
+		if blocked != want {
+			t.Fatalf("payer %q: got blocked %v, want %v", payerID, blocked, want)
+		}
+	}
+}
+
+func testFreeBalance(t *testing.T, h Harness) {
+	seedPool(h, nil)
+	h.Seed.PutWallet(memory.Wallet{ID: walletID, PayInBalance: decimal.NewFromInt(1000)})
+	createInvoice(t, h, 700)
+
+	expectIDs(t, selectIDs(t, h, 300, ""), requisiteID)
+	expectIDs(t, selectIDs(t, h, 400, ""))
+
+	inv := &domain.Invoice{
+		MerchantID:       merchantID,
+		Amount:           decimal.NewFromInt(400),
+		Status:           domain.InvoiceStatusCreated,
+		Type:             domain.RequisiteTypeCard,
+		TerminalID:       terminalID,
+		UserID:           "user-1",
+		BankID:           bankID,
+		TraiderAccountID: accountID,
+		RequisiteID:      requisiteID,
+		TimeExpires:      h.Clock.Now().Add(15 * time.Minute),
+		Exchange:         decimal.NewFromInt(90),
+	}
+	if _, err := h.Store.CreateInvoice(context.Background(), inv); !errors.Is(err, domain.ErrorInsufficientBalance) {
+		t.Fatalf("got error %v, want %v", err, domain.ErrorInsufficientBalance)
+	}
+}
+
+func testFinalizeInvoice(t *testing.T, h Harness) {
+	seedPool(h, func(account *memory.TraderAccount, _ *memory.Terminal, _ *memory.Requisite) {
+		account.MaxActiveInvoices = intPtr(1)
+	})
+	h.Seed.PutWallet(memory.Wallet{ID: walletID, PayInBalance: decimal.NewFromInt(1000)})
+	ctx := context.Background()
+
+	paid := createInvoice(t, h, 700)
+	expectIDs(t, selectIDs(t, h, 200, ""))
+
+	finalized, err := h.Store.FinalizeInvoice(ctx, paid.ID, domain.InvoiceStatusSuccess)
+	if err != nil {
+		t.Fatalf("FinalizeInvoice: %v", err)
+	}
+	if finalized.Status != domain.InvoiceStatusSuccess || finalized.RequisiteID != requisiteID {
+		t.Fatalf("unexpected finalized invoice %+v", finalized)
+	}
+
+	// Hold списан с баланса: свободно 300, активных Invoice нет
+	expectIDs(t, selectIDs(t, h, 300, ""), requisiteID)
+	expectIDs(t, selectIDs(t, h, 400, ""))
+
+	if _, err := h.Store.FinalizeInvoice(ctx, paid.ID, domain.InvoiceStatusExpired); !errors.Is(err, domain.ErrorInvoiceNotActive) {
+		t.Fatalf("got error %v, want %v", err, domain.ErrorInvoiceNotActive)
+	}
+	if _, err := h.Store.FinalizeInvoice(ctx, "missing", domain.InvoiceStatusExpired); !errors.Is(err, domain.ErrorInvoiceNotFound) {
+		t.Fatalf("got error %v, want %v", err, domain.ErrorInvoiceNotFound)
+	}
+	if _, err := h.Store.FinalizeInvoice(ctx, paid.ID, domain.InvoiceStatusCreated); !errors.Is(err, domain.ErrorInvalidStatus) {
+		t.Fatalf("got error %v, want %v", err, domain.ErrorInvalidStatus)
+	}
+
+	released := createInvoice(t, h, 300)
+	if _, err := h.Store.FinalizeInvoice(ctx, released.ID, domain.InvoiceStatusCanceled); err != nil {
+		t.Fatalf("FinalizeInvoice: %v", err)
+	}
+	// Освобожденный hold возвращает сумму в свободный баланс
+	expectIDs(t, selectIDs(t, h, 300, ""), requisiteID)
+}
+
+func testExternalStatusChange(t *testing.T, h Harness) {
+	seedPool(h, nil)
+	h.Seed.PutWallet(memory.Wallet{ID: walletID, PayInBalance: decimal.NewFromInt(1000)})
+
+	// Статусы меняет внешняя платежная система, FinalizeInvoice не вызывается
+	paid := createInvoice(t, h, 700)
+	h.Seed.SetInvoiceStatus(paid.ID, domain.InvoiceStatusSuccessHand)
+
+	// Hold списан с баланса: свободно 300
+	expectIDs(t, selectIDs(t, h, 300, ""), requisiteID)
+	expectIDs(t, selectIDs(t, h, 301, ""))
+
+	expired := createInvoice(t, h, 200)
+	expectIDs(t, selectIDs(t, h, 150, ""))
+	h.Seed.SetInvoiceStatus(expired.ID, domain.InvoiceStatusExpired)
+
+	// Освобожденный hold возвращает сумму в свободный баланс
+	expectIDs(t, selectIDs(t, h, 300, ""), requisiteID)
+
+	// Повторная смена финального статуса не списывает hold второй раз
+	h.Seed.SetInvoiceStatus(paid.ID, domain.InvoiceStatusSuccessAppeal)
+	expectIDs(t, selectIDs(t, h, 300, ""), requisiteID)
+}
+
+func testExternalStatusCounters(t *testing.T, h Harness) {
+	seedPool(h, func(account *memory.TraderAccount, terminal *memory.Terminal, r *memory.Requisite) {
+		account.MaxActiveInvoices = intPtr(1)
+		terminal.DailyLimitMoney = decimalPtr(1000)
+		r.DailyLimitInvoices = intPtr(2)
+	})
+
+	expired := createInvoice(t, h, 600)
+	expectIDs(t, selectIDs(t, h, 300, ""))
+
+	// Истекший во внешней системе Invoice не занимает аккаунт и не идет в дневные лимиты
+	h.Seed.SetInvoiceStatus(expired.ID, domain.InvoiceStatusExpired)
+	expectIDs(t, selectIDs(t, h, 900, ""), requisiteID)
+
+	paid := createInvoice(t, h, 600)
+	h.Seed.SetInvoiceStatus(paid.ID, domain.InvoiceStatusSuccess)
+
+	// Оплаченный остается в дневных лимитах: свободно 400 из 1000 и один Invoice из двух
+	expectIDs(t, selectIDs(t, h, 400, ""), requisiteID)
+	expectIDs(t, selectIDs(t, h, 500, ""))
+
+	// Новый Invoice занимает аккаунт и исчерпывает дневной лимит реквизита
+	createInvoice(t, h, 300)
+	expectIDs(t, selectIDs(t, h, 100, ""))
+}
+
+func testExpiredInvoices(t *testing.T, h Harness) {
+	seedPool(h, nil)
+	ctx := context.Background()
+
+	first := createInvoice(t, h, 500)
+	h.Clock.Advance(time.Minute)
+	second := createInvoice(t, h, 600)
+
+	ids, err := h.Store.SelectExpiredInvoiceIDs(ctx, Start.Add(10*time.Minute), 10)
+	if err != nil {
+		t.Fatalf("SelectExpiredInvoiceIDs: %v", err)
+	}
+	expectIDs(t, ids)
+
+	ids, err = h.Store.SelectExpiredInvoiceIDs(ctx, Start.Add(20*time.Minute), 10)
+	if err != nil {
+		t.Fatalf("SelectExpiredInvoiceIDs: %v", err)
+	}
+	expectIDs(t, ids, first.ID, second.ID)
+
+	ids, err = h.Store.SelectExpiredInvoiceIDs(ctx, Start.Add(20*time.Minute), 1)
+	if err != nil {
+		t.Fatalf("SelectExpiredInvoiceIDs: %v", err)
+	}
+	expectIDs(t, ids, first.ID)
+}
+
+func testSelectFlexible(t *testing.T, h Harness) {
+	seedPool(h, nil)
+	createInvoice(t, h, 500)
+
+	requisites, err := h.Store.SelectAvailableRequisitesFlexible(
+		context.Background(),
+		merchantID,
+		decimal.NewFromInt(500),
+		decimal.NewFromInt(520),
+		decimal.NewFromInt(5),
+		domain.RequisiteTypeCard,
+		domain.BankPreference{},
+		"",
+	)
+	if err != nil {
+		t.Fatalf("SelectAvailableRequisitesFlexible: %v", err)
+	}
+	if len(requisites) != 1 {
+		t.Fatalf("got %d requisites, want 1", len(requisites))
+	}
+	// 500 занята активным Invoice, выбирается следующая сумма сетки
+	if !requisites[0].FlexibleSelectedAmount.Equal(decimal.NewFromInt(505)) {
+		t.Fatalf("got selected amount %s, want 505", requisites[0].FlexibleSelectedAmount)
+	}
+}
@@ -0,0 +1,244 @@
+package storetest
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+	"mateo/internal/fake"
+	"mateo/internal/store/memory"
+	"mateo/internal/store/pg"
+	"mateo/internal/store/pg/migrations"
+)
+
+// DSNEnv переменная окружения со строкой подключения к отдельной тестовой базе
+const DSNEnv = "TEST_DATABASE_URL"
+
+// PGConn подключается к тестовой базе и применяет миграции. Без DSNEnv проверка пропускается.
+// Проверки очищают таблицы, поэтому база не должна содержать нужных данных.
+func PGConn(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	dsn := os.Getenv(DSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", DSNEnv)
+	}
+
+	ctx := context.Background()
+	conn, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(conn.Close)
+
+	migrator, err := migrations.New(conn)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	return conn
+}
+
+// PG возвращает NewHarness над pg.Store. Перед каждой проверкой таблицы очищаются,
+// поэтому conn должен указывать на отдельную базу с примененными миграциями.
+func PG(conn *pgxpool.Pool) NewHarness {
+	return func(t *testing.T, clock *fake.Clock) Harness {
+		const truncateQuery = `
+			TRUNCATE
+				"TeamNotification",
+				"PayerBlock",
+				"CapacityCounterBucket",
+				"CapacityCounter",
+				"WalletHold",
+				"InvoiceIn",
+				"MerchantInvoicesInOnTraiderAccount",
+				"Requisite",
+				"Terminal",
+				"TraiderAccount",
+				"Wallet",
+				"Bank",
+				"Team",
+				"Merchant",
+				"Settings"
+			CASCADE`
+
+		if _, err := conn.Exec(context.Background(), truncateQuery); err != nil {
+			t.Fatalf("truncate tables: %v", err)
+		}
+
+		store := pg.NewStore(conn, clock, fake.NewIDGenerator(t.Name()+"-"), time.UTC, 24*time.Hour)
+		return Harness{
+			Store: store,
+			Seed:  &pgSeeder{t: t, conn: conn},
+			Clock: clock,
+		}
+	}
+}
+
+// pgSeeder сохраняет строки в Postgres, при ошибке проверка завершается
+type pgSeeder struct {
+	t    *testing.T
+	conn *pgxpool.Pool
+}
+
+func (s *pgSeeder) exec(query string, args ...any) {
+	s.t.Helper()
+	if _, err := s.conn.Exec(context.Background(), query, args...); err != nil {
+		s.t.Fatalf("seed: %v", err)
+	}
+}
+
+func (s *pgSeeder) PutMerchant(merchant domain.Merchant) {
+	status := merchant.Status
+	if status == "" {
+		status = domain.MerchantStatusActive
+	}
+
+	s.exec(`
+		INSERT INTO "Merchant" (
+			id, in_limit_card, in_limit_wallet, in_limit_sbp,
+			in_max_card, in_max_wallet, in_max_sbp,
+			in_disabled_card, in_disabled_wallet, in_disabled_sbp,
+			daily_turnover_limit, daily_invoice_limit, is_sandbox, status
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (id) DO UPDATE SET
+			in_limit_card = EXCLUDED.in_limit_card,
+			in_limit_wallet = EXCLUDED.in_limit_wallet,
+			in_limit_sbp = EXCLUDED.in_limit_sbp,
+			in_max_card = EXCLUDED.in_max_card,
+			in_max_wallet = EXCLUDED.in_max_wallet,
+			in_max_sbp = EXCLUDED.in_max_sbp,
+			in_disabled_card = EXCLUDED.in_disabled_card,
+			in_disabled_wallet = EXCLUDED.in_disabled_wallet,
+			in_disabled_sbp = EXCLUDED.in_disabled_sbp,
+			daily_turnover_limit = EXCLUDED.daily_turnover_limit,
+			daily_invoice_limit = EXCLUDED.daily_invoice_limit,
+			is_sandbox = EXCLUDED.is_sandbox,
+			status = EXCLUDED.status`,
+		merchant.ID, merchant.InLimitCard, merchant.InLimitWallet, merchant.InLimitSBP,
+		merchant.InMaxCard, merchant.InMaxWallet, merchant.InMaxSBP,
+		merchant.InDisabledCard, merchant.InDisabledWallet, merchant.InDisabledSBP,
+		merchant.DailyTurnoverLimit, merchant.DailyInvoiceLimit, merchant.IsSandbox, status,
+	)
+}
+
+func (s *pgSeeder) PutTeam(team domain.Team) {
+	s.exec(`
+		INSERT INTO "Team" (id, name, is_boosted)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, is_boosted = EXCLUDED.is_boosted`,
+		team.ID, team.Name, team.IsBoosted,
+	)
+}
+
+func (s *pgSeeder) PutBank(id string, name string) {
+	s.exec(`
+		INSERT INTO "Bank" (id, name)
+		VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name`,
+		id, name,
+	)
+}
+
+func (s *pgSeeder) PutWallet(wallet memory.Wallet) {
+	s.exec(`
+		INSERT INTO "Wallet" (id, pay_in_balance)
+		VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET pay_in_balance = EXCLUDED.pay_in_balance`,
+		wallet.ID, wallet.PayInBalance,
+	)
+}
+
+func (s *pgSeeder) PutTraderAccount(account memory.TraderAccount) {
+	var teamID *string
+	if account.TeamID != "" {
+		teamID = &account.TeamID
+	}
+
+	s.exec(`
+		INSERT INTO "TraiderAccount" (
+			id, user_id, wallet_id, team_id, is_can_work, is_blocked, blocked_until,
+			min_invoice_amount_card, min_invoice_amount_wallet, min_invoice_amount_sbp,
+			is_work_on_card_pay_in, is_work_on_wallet_pay_in, is_work_on_sbp_pay_in,
+			max_invoice_amount_int, max_active_invoices_in
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+		account.ID, account.UserID, account.WalletID, teamID, account.IsCanWork, account.IsBlocked, account.BlockedUntil,
+		account.MinInvoiceAmountCard, account.MinInvoiceAmountWallet, account.MinInvoiceAmountSBP,
+		account.IsWorkOnCardPayIn, account.IsWorkOnWalletPayIn, account.IsWorkOnSBPPayIn,
+		account.MaxInvoiceAmount, account.MaxActiveInvoices,
+	)
+}
+
+func (s *pgSeeder) PutTerminal(terminal memory.Terminal) {
+	s.exec(`
+		INSERT INTO "Terminal" (
+			id, traider_account_id, is_can_work, is_blocked, blocked_until,
+			quarantined_until, quarantine_reason,
+			min_invoice_amount, max_invoice_amount,
+			daily_limit_money, daily_limit_money_window_hours,
+			daily_limit_invoices, daily_limit_invoices_window_hours,
+			max_active_invoice, invoice_interval
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11, $12, $13, $14, $15)`,
+		terminal.ID, terminal.TraiderAccountID, terminal.IsCanWork, terminal.IsBlocked, terminal.BlockedUntil,
+		terminal.QuarantinedUntil, terminal.QuarantineReason,
+		terminal.MinInvoiceAmount, terminal.MaxInvoiceAmount,
+		terminal.DailyLimitMoney, terminal.DailyLimitMoneyWindowHours,
+		terminal.DailyLimitInvoices, terminal.DailyLimitInvoicesWindowHours,
+		terminal.MaxActiveInvoice, terminal.InvoiceInterval,
+	)
+}
+
+func (s *pgSeeder) PutRequisite(requisite memory.Requisite) {
+	s.exec(`
+		INSERT INTO "Requisite" (
+			id, terminal_id, bank_id, type, name, phone_number, card_number, wallet_number,
+			is_can_work, is_blocked, blocked_until, quarantined_until, quarantine_reason,
+			min_invoice_amount, max_invoice_amount,
+			daily_limit_invoices, daily_limit_invoices_window_hours,
+			max_active_invoice, invoice_interval
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), $14, $15, $16, $17, $18, $19)`,
+		requisite.ID, requisite.TerminalID, requisite.BankID, requisite.Type,
+		requisite.Name, requisite.PhoneNumber, requisite.CardNumber, requisite.WalletNumber,
+		requisite.IsCanWork, requisite.IsBlocked, requisite.BlockedUntil,
+		requisite.QuarantinedUntil, requisite.QuarantineReason,
+		requisite.MinInvoiceAmount, requisite.MaxInvoiceAmount,
+		requisite.DailyLimitInvoices, requisite.DailyLimitInvoicesWindowHours,
+		requisite.MaxActiveInvoice, requisite.InvoiceInterval,
+	)
+}
+
+func (s *pgSeeder) LinkMerchant(merchantID string, traiderAccountID string) {
+	s.exec(`
+		INSERT INTO "MerchantInvoicesInOnTraiderAccount" (merchant_id, traider_account_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`,
+		merchantID, traiderAccountID,
+	)
+}
+
+func (s *pgSeeder) PutPayerBlock(block domain.PayerBlock) {
+	s.exec(`
+		INSERT INTO "PayerBlock" (scope, scope_id, payer_id, reason, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		block.Scope, block.ScopeID, block.PayerID, block.Reason, block.CreatedBy, block.CreatedAt,
+	)
+}
+
+func (s *pgSeeder) SetExchangeRate(rate decimal.Decimal) {
+	s.exec(`DELETE FROM "Settings"`)
+	s.exec(`INSERT INTO "Settings" (exchange_rate) VALUES ($1)`, rate)
+}
+
+func (s *pgSeeder) SetInvoiceStatus(invoiceID string, status domain.InvoiceStatus) {
+	s.exec(`UPDATE "InvoiceIn" SET status = $2 WHERE id = $1`, invoiceID, status)
+}
@@ -0,0 +1,245 @@
+// Package storetest содержит общий набор проверок хранилищ сервисов. Набор запускается
+// и для memory.Store, и для pg.Store, чтобы логика отбора в памяти не расходилась с SQL.
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+	"mateo/internal/fake"
+	"mateo/internal/service/health"
+	"mateo/internal/service/invoice"
+	"mateo/internal/service/merchant"
+	"mateo/internal/service/payer"
+	"mateo/internal/service/quarantine"
+	"mateo/internal/service/requisite"
+	"mateo/internal/store/memory"
+)
+
+// Store объединяет интерфейсы хранилищ всех сервисов
+type Store interface {
+	merchant.Store
+	invoice.Store
+	requisite.Store
+	quarantine.Store
+	payer.Store
+	health.Store
+}
+
+// Seeder заполняет хранилище исходными данными. Строки описываются типами memory.
+type Seeder interface {
+	PutMerchant(merchant domain.Merchant)
+	PutTeam(team domain.Team)
+	PutBank(id string, name string)
+	PutWallet(wallet memory.Wallet)
+	PutTraderAccount(account memory.TraderAccount)
+	PutTerminal(terminal memory.Terminal)
+	PutRequisite(requisite memory.Requisite)
+	LinkMerchant(merchantID string, traiderAccountID string)
+	PutPayerBlock(block domain.PayerBlock)
+	SetExchangeRate(rate decimal.Decimal)
+	// SetInvoiceStatus меняет статус Invoice напрямую, как внешняя платежная система
+	SetInvoiceStatus(invoiceID string, status domain.InvoiceStatus)
+}
+
+// Harness пустое хранилище для одной проверки. Clock должен быть часами, с которыми создан Store.
+type Harness struct {
+	Store Store
+	Seed  Seeder
+	Clock *fake.Clock
+}
+
+// NewHarness создает пустое хранилище; часы заданы набором и сдвигаются проверками
+type NewHarness func(t *testing.T, clock *fake.Clock) Harness
+
+// Start момент, с которого начинается каждая проверка. Часовой пояс бизнеса — UTC.
+var Start = time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC)
+
+// Memory создает Harness над memory.Store
+func Memory(t *testing.T, clock *fake.Clock) Harness {
+	store := memory.NewStore(clock, fake.NewIDGenerator("id"), time.UTC, 24*time.Hour)
+	return Harness{Store: store, Seed: store, Clock: clock}
+}
+
+// Run запускает все проверки набора
+func Run(t *testing.T, newHarness NewHarness) {
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, h Harness)
+	}{
+		{"Merchant", testMerchant},
+		{"MerchantTurnover", testMerchantTurnover},
+		{"ExchangeRate", testExchangeRate},
+		{"BoostedTeams", testBoostedTeams},
+		{"SelectExact", testSelectExact},
+		{"SelectBank", testSelectBank},
+		{"SameAmountExcluded", testSameAmountExcluded},
+		{"RequisiteInterval", testRequisiteInterval},
+		{"RequisiteDailyLimit", testRequisiteDailyLimit},
+		{"TerminalRollingMoneyLimit", testTerminalRollingMoneyLimit},
+		{"TimedBlocks", testTimedBlocks},
+		{"Quarantine", testQuarantine},
+		{"QuarantineOutcomes", testQuarantineOutcomes},
+		{"SelectionStats", testSelectionStats},
+		{"Payers", testPayers},
+		{"FreeBalance", testFreeBalance},
+		{"FinalizeInvoice", testFinalizeInvoice},
+		{"ExternalStatusChange", testExternalStatusChange},
+		{"ExternalStatusCounters", testExternalStatusCounters},
+		{"ExpiredInvoices", testExpiredInvoices},
+		{"SelectFlexible", testSelectFlexible},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			h := newHarness(t, fake.NewClock(Start))
+			c.fn(t, h)
+		})
+	}
+}
+
+const (
+	merchantID  = "merchant-1"
+	teamID      = "team-1"
+	bankID      = "bank-1"
+	walletID    = "wallet-1"
+	accountID   = "account-1"
+	terminalID  = "terminal-1"
+	requisiteID = "requisite-1"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func decimalPtr(v int64) *decimal.Decimal {
+	d := decimal.NewFromInt(v)
+	return &d
+}
+
+// seedPool создает мерчанта и один рабочий реквизит CARD с суммами от 100 до 10000.
+// modify позволяет поменять строки до сохранения.
+func seedPool(h Harness, modify func(account *memory.TraderAccount, terminal *memory.Terminal, requisite *memory.Requisite)) {
+	account := memory.TraderAccount{
+		ID:                   accountID,
+		UserID:               "user-1",
+		WalletID:             walletID,
+		TeamID:               teamID,
+		IsCanWork:            true,
+		MinInvoiceAmountCard: decimal.NewFromInt(100),
+		IsWorkOnCardPayIn:    true,
+		MaxInvoiceAmount:     decimal.NewFromInt(10000),
+	}
+	terminal := memory.Terminal{
+		ID:               terminalID,
+		TraiderAccountID: accountID,
+		IsCanWork:        true,
+		MinInvoiceAmount: decimal.NewFromInt(100),
+		MaxInvoiceAmount: decimal.NewFromInt(10000),
+	}
+	req := memory.Requisite{
+		ID:               requisiteID,
+		TerminalID:       terminalID,
+		BankID:           bankID,
+		Type:             domain.RequisiteTypeCard,
+		Name:             "Ivan I.",
+		CardNumber:       "2200000000000001",
+		IsCanWork:        true,
+		MinInvoiceAmount: decimal.NewFromInt(100),
+		MaxInvoiceAmount: decimal.NewFromInt(10000),
+	}
+	if modify != nil {
+		modify(&account, &terminal, &req)
+	}
+
+	h.Seed.PutMerchant(domain.Merchant{ID: merchantID})
+	h.Seed.PutTeam(domain.Team{ID: teamID, Name: "Team"})
+	h.Seed.PutBank(bankID, "Bank")
+	h.Seed.PutWallet(memory.Wallet{ID: walletID, PayInBalance: decimal.NewFromInt(100000)})
+	h.Seed.PutTraderAccount(account)
+	h.Seed.PutTerminal(terminal)
+	h.Seed.PutRequisite(req)
+	h.Seed.LinkMerchant(merchantID, accountID)
+	h.Seed.SetExchangeRate(decimal.NewFromInt(90))
+}
+
+// selectIDs возвращает ID реквизитов, доступных для суммы; непустой bankID разрешает только этот банк
+func selectIDs(t *testing.T, h Harness, amount int64, bankID string) []string {
+	t.Helper()
+
+	var banks domain.BankPreference
+	if bankID != "" {
+		banks = domain.BankPreference{Preferred: []string{bankID}, Strict: true}
+	}
+	return selectAmountIDs(t, h, amount, banks)
+}
+
+// selectBankIDs возвращает ID реквизитов, доступных для суммы 500 с пожеланиями к банку
+func selectBankIDs(t *testing.T, h Harness, banks domain.BankPreference) []string {
+	t.Helper()
+	return selectAmountIDs(t, h, 500, banks)
+}
+
+func selectAmountIDs(t *testing.T, h Harness, amount int64, banks domain.BankPreference) []string {
+	t.Helper()
+
+	requisites, err := h.Store.SelectAvailableRequisites(
+		context.Background(), merchantID, decimal.NewFromInt(amount), domain.RequisiteTypeCard, banks, "",
+	)
+	if err != nil {
+		t.Fatalf("SelectAvailableRequisites: %v", err)
+	}
+
+	ids := make([]string, 0, len(requisites))
+	for _, r := range requisites {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+// createInvoice создает Invoice на единственный реквизит пула
+func createInvoice(t *testing.T, h Harness, amount int64) *domain.Invoice {
+	t.Helper()
+	return createPayerInvoice(t, h, amount, "")
+}
+
+// createPayerInvoice создает Invoice плательщика на единственный реквизит пула
+func createPayerInvoice(t *testing.T, h Harness, amount int64, payerID string) *domain.Invoice {
+	t.Helper()
+
+	inv := &domain.Invoice{
+		MerchantID:       merchantID,
+		PayerID:          payerID,
+		Amount:           decimal.NewFromInt(amount),
+		Status:           domain.InvoiceStatusCreated,
+		Type:             domain.RequisiteTypeCard,
+		TerminalID:       terminalID,
+		UserID:           "user-1",
+		BankID:           bankID,
+		TraiderAccountID: accountID,
+		RequisiteID:      requisiteID,
+		CallbackURL:      "https://example.com/callback",
+		TimeExpires:      h.Clock.Now().Add(15 * time.Minute),
+		Exchange:         decimal.NewFromInt(90),
+	}
+	if _, err := h.Store.CreateInvoice(context.Background(), inv); err != nil {
+		t.Fatalf("CreateInvoice: %v", err)
+	}
+	return inv
+}
+
+func expectIDs(t *testing.T, got []string, want ...string) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got requisites %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got requisites %v, want %v", got, want)
+		}
+	}
+}
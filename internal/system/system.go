@@ -0,0 +1,34 @@
+// Package system содержит реализации domain.Clock, domain.Rand и domain.IDGenerator
+// поверх стандартной библиотеки для работы в production.
+package system
+
+import (
+	"github.com/google/uuid"
+	"math/rand"
+	"time"
+)
+
+// Clock возвращает текущее системное время
+type Clock struct{}
+
+func (Clock) Now() time.Time {
+	return time.Now()
+}
+
+// Rand использует глобальный источник math/rand, безопасный для конкурентного доступа
+type Rand struct{}
+
+func (Rand) Intn(n int) int {
+	return rand.Intn(n)
+}
+
+func (Rand) Float64() float64 {
+	return rand.Float64()
+}
+
+// UUIDGenerator выдает случайные UUID v4
+type UUIDGenerator struct{}
+
+func (UUIDGenerator) NewID() string {
+	return uuid.New().String()
+}
@@ -0,0 +1,122 @@
+// Package tracing настраивает OpenTelemetry: экспортер, распространение W3C trace context
+// и спаны запросов Postgres и Redis.
+package tracing
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "mateo"
+
+const (
+	// ExporterNone спаны не создаются
+	ExporterNone = "none"
+	// ExporterStdout спаны пишутся в stdout, для локальной отладки
+	ExporterStdout = "stdout"
+	// ExporterFile спаны пишутся JSON в файл Options.File
+	ExporterFile = "file"
+	// ExporterOTLP спаны отправляются по OTLP/HTTP; адрес задается стандартными
+	// переменными OTEL_EXPORTER_OTLP_ENDPOINT и OTEL_EXPORTER_OTLP_TRACES_ENDPOINT
+	ExporterOTLP = "otlp"
+)
+
+var ErrorUnknownExporter = errors.New("unknown tracing exporter")
+
+type Options struct {
+	Exporter    string
+	File        string
+	ServiceName string
+	// SampleRatio доля трасс, начатых этим сервисом; решение вызывающего сервиса соблюдается
+	SampleRatio float64
+}
+
+// Setup регистрирует глобальный провайдер спанов и W3C propagator. Возвращаемая функция
+// отправляет оставшиеся спаны и должна быть вызвана при остановке.
+func Setup(ctx context.Context, options Options) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var closer io.Closer
+	switch options.Exporter {
+	case "", ExporterNone:
+		return func(ctx context.Context) error { return nil }, nil
+	case ExporterStdout:
+		e, err := stdouttrace.New()
+		if err != nil {
+			return nil, errors.Wrap(err, "create stdout exporter")
+		}
+		exporter = e
+	case ExporterFile:
+		file, err := os.OpenFile(options.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, errors.Wrap(err, "open trace file")
+		}
+		e, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, errors.Wrap(err, "create file exporter")
+		}
+		exporter, closer = e, file
+	case ExporterOTLP:
+		e, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "create otlp exporter")
+		}
+		exporter = e
+	default:
+		return nil, errors.Wrapf(ErrorUnknownExporter, "%q", options.Exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(options.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			if closeErr := closer.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}
+
+// Tracer трассировщик сервиса; до Setup и с экспортером none спаны не записываются
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start начинает внутренний спан
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End завершает спан, отмечая ошибку
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
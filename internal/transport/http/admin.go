@@ -0,0 +1,524 @@
+package http
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+	"mateo/internal/domain"
+)
+
+// adminActorKey ключ Locals с именем токена администратора
+const adminActorKey = "adminActor"
+
+var (
+	ErrorUnauthorized      = errors.New("unauthorized")
+	ErrorEmptyLimits       = errors.New("no limits to update")
+	ErrorInvalidAuditLimit = errors.New("limit must be a non-negative integer")
+)
+
+type AdminResponse struct {
+	Status  string `json:"status"`
+	Error   bool   `json:"error"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+type MerchantData struct {
+	ID                 string          `json:"id"`
+	Status             string          `json:"status"`
+	InLimitCard        decimal.Decimal `json:"inLimitCard"`
+	InLimitWallet      decimal.Decimal `json:"inLimitWallet"`
+	InLimitSBP         decimal.Decimal `json:"inLimitSbp"`
+	InMaxCard          decimal.Decimal `json:"inMaxCard"`
+	InMaxWallet        decimal.Decimal `json:"inMaxWallet"`
+	InMaxSBP           decimal.Decimal `json:"inMaxSbp"`
+	InDisabledCard     bool            `json:"inDisabledCard"`
+	InDisabledWallet   bool            `json:"inDisabledWallet"`
+	InDisabledSBP      bool            `json:"inDisabledSbp"`
+	DailyTurnoverLimit decimal.Decimal `json:"dailyTurnoverLimit"`
+	DailyInvoiceLimit  int             `json:"dailyInvoiceLimit"`
+	IsSandbox          bool            `json:"isSandbox"`
+}
+
+// CreateMerchantRequest новый мерчант; без status создается активным
+type CreateMerchantRequest struct {
+	ID                 string          `json:"id"`
+	Status             string          `json:"status"`
+	InLimitCard        decimal.Decimal `json:"inLimitCard"`
+	InLimitWallet      decimal.Decimal `json:"inLimitWallet"`
+	InLimitSBP         decimal.Decimal `json:"inLimitSbp"`
+	InMaxCard          decimal.Decimal `json:"inMaxCard"`
+	InMaxWallet        decimal.Decimal `json:"inMaxWallet"`
+	InMaxSBP           decimal.Decimal `json:"inMaxSbp"`
+	InDisabledCard     bool            `json:"inDisabledCard"`
+	InDisabledWallet   bool            `json:"inDisabledWallet"`
+	InDisabledSBP      bool            `json:"inDisabledSbp"`
+	DailyTurnoverLimit decimal.Decimal `json:"dailyTurnoverLimit"`
+	DailyInvoiceLimit  int             `json:"dailyInvoiceLimit"`
+	IsSandbox          bool            `json:"isSandbox"`
+}
+
+// UpdateMerchantRequest частичное изменение мерчанта: отсутствующие поля не меняются
+type UpdateMerchantRequest struct {
+	Status             *domain.MerchantStatus `json:"status"`
+	InLimitCard        *decimal.Decimal       `json:"inLimitCard"`
+	InLimitWallet      *decimal.Decimal       `json:"inLimitWallet"`
+	InLimitSBP         *decimal.Decimal       `json:"inLimitSbp"`
+	InMaxCard          *decimal.Decimal       `json:"inMaxCard"`
+	InMaxWallet        *decimal.Decimal       `json:"inMaxWallet"`
+	InMaxSBP           *decimal.Decimal       `json:"inMaxSbp"`
+	InDisabledCard     *bool                  `json:"inDisabledCard"`
+	InDisabledWallet   *bool                  `json:"inDisabledWallet"`
+	InDisabledSBP      *bool                  `json:"inDisabledSbp"`
+	DailyTurnoverLimit *decimal.Decimal       `json:"dailyTurnoverLimit"`
+	DailyInvoiceLimit  *int                   `json:"dailyInvoiceLimit"`
+	IsSandbox          *bool                  `json:"isSandbox"`
+}
+
+type MerchantLimitsData struct {
+	Card   decimal.Decimal `json:"card"`
+	Wallet decimal.Decimal `json:"wallet"`
+	SBP    decimal.Decimal `json:"sbp"`
+}
+
+type UpdateMerchantLimitsRequest struct {
+	Card   *decimal.Decimal `json:"card"`
+	Wallet *decimal.Decimal `json:"wallet"`
+	SBP    *decimal.Decimal `json:"sbp"`
+}
+
+type AuditEntryData struct {
+	ID         string  `json:"id"`
+	Actor      string  `json:"actor"`
+	Action     string  `json:"action"`
+	EntityType string  `json:"entityType"`
+	EntityID   string  `json:"entityId"`
+	Before     rawJSON `json:"before"`
+	After      rawJSON `json:"after"`
+	CreatedAt  string  `json:"createdAt"`
+}
+
+// rawJSON вставляет сохраненный JSON как есть; пустое значение кодируется как null
+type rawJSON []byte
+
+func (r rawJSON) MarshalJSON() ([]byte, error) {
+	if len(r) == 0 {
+		return []byte("null"), nil
+	}
+	return r, nil
+}
+
+// adminAuth пропускает запросы с заголовком Authorization: Bearer <token>, где token есть
+// в tokens (имя → токен). Имя токена сохраняется как автор изменений для журнала.
+func adminAuth(tokens map[string]string) fiber.Handler {
+	// Сравниваем хеши: время сравнения не зависит от длины и содержимого токена
+	hashes := make(map[string][32]byte, len(tokens))
+	for name, token := range tokens {
+		hashes[name] = sha256.Sum256([]byte(token))
+	}
+
+	return func(c fiber.Ctx) error {
+		token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if ok && token != "" {
+			hash := sha256.Sum256([]byte(token))
+			for name, expected := range hashes {
+				if subtle.ConstantTimeCompare(hash[:], expected[:]) == 1 {
+					c.Locals(adminActorKey, name)
+					return c.Next()
+				}
+			}
+		}
+
+		return c.Status(fiber.StatusUnauthorized).JSON(buildAdminResponseWithError(ErrorUnauthorized))
+	}
+}
+
+func adminActor(c fiber.Ctx) string {
+	return fiber.Locals[string](c, adminActorKey)
+}
+
+func (s *Server) ListMerchants(c fiber.Ctx) error {
+	merchants, err := s.app.ListMerchants(c.Context())
+	if err != nil {
+		return adminError(c, err)
+	}
+
+	data := make([]*MerchantData, 0, len(merchants))
+	for _, m := range merchants {
+		data = append(data, newMerchantData(m))
+	}
+	return c.Status(fiber.StatusOK).JSON(buildAdminResponse(data))
+}
+
+func (s *Server) GetMerchant(c fiber.Ctx) error {
+	merchant, err := s.app.GetMerchant(c.Context(), c.Params("id"))
+	if err != nil {
+		return adminError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(buildAdminResponse(newMerchantData(merchant)))
+}
+
+func (s *Server) CreateMerchant(c fiber.Ctx) error {
+	req := &CreateMerchantRequest{}
+	if err := c.Bind().Body(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).
+			JSON(buildAdminResponseWithError(errors.Wrap(err, "parse request body")))
+	}
+
+	merchant, err := s.app.CreateMerchant(c.Context(), adminActor(c), &domain.Merchant{
+		ID:                 req.ID,
+		Status:             domain.MerchantStatus(req.Status),
+		InLimitCard:        req.InLimitCard,
+		InLimitWallet:      req.InLimitWallet,
+		InLimitSBP:         req.InLimitSBP,
+		InMaxCard:          req.InMaxCard,
+		InMaxWallet:        req.InMaxWallet,
+		InMaxSBP:           req.InMaxSBP,
+		InDisabledCard:     req.InDisabledCard,
+		InDisabledWallet:   req.InDisabledWallet,
+		InDisabledSBP:      req.InDisabledSBP,
+		DailyTurnoverLimit: req.DailyTurnoverLimit,
+		DailyInvoiceLimit:  req.DailyInvoiceLimit,
+		IsSandbox:          req.IsSandbox,
+	})
+	if err != nil {
+		return adminError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(buildAdminResponse(newMerchantData(merchant)))
+}
+
+func (s *Server) UpdateMerchant(c fiber.Ctx) error {
+	req := &UpdateMerchantRequest{}
+	if err := c.Bind().Body(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).
+			JSON(buildAdminResponseWithError(errors.Wrap(err, "parse request body")))
+	}
+
+	merchant, err := s.app.UpdateMerchant(c.Context(), adminActor(c), c.Params("id"), domain.MerchantPatch{
+		Status:             req.Status,
+		InLimitCard:        req.InLimitCard,
+		InLimitWallet:      req.InLimitWallet,
+		InLimitSBP:         req.InLimitSBP,
+		InMaxCard:          req.InMaxCard,
+		InMaxWallet:        req.InMaxWallet,
+		InMaxSBP:           req.InMaxSBP,
+		InDisabledCard:     req.InDisabledCard,
+		InDisabledWallet:   req.InDisabledWallet,
+		InDisabledSBP:      req.InDisabledSBP,
+		DailyTurnoverLimit: req.DailyTurnoverLimit,
+		DailyInvoiceLimit:  req.DailyInvoiceLimit,
+		IsSandbox:          req.IsSandbox,
+	})
+	if err != nil {
+		return adminError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(buildAdminResponse(newMerchantData(merchant)))
+}
+
+func (s *Server) DeleteMerchant(c fiber.Ctx) error {
+	if err := s.app.DeleteMerchant(c.Context(), adminActor(c), c.Params("id")); err != nil {
+		return adminError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(buildAdminResponse(nil))
+}
+
+func (s *Server) GetMerchantLimits(c fiber.Ctx) error {
+	merchant, err := s.app.GetMerchant(c.Context(), c.Params("id"))
+	if err != nil {
+		return adminError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(buildAdminResponse(newMerchantLimitsData(merchant)))
+}
+
+func (s *Server) UpdateMerchantLimits(c fiber.Ctx) error {
+	req := &UpdateMerchantLimitsRequest{}
+	if err := c.Bind().Body(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).
+			JSON(buildAdminResponseWithError(errors.Wrap(err, "parse request body")))
+	}
+	if req.Card == nil && req.Wallet == nil && req.SBP == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(buildAdminResponseWithError(ErrorEmptyLimits))
+	}
+
+	merchant, err := s.app.UpdateMerchant(c.Context(), adminActor(c), c.Params("id"), domain.MerchantPatch{
+		InLimitCard:   req.Card,
+		InLimitWallet: req.Wallet,
+		InLimitSBP:    req.SBP,
+	})
+	if err != nil {
+		return adminError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(buildAdminResponse(newMerchantLimitsData(merchant)))
+}
+
+func (s *Server) ListMerchantTraderAccounts(c fiber.Ctx) error {
+	accountIDs, err := s.app.ListMerchantTraderAccounts(c.Context(), c.Params("id"))
+	if err != nil {
+		return adminError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(buildAdminResponse(accountIDs))
+}
+
+func (s *Server) LinkTraderAccount(c fiber.Ctx) error {
+	err := s.app.LinkTraderAccount(c.Context(), adminActor(c), c.Params("id"), c.Params("accountId"))
+	if err != nil {
+		return adminError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(buildAdminResponse(nil))
+}
+
+func (s *Server) UnlinkTraderAccount(c fiber.Ctx) error {
+	err := s.app.UnlinkTraderAccount(c.Context(), adminActor(c), c.Params("id"), c.Params("accountId"))
+	if err != nil {
+		return adminError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(buildAdminResponse(nil))
+}
+
+// ListAuditLog возвращает журнал изменений; фильтры entityType, entityId и limit передаются в query
+func (s *Server) ListAuditLog(c fiber.Ctx) error {
+	limit, err := parseAuditLimit(c.Query("limit"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(buildAdminResponseWithError(err))
+	}
+
+	entries, err := s.app.ListAuditLog(c.Context(), c.Query("entityType"), c.Query("entityId"), limit)
+	if err != nil {
+		return adminError(c, err)
+	}
+
+	data := make([]*AuditEntryData, 0, len(entries))
+	for _, e := range entries {
+		data = append(data, &AuditEntryData{
+			ID:         e.ID,
+			Actor:      e.Actor,
+			Action:     e.Action,
+			EntityType: e.EntityType,
+			EntityID:   e.EntityID,
+			Before:     e.Before,
+			After:      e.After,
+			CreatedAt:  e.CreatedAt.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(buildAdminResponse(data))
+}
+
+// parseAuditLimit разбирает limit журнала; пустой и 0 означают размер по умолчанию
+func parseAuditLimit(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		return 0, ErrorInvalidAuditLimit
+	}
+	return limit, nil
+}
+
+func newMerchantData(m *domain.Merchant) *MerchantData {
+	return &MerchantData{
+		ID:                 m.ID,
+		Status:             string(m.Status),
+		InLimitCard:        m.InLimitCard,
+		InLimitWallet:      m.InLimitWallet,
+		InLimitSBP:         m.InLimitSBP,
+		InMaxCard:          m.InMaxCard,
+		InMaxWallet:        m.InMaxWallet,
+		InMaxSBP:           m.InMaxSBP,
+		InDisabledCard:     m.InDisabledCard,
+		InDisabledWallet:   m.InDisabledWallet,
+		InDisabledSBP:      m.InDisabledSBP,
+		DailyTurnoverLimit: m.DailyTurnoverLimit,
+		DailyInvoiceLimit:  m.DailyInvoiceLimit,
+		IsSandbox:          m.IsSandbox,
+	}
+}
+
+func newMerchantLimitsData(m *domain.Merchant) *MerchantLimitsData {
+	return &MerchantLimitsData{
+		Card:   m.InLimitCard,
+		Wallet: m.InLimitWallet,
+		SBP:    m.InLimitSBP,
+	}
+}
+
+// adminError отвечает статусом, соответствующим ошибке domain
+func adminError(c fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrorInvalidMerchantID),
+		errors.Is(err, domain.ErrorInvalidTraderAccountID),
+		errors.Is(err, domain.ErrorInvalidLimit),
+		errors.Is(err, domain.ErrorEmptyBlockReason),
+		errors.Is(err, domain.ErrorInvalidBlockedUntil),
+		errors.Is(err, domain.ErrorInvalidUserID),
+		errors.Is(err, domain.ErrorUnknownMerchantStatus),
+		errors.Is(err, domain.ErrorUnknownPayerBlockScope):
+		status = fiber.StatusBadRequest
+	case errors.Is(err, domain.ErrorMerchantNotFound),
+		errors.Is(err, domain.ErrorTraderAccountNotFound),
+		errors.Is(err, domain.ErrorBlockEntityNotFound),
+		errors.Is(err, domain.ErrorPayerBlockScopeNotFound),
+		errors.Is(err, domain.ErrorAdminDisabled):
+		status = fiber.StatusNotFound
+	case errors.Is(err, domain.ErrorMerchantAlreadyExists),
+		errors.Is(err, domain.ErrorMerchantHasInvoices):
+		status = fiber.StatusConflict
+	default:
+		log.Ctx(c.Context()).Error().Err(err).Str("path", c.Path()).Msg("admin request failed")
+	}
+
+	return c.Status(status).JSON(buildAdminResponseWithError(err))
+}
+
+func buildAdminResponse(data any) *AdminResponse {
+	return &AdminResponse{
+		Status:  "ok",
+		Error:   false,
+		Message: "success",
+		Data:    data,
+	}
+}
+
+func buildAdminResponseWithError(err error) *AdminResponse {
+	return &AdminResponse{
+		Status:  "error",
+		Error:   true,
+		Message: err.Error(),
+	}
+}
+
+type BlockRequest struct {
+	Reason string `json:"reason"`
+	// BlockedUntil необязательный срок блокировки в RFC 3339
+	BlockedUntil *time.Time `json:"blockedUntil"`
+}
+
+type BlockData struct {
+	Entity       string     `json:"entity"`
+	EntityID     string     `json:"entityId"`
+	IsBlocked    bool       `json:"isBlocked"`
+	Reason       string     `json:"reason,omitempty"`
+	BlockedUntil *time.Time `json:"blockedUntil,omitempty"`
+	BlockedBy    string     `json:"blockedBy,omitempty"`
+}
+
+// GetBlock, Block и Unblock обслуживают одну сущность; обработчики создаются на каждый тип
+func (s *Server) GetBlock(entity domain.BlockEntity) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		block, err := s.app.GetBlock(c.Context(), entity, c.Params("id"))
+		if err != nil {
+			return adminError(c, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(buildAdminResponse(newBlockData(block)))
+	}
+}
+
+func (s *Server) Block(entity domain.BlockEntity) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		req := &BlockRequest{}
+		if err := c.Bind().Body(req); err != nil {
+			return c.Status(fiber.StatusBadRequest).
+				JSON(buildAdminResponseWithError(errors.Wrap(err, "parse request body")))
+		}
+
+		block, err := s.app.Block(c.Context(), adminActor(c), entity, c.Params("id"), req.Reason, req.BlockedUntil)
+		if err != nil {
+			return adminError(c, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(buildAdminResponse(newBlockData(block)))
+	}
+}
+
+func (s *Server) Unblock(entity domain.BlockEntity) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		block, err := s.app.Unblock(c.Context(), adminActor(c), entity, c.Params("id"))
+		if err != nil {
+			return adminError(c, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(buildAdminResponse(newBlockData(block)))
+	}
+}
+
+func newBlockData(b *domain.Block) *BlockData {
+	return &BlockData{
+		Entity:       string(b.Entity),
+		EntityID:     b.EntityID,
+		IsBlocked:    b.IsBlocked,
+		Reason:       b.Reason,
+		BlockedUntil: b.BlockedUntil,
+		BlockedBy:    b.BlockedBy,
+	}
+}
+
+type PayerBlockRequest struct {
+	Reason string `json:"reason"`
+}
+
+type PayerBlockData struct {
+	Scope     string    `json:"scope"`
+	ScopeID   string    `json:"scopeId"`
+	PayerID   string    `json:"userId"`
+	Reason    string    `json:"reason"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ListPayerBlocks, BlockPayer и UnblockPayer обслуживают список блокировки плательщиков мерчанта или команды
+func (s *Server) ListPayerBlocks(scope domain.PayerBlockScope) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		blocks, err := s.app.ListPayerBlocks(c.Context(), scope, c.Params("id"))
+		if err != nil {
+			return adminError(c, err)
+		}
+
+		data := make([]*PayerBlockData, 0, len(blocks))
+		for _, block := range blocks {
+			data = append(data, newPayerBlockData(block))
+		}
+		return c.Status(fiber.StatusOK).JSON(buildAdminResponse(data))
+	}
+}
+
+func (s *Server) BlockPayer(scope domain.PayerBlockScope) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		req := &PayerBlockRequest{}
+		if err := c.Bind().Body(req); err != nil {
+			return c.Status(fiber.StatusBadRequest).
+				JSON(buildAdminResponseWithError(errors.Wrap(err, "parse request body")))
+		}
+
+		block, err := s.app.BlockPayer(c.Context(), adminActor(c), scope, c.Params("id"), c.Params("payerId"), req.Reason)
+		if err != nil {
+			return adminError(c, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(buildAdminResponse(newPayerBlockData(block)))
+	}
+}
+
+func (s *Server) UnblockPayer(scope domain.PayerBlockScope) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if err := s.app.UnblockPayer(c.Context(), adminActor(c), scope, c.Params("id"), c.Params("payerId")); err != nil {
+			return adminError(c, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(buildAdminResponse(nil))
+	}
+}
+
+func newPayerBlockData(b *domain.PayerBlock) *PayerBlockData {
+	return &PayerBlockData{
+		Scope:     string(b.Scope),
+		ScopeID:   b.ScopeID,
+		PayerID:   b.PayerID,
+		Reason:    b.Reason,
+		CreatedBy: b.CreatedBy,
+		CreatedAt: b.CreatedAt,
+	}
+}
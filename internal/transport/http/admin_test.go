@@ -0,0 +1,28 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAuditLimit(t *testing.T) {
+	cases := []struct {
+		value string
+		want  int
+		err   error
+	}{
+		{"", 0, nil},
+		{"0", 0, nil},
+		{"50", 50, nil},
+		{"-1", 0, ErrorInvalidAuditLimit},
+		{"ten", 0, ErrorInvalidAuditLimit},
+		{"1.5", 0, ErrorInvalidAuditLimit},
+	}
+
+	for _, c := range cases {
+		limit, err := parseAuditLimit(c.value)
+		assert.Equal(t, c.err, err, "limit %q", c.value)
+		assert.Equal(t, c.want, limit, "limit %q", c.value)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"mateo/internal/domain"
+	"strconv"
 	"time"
 )
 
@@ -13,24 +14,29 @@ const (
 )
 
 var (
-	ErrorInvalidAmount      = errors.New("invalid amount")
-	ErrorEmptyMerchantID    = errors.New("empty merchantId field")
-	ErrorEmptyCallbackURL   = errors.New("empty callbackUrl field")
-	ErrorEmptyUserID        = errors.New("empty userId field")
-	ErrorEmptyRequisiteType = errors.New("empty requisiteType field")
+	ErrorInvalidAmount               = errors.New("invalid amount")
+	ErrorEmptyMerchantID             = errors.New("empty merchantId field")
+	ErrorEmptyCallbackURL            = errors.New("empty callbackUrl field")
+	ErrorEmptyUserID                 = errors.New("empty userId field")
+	ErrorEmptyRequisiteType          = errors.New("empty requisiteType field")
+	ErrorStrictBankWithoutPreference = errors.New("strictBank requires bankId or preferredBankIds")
 )
 
 type CreateInvoiceRequest struct {
-	Amount              int    `json:"amount"`
-	InternalRequestID   string `json:"internalRequestID"`
-	CallbackUrl         string `json:"callbackUrl"`
-	CallbackKey         string `json:"callbackKey"`
-	MerchantID          string `json:"merchantID"`
-	Type                string `json:"type"`
-	ActiveTime          int    `json:"activeTime"`
-	BankID              string `json:"bankId"`
-	FlexibleRange       int    `json:"flexibleRange"`
-	AllowFlexibleAmount bool   `json:"allowFlexibleAmount"`
+	Amount              int      `json:"amount"`
+	InternalRequestID   string   `json:"internalRequestID"`
+	CallbackUrl         string   `json:"callbackUrl"`
+	CallbackKey         string   `json:"callbackKey"`
+	MerchantID          string   `json:"merchantID"`
+	Type                string   `json:"type"`
+	ActiveTime          int      `json:"activeTime"`
+	BankID              string   `json:"bankId"`
+	PreferredBankIDs    []string `json:"preferredBankIds"`
+	ExcludedBankIDs     []string `json:"excludedBankIds"`
+	StrictBank          bool     `json:"strictBank"`
+	PayerID             string   `json:"userId"`
+	FlexibleRange       int      `json:"flexibleRange"`
+	AllowFlexibleAmount bool     `json:"allowFlexibleAmount"`
 }
 
 func (req *CreateInvoiceRequest) Validate() error {
@@ -46,9 +52,30 @@ func (req *CreateInvoiceRequest) Validate() error {
 	if req.Type == "" {
 		return ErrorEmptyRequisiteType
 	}
+	if req.StrictBank && req.BankID == "" && len(req.PreferredBankIDs) == 0 {
+		return ErrorStrictBankWithoutPreference
+	}
+	// Идентификатор плательщика необязателен
+	if req.PayerID != "" && !domain.PayerIDPattern.MatchString(req.PayerID) {
+		return domain.ErrorInvalidUserID
+	}
 	return nil
 }
 
+// BankPreference собирает пожелания к банку; bankId добавляется к предпочтительным.
+// В строгом режиме без свободных реквизитов предпочтительных банков Invoice не создается.
+func (req *CreateInvoiceRequest) BankPreference() domain.BankPreference {
+	preferred := req.PreferredBankIDs
+	if req.BankID != "" && !domain.Contains(preferred, req.BankID) {
+		preferred = append([]string{req.BankID}, preferred...)
+	}
+	return domain.BankPreference{
+		Preferred: preferred,
+		Excluded:  req.ExcludedBankIDs,
+		Strict:    req.StrictBank,
+	}
+}
+
 type CreateInvoiceResponse struct {
 	Status  string                     `json:"status"`
 	Error   bool                       `json:"error"`
@@ -72,6 +99,8 @@ type CreateInvoiceResponseData struct {
 	CardName          string    `json:"cardName"`
 	Issuer            string    `json:"issuer"`
 	TimeExperies      time.Time `json:"timeExperies"`
+	// BankPreferenceMet выбран ли реквизит предпочтительного банка; без пожеланий не передается
+	BankPreferenceMet *bool `json:"bankPreferenceMet,omitempty"`
 }
 
 func (s *Server) CreateInvoice(fiberContext fiber.Ctx) error {
@@ -91,6 +120,7 @@ func (s *Server) CreateInvoice(fiberContext fiber.Ctx) error {
 		return fiberContext.Status(fiber.StatusBadRequest).JSON(buildCreateInvoiceResponseWithError(err))
 	}
 
+	banks := req.BankPreference()
 	invoice, requisite, err := s.app.CreateInvoice(
 		ctx,
 		decimal.NewFromInt(int64(req.Amount)),
@@ -100,18 +130,66 @@ func (s *Server) CreateInvoice(fiberContext fiber.Ctx) error {
 		req.CallbackUrl,
 		req.CallbackKey,
 		time.Duration(req.ActiveTime)*time.Minute,
-		req.BankID,
+		banks,
+		req.PayerID,
 		req.FlexibleRange,
 		req.AllowFlexibleAmount,
 	)
 	if err != nil {
-		return fiberContext.Status(fiber.StatusInternalServerError).JSON(buildCreateInvoiceResponseWithError(err))
+		var rateLimitErr *domain.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			fiberContext.Set(fiber.HeaderRetryAfter, retryAfterSeconds(rateLimitErr.RetryAfter))
+		}
+		return fiberContext.Status(createInvoiceErrorStatus(err)).JSON(buildCreateInvoiceResponseWithError(err))
+	}
+
+	return fiberContext.Status(fiber.StatusOK).JSON(buildCreateInvoiceResponseWithInvoice(invoice, requisite, banks))
+}
+
+// createInvoiceErrorStatus HTTP-статус ошибки создания Invoice
+func createInvoiceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrorAmountLessThanLimit),
+		errors.Is(err, domain.ErrorAmountGreaterThanLimit):
+		return fiber.StatusBadRequest
+	case errors.Is(err, domain.ErrorPayerBlocked),
+		errors.Is(err, domain.ErrorPayerVelocityExceeded),
+		errors.Is(err, domain.ErrorMerchantSuspended),
+		errors.Is(err, domain.ErrorRequisiteTypeDisabled),
+		errors.Is(err, domain.ErrorMerchantDailyTurnoverExceeded),
+		errors.Is(err, domain.ErrorMerchantDailyInvoicesExceeded):
+		return fiber.StatusForbidden
+	case errors.Is(err, domain.ErrorMerchantRateLimited),
+		errors.Is(err, domain.ErrorMerchantConcurrencyLimited):
+		return fiber.StatusTooManyRequests
+	case errors.Is(err, domain.ErrorNoAvailableRequisites),
+		errors.Is(err, domain.ErrorInsufficientBalance):
+		return fiber.StatusServiceUnavailable
+	default:
+		return fiber.StatusInternalServerError
 	}
+}
 
-	return fiberContext.Status(fiber.StatusOK).JSON(buildCreateInvoiceResponseWithInvoice(invoice, requisite))
+// retryAfterSeconds значение Retry-After: целые секунды с округлением вверх, не меньше 1
+func retryAfterSeconds(retryAfter time.Duration) string {
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
 }
 
-func buildCreateInvoiceResponseWithInvoice(invoice *domain.Invoice, requisite *domain.Requisite) *CreateInvoiceResponse {
+func buildCreateInvoiceResponseWithInvoice(
+	invoice *domain.Invoice,
+	requisite *domain.Requisite,
+	banks domain.BankPreference,
+) *CreateInvoiceResponse {
+	var bankPreferenceMet *bool
+	if len(banks.Preferred) > 0 {
+		met := banks.Prefers(requisite.BankID)
+		bankPreferenceMet = &met
+	}
+
 	return &CreateInvoiceResponse{
 		Status:  "ok",
 		Error:   false,
@@ -132,6 +210,7 @@ func buildCreateInvoiceResponseWithInvoice(invoice *domain.Invoice, requisite *d
 			CardName:          requisite.RecipientName,
 			Issuer:            requisite.BankName,
 			TimeExperies:      invoice.TimeExpires,
+			BankPreferenceMet: bankPreferenceMet,
 		},
 	}
 }
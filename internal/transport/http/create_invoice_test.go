@@ -0,0 +1,56 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"mateo/internal/domain"
+)
+
+func TestCreateInvoiceErrorStatus(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{domain.ErrorAmountLessThanLimit, fiber.StatusBadRequest},
+		{domain.ErrorAmountGreaterThanLimit, fiber.StatusBadRequest},
+		{domain.ErrorMerchantSuspended, fiber.StatusForbidden},
+		{domain.ErrorRequisiteTypeDisabled, fiber.StatusForbidden},
+		{domain.ErrorMerchantDailyInvoicesExceeded, fiber.StatusForbidden},
+		{domain.ErrorMerchantDailyTurnoverExceeded, fiber.StatusForbidden},
+		{domain.ErrorPayerBlocked, fiber.StatusForbidden},
+		{domain.ErrorPayerVelocityExceeded, fiber.StatusForbidden},
+		{domain.ErrorMerchantRateLimited, fiber.StatusTooManyRequests},
+		{domain.ErrorMerchantConcurrencyLimited, fiber.StatusTooManyRequests},
+		{domain.ErrorNoAvailableRequisites, fiber.StatusServiceUnavailable},
+		{domain.ErrorInsufficientBalance, fiber.StatusServiceUnavailable},
+		{domain.ErrorFailedCreateInvoice, fiber.StatusInternalServerError},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.want, createInvoiceErrorStatus(errors.Wrap(c.err, "create invoice")), c.err.Error())
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	cases := []struct {
+		retryAfter time.Duration
+		want       string
+	}{
+		{0, "1"},
+		{-time.Second, "1"},
+		{time.Millisecond, "1"},
+		{250 * time.Millisecond, "1"},
+		{time.Second, "1"},
+		{time.Second + time.Millisecond, "2"},
+		{1500 * time.Millisecond, "2"},
+		{10 * time.Second, "10"},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.want, retryAfterSeconds(c.retryAfter), "retry after %s", c.retryAfter)
+	}
+}
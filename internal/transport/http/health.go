@@ -0,0 +1,45 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v3"
+)
+
+type HealthResponse struct {
+	Status string              `json:"status"`
+	Checks []HealthCheckResult `json:"checks,omitempty"`
+}
+
+type HealthCheckResult struct {
+	Name    string            `json:"name"`
+	Status  string            `json:"status"`
+	Error   string            `json:"error,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Liveness отвечает, пока процесс обрабатывает запросы; зависимости не проверяет
+func (s *Server) Liveness(fiberContext fiber.Ctx) error {
+	return fiberContext.Status(fiber.StatusOK).JSON(&HealthResponse{Status: "ok"})
+}
+
+// Readiness отвечает 503, пока сервис не может обслуживать запросы или останавливается
+func (s *Server) Readiness(fiberContext fiber.Ctx) error {
+	report := s.app.Readiness(fiberContext.Context())
+
+	resp := &HealthResponse{Status: "ok"}
+	status := fiber.StatusOK
+	if !report.Ready {
+		resp.Status = "unavailable"
+		status = fiber.StatusServiceUnavailable
+	}
+
+	for _, check := range report.Checks {
+		resp.Checks = append(resp.Checks, HealthCheckResult{
+			Name:    check.Name,
+			Status:  string(check.Status),
+			Error:   check.Error,
+			Details: check.Details,
+		})
+	}
+
+	return fiberContext.Status(status).JSON(resp)
+}
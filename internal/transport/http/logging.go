@@ -0,0 +1,74 @@
+package http
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	// maxRequestIDLength длиннее id из заголовка заменяется новым
+	maxRequestIDLength = 128
+)
+
+// logRequests берет id запроса из X-Request-ID или создает новый, возвращает его в ответе
+// и кладет в контекст логгер с этим id и трассой. После ответа пишет строку access-лога.
+func logRequests(c fiber.Ctx) error {
+	start := time.Now()
+
+	requestID := c.Get(requestIDHeader)
+	if !validRequestID(requestID) {
+		requestID = uuid.NewString()
+	}
+	c.Set(requestIDHeader, requestID)
+
+	logContext := log.With().Str("request_id", requestID)
+	if span := trace.SpanContextFromContext(c.Context()); span.IsValid() {
+		logContext = logContext.Str("trace_id", span.TraceID().String())
+	}
+	logger := logContext.Logger()
+	c.SetContext(logger.WithContext(c.Context()))
+
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if err != nil {
+		status = fiber.StatusInternalServerError
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			status = fiberErr.Code
+		}
+	}
+
+	event := logger.Info()
+	if status >= fiber.StatusInternalServerError {
+		event = logger.Error().Err(err)
+	}
+	event.
+		Str("method", c.Method()).
+		Str("path", c.Path()).
+		Int("status", status).
+		Dur("latency", time.Since(start)).
+		Str("ip", c.IP()).
+		Msg("request")
+
+	return err
+}
+
+// validRequestID принимает id из печатных ASCII-символов, чтобы заголовок не ломал логи
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/pkg/errors"
+	"mateo/internal/callback"
+	"mateo/internal/domain"
+)
+
+var ErrorEmptyOutcome = errors.New("empty outcome field")
+
+type SimulateSandboxRequest struct {
+	MerchantID string `json:"merchantID"`
+	Outcome    string `json:"outcome"`
+}
+
+func (req *SimulateSandboxRequest) Validate() error {
+	if req.MerchantID == "" {
+		return ErrorEmptyMerchantID
+	}
+	if req.Outcome == "" {
+		return ErrorEmptyOutcome
+	}
+	return nil
+}
+
+type SimulateSandboxResponse struct {
+	Status  string                       `json:"status"`
+	Error   bool                         `json:"error"`
+	Message string                       `json:"message"`
+	Data    *SimulateSandboxResponseData `json:"data,omitempty"`
+}
+
+type SimulateSandboxResponseData struct {
+	InvoiceId         string `json:"invoiceId"`
+	InvoiceStatus     string `json:"invoiceStatus"`
+	Amount            string `json:"amount"`
+	CallbackDelivered bool   `json:"callbackDelivered"`
+}
+
+// SimulateSandboxPayment имитирует оплату, истечение или апелляцию Invoice песочницы.
+// Тело запроса подписывается ключом callbackKey Invoice в заголовке X-Signature.
+func (s *Server) SimulateSandboxPayment(fiberContext fiber.Ctx) error {
+	ctx := fiberContext.Context()
+	req := &SimulateSandboxRequest{}
+	if err := fiberContext.Bind().Body(req); err != nil {
+		return fiberContext.Status(fiber.StatusBadRequest).
+			JSON(buildSimulateSandboxResponseWithError(errors.Wrap(err, "parse request body")))
+	}
+
+	if err := req.Validate(); err != nil {
+		return fiberContext.Status(fiber.StatusBadRequest).JSON(buildSimulateSandboxResponseWithError(err))
+	}
+
+	outcome, err := domain.ParseSandboxOutcome(req.Outcome)
+	if err != nil {
+		return fiberContext.Status(fiber.StatusBadRequest).JSON(buildSimulateSandboxResponseWithError(err))
+	}
+
+	signed := domain.SignedBody{
+		Body:      fiberContext.Body(),
+		Signature: fiberContext.Get(callback.SignatureHeader),
+	}
+	simulation, err := s.app.SimulateSandboxPayment(ctx, req.MerchantID, fiberContext.Params("id"), outcome, signed)
+	if err != nil {
+		return fiberContext.Status(sandboxErrorStatus(err)).JSON(buildSimulateSandboxResponseWithError(err))
+	}
+
+	return fiberContext.Status(fiber.StatusOK).JSON(&SimulateSandboxResponse{
+		Status:  "ok",
+		Error:   false,
+		Message: "success",
+		Data: &SimulateSandboxResponseData{
+			InvoiceId:         simulation.Invoice.ID,
+			InvoiceStatus:     string(simulation.Invoice.Status),
+			Amount:            simulation.Invoice.Amount.String(),
+			CallbackDelivered: simulation.CallbackDelivered,
+		},
+	})
+}
+
+func sandboxErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrorSandboxDisabled), errors.Is(err, domain.ErrorInvoiceNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, domain.ErrorInvalidSignature):
+		return fiber.StatusUnauthorized
+	case errors.Is(err, domain.ErrorInvoiceNotActive):
+		return fiber.StatusConflict
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
+
+func buildSimulateSandboxResponseWithError(err error) *SimulateSandboxResponse {
+	return &SimulateSandboxResponse{
+		Status:  "error",
+		Error:   true,
+		Message: err.Error(),
+	}
+}
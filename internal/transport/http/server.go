@@ -3,9 +3,8 @@ package http
 import (
 	"fmt"
 	"github.com/gofiber/fiber/v3"
-	"github.com/gofiber/fiber/v3/middleware/logger"
 	"github.com/gofiber/fiber/v3/middleware/recover"
-	"log"
+	"github.com/rs/zerolog/log"
 	"time"
 
 	"mateo/internal/domain"
@@ -19,9 +18,23 @@ type Server struct {
 	app   *domain.App
 }
 
-// NewServer creates a new HTTP server
-func NewServer(app *domain.App) (*Server, error) {
-	f := fiber.New()
+// ServerOptions таймауты соединений и размер тела запроса; нулевой таймаут не ограничен
+type ServerOptions struct {
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// BodyLimit наибольший размер тела в байтах; больший запрос получает 413
+	BodyLimit int
+}
+
+// NewServer creates a new HTTP server. The admin API is mounted only when adminTokens is not empty.
+func NewServer(app *domain.App, adminTokens map[string]string, options ServerOptions) (*Server, error) {
+	f := fiber.New(fiber.Config{
+		ReadTimeout:  options.ReadTimeout,
+		WriteTimeout: options.WriteTimeout,
+		IdleTimeout:  options.IdleTimeout,
+		BodyLimit:    options.BodyLimit,
+	})
 	s := &Server{
 		fiber: f,
 		app:   app,
@@ -29,12 +42,52 @@ func NewServer(app *domain.App) (*Server, error) {
 
 	// Middleware
 	f.Use(recover.New())
-	f.Use(logger.New())
+	f.Use(traceRequests)
+	f.Use(logRequests)
+
+	f.Get("/healthz", s.Liveness)
+	f.Get("/readyz", s.Readiness)
 
 	// API v1 group
 	api := f.Group("/api")
 
 	api.Post("/invoice-in", s.CreateInvoice)
+	api.Post("/sandbox/invoice-in/:id/simulate", s.SimulateSandboxPayment)
+
+	if len(adminTokens) > 0 {
+		admin := api.Group("/admin", adminAuth(adminTokens))
+
+		admin.Get("/merchants", s.ListMerchants)
+		admin.Post("/merchants", s.CreateMerchant)
+		admin.Get("/merchants/:id", s.GetMerchant)
+		admin.Patch("/merchants/:id", s.UpdateMerchant)
+		admin.Delete("/merchants/:id", s.DeleteMerchant)
+		admin.Get("/merchants/:id/limits", s.GetMerchantLimits)
+		admin.Put("/merchants/:id/limits", s.UpdateMerchantLimits)
+		admin.Get("/merchants/:id/trader-accounts", s.ListMerchantTraderAccounts)
+		admin.Put("/merchants/:id/trader-accounts/:accountId", s.LinkTraderAccount)
+		admin.Delete("/merchants/:id/trader-accounts/:accountId", s.UnlinkTraderAccount)
+		admin.Get("/audit", s.ListAuditLog)
+
+		for path, entity := range map[string]domain.BlockEntity{
+			"/trader-accounts": domain.BlockEntityTraderAccount,
+			"/terminals":       domain.BlockEntityTerminal,
+			"/requisites":      domain.BlockEntityRequisite,
+		} {
+			admin.Get(path+"/:id/block", s.GetBlock(entity))
+			admin.Put(path+"/:id/block", s.Block(entity))
+			admin.Delete(path+"/:id/block", s.Unblock(entity))
+		}
+
+		for path, scope := range map[string]domain.PayerBlockScope{
+			"/merchants": domain.PayerBlockScopeMerchant,
+			"/teams":     domain.PayerBlockScopeTeam,
+		} {
+			admin.Get(path+"/:id/payer-blocks", s.ListPayerBlocks(scope))
+			admin.Put(path+"/:id/payer-blocks/:payerId", s.BlockPayer(scope))
+			admin.Delete(path+"/:id/payer-blocks/:payerId", s.UnblockPayer(scope))
+		}
+	}
 
 	return s, nil
 }
@@ -42,7 +95,7 @@ func NewServer(app *domain.App) (*Server, error) {
 // Start starts the HTTP server
 func (s *Server) Start(port string) error {
 	addr := fmt.Sprintf(":%s", port)
-	log.Printf("Server starting on %s", addr)
+	log.Info().Str("addr", addr).Msg("Server starting")
 	return s.fiber.Listen(addr)
 }
 
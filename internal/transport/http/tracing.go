@@ -0,0 +1,72 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"mateo/internal/tracing"
+)
+
+// headerCarrier читает заголовки запроса Fiber для propagator
+type headerCarrier struct {
+	c fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h headerCarrier) Set(key string, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0)
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+var _ propagation.TextMapCarrier = headerCarrier{}
+
+// traceRequests продолжает трассу из заголовков traceparent/tracestate и открывает
+// серверный спан на запрос. Имя спана — шаблон маршрута, чтобы id не размножали имена.
+func traceRequests(c fiber.Ctx) error {
+	ctx := otel.GetTextMapPropagator().Extract(c.Context(), headerCarrier{c: c})
+	ctx, span := tracing.Tracer().Start(ctx, c.Method(),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(c.Method()),
+			semconv.URLPath(c.Path()),
+		),
+	)
+	defer span.End()
+
+	c.SetContext(ctx)
+	err := c.Next()
+
+	route := c.Route().Path
+	span.SetName(c.Method() + " " + route)
+	span.SetAttributes(semconv.HTTPRoute(route))
+
+	status := c.Response().StatusCode()
+	if err != nil {
+		// Статус ошибки выставит обработчик ошибок Fiber уже после этого middleware
+		span.RecordError(err)
+		status = fiber.StatusInternalServerError
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			status = fiberErr.Code
+		}
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if status >= fiber.StatusInternalServerError {
+		span.SetStatus(codes.Error, "")
+	}
+	return err
+}